@@ -0,0 +1,339 @@
+// Command tfimport generates Terraform resources and import commands out of an
+// existing OPNSense DHCP static mappings and DNS host overrides configuration,
+// so that brownfield instances can be adopted by the provider.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gxben/terraform-provider-opnsense/opnsense"
+)
+
+var rxInvalidName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// resourceNames hands out unique Terraform resource names
+type resourceNames map[string]int
+
+func (n resourceNames) get(prefix, hint string) string {
+	name := strings.Trim(rxInvalidName.ReplaceAllString(strings.ToLower(hint), "_"), "_-")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = prefix + "_" + name
+	}
+	n[name]++
+	if n[name] > 1 {
+		name = fmt.Sprintf("%s_%d", name, n[name])
+	}
+	return name
+}
+
+func split(list string) []string {
+	res := []string{}
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// envDefault returns the value of an environment variable, or a default one if unset
+func envDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// contains tells whether a list holds a value
+func contains(list []string, v string) bool {
+	for _, e := range list {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func main() {
+	uri := flag.String("uri", os.Getenv("OPNSENSE_URI"), "OPNsense platform URI")
+	user := flag.String("user", os.Getenv("OPNSENSE_USER_ID"), "OPNsense platform user ID")
+	loginPath := flag.String("login-path", "/", "OPNsense platform WebUI page, relative to uri, to log in through")
+	language := flag.String("ui-language", envDefault("OPNSENSE_UI_LANGUAGE", opnsense.DefaultUILanguage), "OPNsense platform WebUI language")
+	dnsBackend := flag.String("dns-backend", opnsense.DNSBackendUnbound, "OPNsense DNS service host overrides are read from (unbound or dnsmasq)")
+	interfaces := flag.String("interfaces", "", "comma-separated list of interfaces whose DHCP static mappings are imported (all if empty)")
+	domains := flag.String("domains", "", "comma-separated list of domains whose DNS host overrides are imported (all if empty)")
+	mode := flag.String("mode", "hcl", "output either HCL resource blocks (hcl), resource blocks along with import blocks (blocks) or terraform import commands (import)")
+	flag.Parse()
+
+	// the password is only taken from the environment, so that it never shows up in process listings
+	password := os.Getenv("OPNSENSE_USER_PASSWORD")
+	if *uri == "" || *user == "" || password == "" {
+		fmt.Fprintln(os.Stderr, "uri, user and password (OPNSENSE_USER_PASSWORD environment variable) are required")
+		os.Exit(1)
+	}
+	if *mode != "hcl" && *mode != "blocks" && *mode != "import" {
+		fmt.Fprintf(os.Stderr, "unsupported mode %q\n", *mode)
+		os.Exit(1)
+	}
+	if !contains(opnsense.SupportedUILanguages(), *language) {
+		fmt.Fprintf(os.Stderr, "unsupported WebUI language %q\n", *language)
+		os.Exit(1)
+	}
+	if !contains(opnsense.DNSBackends, *dnsBackend) {
+		fmt.Fprintf(os.Stderr, "unsupported DNS backend %q\n", *dnsBackend)
+		os.Exit(1)
+	}
+
+	// TLS settings are taken from the provider environment variables, as PEM contents
+	tlsConfig, err := opnsense.NewTLSConfig(os.Getenv("OPNSENSE_CA_CERT_PEM"), os.Getenv("OPNSENSE_CLIENT_CERT_PEM"), os.Getenv("OPNSENSE_CLIENT_KEY_PEM"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid TLS settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	opn := opnsense.OPNSession{
+		Language:  *language,
+		LoginPath: *loginPath,
+		TLSConfig: tlsConfig,
+	}
+	err = opn.Authenticate(*uri, *user, password)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to OPNSense at %s: %v\n", *uri, err)
+		os.Exit(1)
+	}
+
+	err = generate(os.Stdout, &opn, *dnsBackend, split(*interfaces), split(*domains), *mode)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// attribute is a rendered HCL resource attribute, or a nested block whenever it holds attributes
+type attribute struct {
+	key   string
+	value string
+	block []attribute
+}
+
+// hclString renders a string as an HCL literal, escaping template sequences
+func hclString(v string) string {
+	q := strconv.Quote(v)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+// hclList renders a list of strings as an HCL literal
+func hclList(values []string) string {
+	quoted := []string{}
+	for _, v := range values {
+		quoted = append(quoted, hclString(v))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(quoted, ", "))
+}
+
+// shellQuote renders a string as a single-quoted shell argument
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+// emit writes out a resource, either as a resource block (along with an import block
+// in blocks mode), or as a terraform import command
+func emit(w io.Writer, mode, kind, name, id string, attrs []attribute) {
+	if mode == "import" {
+		fmt.Fprintf(w, "terraform import %s.%s %s\n", kind, name, shellQuote(id))
+		return
+	}
+	if mode == "blocks" {
+		fmt.Fprintf(w, "import {\n  to = %s.%s\n  id = %s\n}\n\n", kind, name, hclString(id))
+	}
+
+	fmt.Fprintf(w, "resource %q %q {\n", kind, name)
+	writeBody(w, "  ", attrs)
+	fmt.Fprintf(w, "}\n\n")
+}
+
+// writeBody writes out attributes, then nested blocks
+func writeBody(w io.Writer, indent string, attrs []attribute) {
+	// align values the way terraform fmt does
+	width := 0
+	for _, a := range attrs {
+		if a.block == nil && len(a.key) > width {
+			width = len(a.key)
+		}
+	}
+	for _, a := range attrs {
+		if a.block == nil {
+			fmt.Fprintf(w, "%s%-*s = %s\n", indent, width, a.key, a.value)
+		}
+	}
+	for _, a := range attrs {
+		if a.block != nil {
+			fmt.Fprintf(w, "\n%s%s {\n", indent, a.key)
+			writeBody(w, indent+"  ", a.block)
+			fmt.Fprintf(w, "%s}\n", indent)
+		}
+	}
+}
+
+func generate(w io.Writer, opn *opnsense.OPNSession, dnsBackend string, interfaces, domains []string, mode string) error {
+	names := resourceNames{}
+
+	// DHCP static mappings, either of all interfaces DHCP can be configured on or of the given ones
+	dhcp := opnsense.DHCPSession{
+		OPN: opn,
+	}
+	all, err := dhcp.GetAllStaticMappings()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve static mappings: %v", err)
+	}
+	if len(interfaces) == 0 {
+		for iface := range all {
+			interfaces = append(interfaces, iface)
+		}
+		sort.Strings(interfaces)
+	}
+	for _, iface := range interfaces {
+		mappings, ok := all[iface]
+		if !ok {
+			return fmt.Errorf("unable to retrieve %s static mappings: not a DHCP interface", iface)
+		}
+		for _, m := range mappings {
+			// settings only shown on the edit page would otherwise be reset by the first apply
+			err := dhcp.ReadDetails(&m)
+			if err != nil {
+				return fmt.Errorf("unable to retrieve %s static mapping settings: %v", m.MAC, err)
+			}
+			hint := m.Hostname
+			if hint == "" {
+				hint = m.MAC
+			}
+			attrs := []attribute{
+				{key: "interface", value: hclString(m.Interface)},
+				{key: "mac", value: hclString(m.MAC)},
+				{key: "ipaddr", value: hclString(m.IP)},
+			}
+			if m.Hostname != "" {
+				attrs = append(attrs, attribute{key: "hostname", value: hclString(m.Hostname)})
+			}
+			if m.Disabled {
+				attrs = append(attrs, attribute{key: "enabled", value: "false"})
+			}
+			if len(m.MACs) > 0 {
+				attrs = append(attrs, attribute{key: "macs", value: hclList(m.MACs)})
+			}
+			for _, a := range []attribute{
+				{key: "next_server", value: m.NextServer},
+				{key: "boot_filename", value: m.Filename},
+				{key: "root_path", value: m.RootPath},
+				{key: "pool", value: m.Pool},
+			} {
+				if a.value != "" {
+					attrs = append(attrs, attribute{key: a.key, value: hclString(a.value)})
+				}
+			}
+			for _, o := range m.Options {
+				option := []attribute{
+					{key: "number", value: strconv.Itoa(o.Number)},
+				}
+				if o.Type != "" {
+					option = append(option, attribute{key: "type", value: hclString(o.Type)})
+				}
+				option = append(option, attribute{key: "value", value: hclString(o.Value)})
+				attrs = append(attrs, attribute{key: "dhcp_options", block: option})
+			}
+			id := fmt.Sprintf("%s/%s", m.Interface, m.MAC)
+			emit(w, mode, "opnsense_dhcp_static_map", names.get("dhcp", hint), id, attrs)
+		}
+	}
+
+	// DNS host overrides, either all of them or those of the given domains
+	dns := opnsense.DNSSession{
+		OPN:     opn,
+		Backend: dnsBackend,
+	}
+	entries := []opnsense.DNSHostEntry{}
+	if len(domains) == 0 {
+		all, err := dns.GetAllHostEntries()
+		if err != nil {
+			return fmt.Errorf("unable to retrieve DNS host overrides: %v", err)
+		}
+		entries = all
+	}
+	for _, d := range domains {
+		found, err := dns.GetHostEntriesByDomain(d)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve %s DNS host overrides: %v", d, err)
+		}
+		entries = append(entries, found...)
+	}
+
+	// unnamed A/AAAA entries sharing type, host and domain make a single round-robin record
+	records := [][]opnsense.DNSHostEntry{}
+	index := map[string]int{}
+	for _, e := range entries {
+		key := fmt.Sprintf("%s/%s/%s", e.Type, e.Host, opnsense.NormalizeDomain(e.Domain))
+		rr := opnsense.DNSEntryName(&e) == "" && (e.Type == "A" || e.Type == "AAAA")
+		if i, ok := index[key]; rr && ok {
+			records[i] = append(records[i], e)
+			continue
+		}
+		if rr {
+			index[key] = len(records)
+		}
+		records = append(records, []opnsense.DNSHostEntry{e})
+	}
+
+	for _, r := range records {
+		// settings only shown on the edit page (TTL, view, verbatim TXT values) are shared by round-robin entries
+		e := r[0]
+		err := dns.ReadDetails(&e)
+		if err != nil {
+			return fmt.Errorf("unable to retrieve %s.%s settings: %v", e.Host, e.Domain, err)
+		}
+		// A/AAAA types are left to be inferred from the IP address family
+		attrs := []attribute{}
+		if !opnsense.DNSHostOverrideInfersType(&e) {
+			attrs = append(attrs, attribute{key: "type", value: hclString(e.Type)})
+		}
+		attrs = append(attrs,
+			attribute{key: "host", value: hclString(e.Host)},
+			attribute{key: "domain", value: hclString(e.Domain)},
+		)
+		if len(r) > 1 {
+			ips := []string{}
+			for _, rr := range r {
+				ips = append(ips, rr.IP)
+			}
+			sort.Strings(ips)
+			e.IP = strings.Join(ips, ",")
+			attrs = append(attrs, attribute{key: "ips", value: hclList(ips)})
+		} else {
+			attrs = append(attrs, attribute{key: "ip", value: hclString(e.IP)})
+		}
+		if e.Type == "MX" {
+			attrs = append(attrs, attribute{key: "mx_priority", value: fmt.Sprintf("%d", e.MXPriority)})
+		}
+		if e.TTL != 0 {
+			attrs = append(attrs, attribute{key: "ttl", value: fmt.Sprintf("%d", e.TTL)})
+		}
+		if e.View != "" {
+			attrs = append(attrs, attribute{key: "view", value: hclString(e.View)})
+		}
+		if name := opnsense.DNSEntryName(&e); name != "" {
+			attrs = append(attrs, attribute{key: "name", value: hclString(name)})
+		}
+		name := names.get("dns", fmt.Sprintf("%s_%s", e.Host, e.Domain))
+		emit(w, mode, "opnsense_dns_host_override", name, opnsense.DNSHostOverrideImportID(&e), attrs)
+	}
+
+	return nil
+}
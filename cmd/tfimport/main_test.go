@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEmitNestedBlocks(t *testing.T) {
+	var b bytes.Buffer
+	emit(&b, "hcl", "opnsense_dhcp_static_map", "pxe", "lan/00:11:22:33:44:55", []attribute{
+		{key: "interface", value: hclString("lan")},
+		{key: "mac", value: hclString("00:11:22:33:44:55")},
+		{key: "enabled", value: "false"},
+		{key: "macs", value: hclList([]string{"00:11:22:33:44:56"})},
+		{key: "dhcp_options", block: []attribute{
+			{key: "number", value: "66"},
+			{key: "value", value: hclString("${tftp}")},
+		}},
+		{key: "next_server", value: hclString("192.168.1.2")},
+	})
+
+	expected := `resource "opnsense_dhcp_static_map" "pxe" {
+  interface   = "lan"
+  mac         = "00:11:22:33:44:55"
+  enabled     = false
+  macs        = ["00:11:22:33:44:56"]
+  next_server = "192.168.1.2"
+
+  dhcp_options {
+    number = 66
+    value  = "$${tftp}"
+  }
+}
+
+`
+	if b.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+}
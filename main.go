@@ -25,6 +25,9 @@ func main() {
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: opnsense.Provider,
 	})
+
+	// Terraform is done with the provider, don't leave sessions behind
+	opnsense.Teardown()
 }
 
 func printVersion(writer io.Writer) error {
@@ -0,0 +1,306 @@
+package opnsense
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// FirewallAliasAPI is the firewall alias MVC API root
+	FirewallAliasAPI = "/api/firewall/alias"
+	// FirewallAliasUtilAPI is the firewall alias entries MVC API root
+	FirewallAliasUtilAPI = "/api/firewall/alias_util"
+)
+
+const (
+	// ErrNoSuchAlias is thrown if no firewall alias exists with the referenced name
+	ErrNoSuchAlias = "firewall alias doesn't exists"
+)
+
+// AliasIncrementalMaxChanges is the number of added/removed entries above which
+// rewriting the whole alias content is cheaper than changing entries one by one
+const AliasIncrementalMaxChanges = 100
+
+// AliasIncrementalTypes are the alias types whose entries can be changed one by one
+var AliasIncrementalTypes = []string{"host", "network"}
+
+var rxAliasName = regexp.MustCompile(`^[a-zA-Z0-9_]{1,32}$`)
+
+// Alias abstracts a firewall alias
+type Alias struct {
+	UUID        string
+	Name        string
+	Type        string
+	Content     []string
+	Categories  []string
+	Description string
+}
+
+type apiAlias struct {
+	Enabled     string `json:"enabled"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Content     string `json:"content"`
+	Categories  string `json:"categories"`
+	Description string `json:"description"`
+}
+
+type apiAliasUtilResult struct {
+	Status string `json:"status"`
+}
+
+type apiAliasRead struct {
+	Name        string               `json:"name"`
+	Type        map[string]APIOption `json:"type"`
+	Content     map[string]APIOption `json:"content"`
+	Categories  map[string]APIOption `json:"categories"`
+	Description string               `json:"description"`
+}
+
+func (a *Alias) toAPI() map[string]apiAlias {
+	// content is a set, write it in a stable order
+	content := append([]string{}, a.Content...)
+	sort.Strings(content)
+	categories := append([]string{}, a.Categories...)
+	sort.Strings(categories)
+
+	return map[string]apiAlias{
+		"alias": {
+			Enabled:     "1",
+			Name:        a.Name,
+			Type:        a.Type,
+			Content:     strings.Join(content, "\n"),
+			Categories:  strings.Join(categories, ","),
+			Description: a.Description,
+		},
+	}
+}
+
+// ApplyAliases reconfigures firewall aliases
+func (s *FirewallSession) ApplyAliases() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/reconfigure", FirewallAliasAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// CreateAlias creates a new firewall alias
+func (s *FirewallSession) CreateAlias(a *Alias) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/addItem", FirewallAliasAPI), a.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+	a.UUID = res.UUID
+
+	// apply changes
+	return s.ApplyAliases()
+}
+
+// ReadAlias retrieves firewall alias information for a specified UUID
+func (s *FirewallSession) ReadAlias(a *Alias) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]apiAliasRead{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/getItem/%s", FirewallAliasAPI, a.UUID), &res)
+	if err != nil {
+		return err
+	}
+	e, ok := res["alias"]
+	if !ok {
+		return fmt.Errorf("firewall alias %s doesn't exists", a.UUID)
+	}
+
+	// assign values accordingly
+	a.Name = e.Name
+	a.Type = SelectedOption(e.Type)
+	a.Content = SelectedOptions(e.Content)
+	a.Categories = SelectedOptions(e.Categories)
+	a.Description = e.Description
+
+	return nil
+}
+
+// FindAliasByName retrieves firewall alias information for a specified name
+func (s *FirewallSession) FindAliasByName(name string) (*Alias, error) {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return nil, err
+	}
+
+	// unknown names get an empty list rather than an object
+	var res interface{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/getAliasUUID/%s", FirewallAliasAPI, url.PathEscape(name)), &res)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := res.(map[string]interface{})
+	uuid, _ := m["uuid"].(string)
+	if uuid == "" {
+		return nil, fmt.Errorf("%s: %s", ErrNoSuchAlias, name)
+	}
+
+	a := Alias{
+		UUID: uuid,
+	}
+	err = s.ReadAlias(&a)
+	if err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// UpdateAlias modifies an already existing firewall alias
+func (s *FirewallSession) UpdateAlias(a *Alias) error {
+
+	err := s.setAlias(a)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplyAliases()
+}
+
+// setAlias saves an already existing firewall alias, without applying changes
+func (s *FirewallSession) setAlias(a *Alias) error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/setItem/%s", FirewallAliasAPI, a.UUID), a.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// aliasUtil adds or removes a single entry of the running pf table of an alias, through the
+// alias entries API. Saved alias content isn't affected.
+func (s *FirewallSession) aliasUtil(action, name, entry string) error {
+	res := apiAliasUtilResult{}
+	body := map[string]string{
+		"address": entry,
+	}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/%s/%s", FirewallAliasUtilAPI, action, name), body, &res)
+	if err != nil {
+		return err
+	}
+	if res.Status != "done" {
+		return fmt.Errorf("unable to %s %s on firewall alias %s (status: %q)", action, entry, name, res.Status)
+	}
+	return nil
+}
+
+// UpdateAliasContent changes the given entries of an already existing firewall alias, a being
+// its expected state, without reloading all aliases, which is slow on aliases with thousands
+// of entries. The API can't patch saved alias content: it's read back and saved whole, sorted,
+// with the given entries added and removed, so that entries changed out of band are kept and
+// changes persist across filter reloads. Only the running pf table is changed entry by entry.
+// This takes more round-trips and payload than UpdateAlias, the alias being read back first:
+// only the reload is saved.
+// Aliases are reloaded whenever entries can't be changed this way (alias type, too many
+// changes, or any entry change failing).
+func (s *FirewallSession) UpdateAliasContent(a *Alias, added, removed []string) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	// patch the saved content, leaving other entries as they are
+	live := Alias{
+		UUID: a.UUID,
+	}
+	err = s.ReadAlias(&live)
+	if err != nil {
+		return err
+	}
+	entries := map[string]bool{}
+	for _, entry := range live.Content {
+		entries[entry] = true
+	}
+	for _, entry := range removed {
+		delete(entries, entry)
+	}
+	for _, entry := range added {
+		entries[entry] = true
+	}
+	patched := *a
+	patched.Content = []string{}
+	for entry := range entries {
+		patched.Content = append(patched.Content, entry)
+	}
+	sort.Strings(patched.Content)
+	err = s.setAlias(&patched)
+	if err != nil {
+		return err
+	}
+
+	incremental := len(added)+len(removed) <= AliasIncrementalMaxChanges
+	supported := false
+	for _, t := range AliasIncrementalTypes {
+		supported = supported || a.Type == t
+	}
+	if !incremental || !supported {
+		return s.ApplyAliases()
+	}
+
+	// the running table is updated in place, saved content matching it
+	for _, entry := range removed {
+		err := s.aliasUtil("delete", a.Name, entry)
+		if err != nil {
+			log.Printf("[WARN] %v, reloading aliases", err)
+			return s.ApplyAliases()
+		}
+	}
+	for _, entry := range added {
+		err := s.aliasUtil("add", a.Name, entry)
+		if err != nil {
+			log.Printf("[WARN] %v, reloading aliases", err)
+			return s.ApplyAliases()
+		}
+	}
+
+	return nil
+}
+
+// DeleteAlias destroy an existing firewall alias
+func (s *FirewallSession) DeleteAlias(a *Alias) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/delItem/%s", FirewallAliasAPI, a.UUID), nil, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplyAliases()
+}
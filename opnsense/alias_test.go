@@ -0,0 +1,396 @@
+package opnsense
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// aliasAPI fakes the firewall alias MVC API, holding a single alias whose saved content
+// only reaches the running pf table on reconfigure, or through the alias entries API.
+// It also records the firewall rules being added.
+type aliasAPI struct {
+	mu       sync.Mutex
+	alias    Alias
+	saved    map[string]bool
+	table    map[string]bool
+	reloads  int
+	utilFail bool
+	rules    []apiFirewallRuleWrite
+	// requests and payload count API calls and the bytes they exchanged
+	requests int
+	payload  int
+	// order is the saved content, as last submitted
+	order []string
+}
+
+// countingWriter counts the bytes of an API answer
+type countingWriter struct {
+	http.ResponseWriter
+	n int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += len(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func newAliasAPI(typ string, content []string) *aliasAPI {
+	f := aliasAPI{
+		alias: Alias{
+			UUID: "a1",
+			Name: "blocklist",
+			Type: typ,
+		},
+		saved: map[string]bool{},
+		table: map[string]bool{},
+	}
+	for _, entry := range content {
+		f.saved[entry] = true
+		f.table[entry] = true
+	}
+	return &f
+}
+
+func entries(set map[string]bool) []string {
+	res := []string{}
+	for entry := range set {
+		res = append(res, entry)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func (f *aliasAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	cw := &countingWriter{ResponseWriter: w}
+	w = cw
+	defer func() {
+		f.requests++
+		f.payload += len(body) + cw.n
+	}()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, FirewallAliasAPI+"/getAliasUUID/"):
+		// unknown names get an empty list
+		if strings.TrimPrefix(r.URL.Path, FirewallAliasAPI+"/getAliasUUID/") != f.alias.Name {
+			writeJSON(w, []string{})
+			return
+		}
+		writeJSON(w, map[string]string{"uuid": f.alias.UUID})
+	case r.URL.Path == FirewallAliasAPI+"/getItem/"+f.alias.UUID:
+		content := map[string]APIOption{}
+		for entry := range f.saved {
+			content[entry] = APIOption{Value: entry, Selected: 1}
+		}
+		writeJSON(w, map[string]interface{}{
+			"alias": map[string]interface{}{
+				"name":        f.alias.Name,
+				"type":        map[string]APIOption{f.alias.Type: {Value: f.alias.Type, Selected: 1}},
+				"content":     content,
+				"categories":  map[string]APIOption{},
+				"description": "",
+			},
+		})
+	case r.URL.Path == FirewallAliasAPI+"/setItem/"+f.alias.UUID:
+		body := map[string]apiAlias{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.saved = map[string]bool{}
+		f.order = strings.Split(body["alias"].Content, "\n")
+		for _, entry := range f.order {
+			if entry != "" {
+				f.saved[entry] = true
+			}
+		}
+		writeJSON(w, APIResult{Result: "saved"})
+	case r.URL.Path == FirewallAliasAPI+"/reconfigure":
+		f.reloads++
+		f.table = map[string]bool{}
+		for entry := range f.saved {
+			f.table[entry] = true
+		}
+		writeJSON(w, APIResult{Status: "ok"})
+	case strings.HasPrefix(r.URL.Path, FirewallAliasUtilAPI+"/"):
+		body := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if f.utilFail {
+			writeJSON(w, apiAliasUtilResult{Status: "failed"})
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, FirewallAliasUtilAPI+"/add/") {
+			f.table[body["address"]] = true
+		} else {
+			delete(f.table, body["address"])
+		}
+		writeJSON(w, apiAliasUtilResult{Status: "done"})
+	case r.URL.Path == FirewallFilterAPI+"/addRule":
+		body := map[string]apiFirewallRuleWrite{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.rules = append(f.rules, body["rule"])
+		writeJSON(w, APIResult{Result: "saved", UUID: fmt.Sprintf("r%d", len(f.rules))})
+	case r.URL.Path == FirewallFilterAPI+"/apply":
+		writeJSON(w, APIResult{Status: "ok"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestUpdateAliasContentPersists(t *testing.T) {
+	f := newAliasAPI("host", []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+	fw := FirewallSession{OPN: newTestSession(t, f)}
+
+	a := f.alias
+	a.Content = []string{"10.0.0.1", "10.0.0.3", "10.0.0.4"}
+	err := fw.UpdateAliasContent(&a, []string{"10.0.0.4"}, []string{"10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// saved content is read back, and the running table matches it without any reload
+	r := Alias{UUID: a.UUID}
+	err = fw.ReadAlias(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Content, a.Content) {
+		t.Errorf("read back content %v, expected %v", r.Content, a.Content)
+	}
+	if !reflect.DeepEqual(entries(f.table), a.Content) {
+		t.Errorf("running table %v, expected %v", entries(f.table), a.Content)
+	}
+	if f.reloads != 0 {
+		t.Errorf("got %d aliases reloads, expected none", f.reloads)
+	}
+}
+
+func TestUpdateAliasContentReloadsOnFailure(t *testing.T) {
+	f := newAliasAPI("host", []string{"10.0.0.1"})
+	f.utilFail = true
+	fw := FirewallSession{OPN: newTestSession(t, f)}
+
+	a := f.alias
+	a.Content = []string{"10.0.0.1", "10.0.0.2"}
+	err := fw.UpdateAliasContent(&a, []string{"10.0.0.2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.reloads != 1 {
+		t.Errorf("got %d aliases reloads, expected 1", f.reloads)
+	}
+	if !reflect.DeepEqual(entries(f.table), a.Content) {
+		t.Errorf("running table %v, expected %v", entries(f.table), a.Content)
+	}
+}
+
+func TestUpdateAliasContentUnsupportedType(t *testing.T) {
+	f := newAliasAPI("port", []string{"80"})
+	fw := FirewallSession{OPN: newTestSession(t, f)}
+
+	a := f.alias
+	a.Content = []string{"443", "80"}
+	err := fw.UpdateAliasContent(&a, []string{"443"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.reloads != 1 {
+		t.Errorf("got %d aliases reloads, expected 1", f.reloads)
+	}
+	if !reflect.DeepEqual(entries(f.saved), a.Content) {
+		t.Errorf("saved content %v, expected %v", entries(f.saved), a.Content)
+	}
+}
+
+func TestUpdateAliasContentTooManyChanges(t *testing.T) {
+	f := newAliasAPI("host", []string{"10.0.0.1"})
+	fw := FirewallSession{OPN: newTestSession(t, f)}
+
+	// one entry more than can be changed one by one
+	a := f.alias
+	added := []string{}
+	for i := 0; i <= AliasIncrementalMaxChanges; i++ {
+		added = append(added, fmt.Sprintf("10.1.0.%d", i))
+	}
+	a.Content = append([]string{"10.0.0.1"}, added...)
+	sort.Strings(a.Content)
+	err := fw.UpdateAliasContent(&a, added, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.reloads != 1 {
+		t.Errorf("got %d aliases reloads, expected 1", f.reloads)
+	}
+	if !reflect.DeepEqual(entries(f.table), a.Content) {
+		t.Errorf("running table doesn't match expected content: %v", entries(f.table))
+	}
+}
+
+func TestUpdateAliasContentKeepsOtherEntries(t *testing.T) {
+	f := newAliasAPI("network", []string{"10.0.0.0/24", "10.0.1.0/24"})
+	fw := FirewallSession{OPN: newTestSession(t, f)}
+
+	// an entry has been added out of band since the alias was last read
+	f.saved["10.0.2.0/24"] = true
+	f.table["10.0.2.0/24"] = true
+
+	a := f.alias
+	a.Content = []string{"10.0.1.0/24", "10.0.3.0/24"}
+	err := fw.UpdateAliasContent(&a, []string{"10.0.3.0/24"}, []string{"10.0.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"}
+	if !reflect.DeepEqual(entries(f.saved), expected) {
+		t.Errorf("saved content %v, expected %v", entries(f.saved), expected)
+	}
+	if !reflect.DeepEqual(entries(f.table), expected) {
+		t.Errorf("running table %v, expected %v", entries(f.table), expected)
+	}
+
+	// saved whole in a stable order, so that the configuration only changes where entries do
+	if !reflect.DeepEqual(f.order, expected) {
+		t.Errorf("content saved as %v, expected %v", f.order, expected)
+	}
+}
+
+func TestRuleAliasRef(t *testing.T) {
+	for network, expected := range map[string]string{
+		"blocklist":      "blocklist",
+		"any":            "",
+		"lan":            "",
+		"opt12ip":        "",
+		"10.0.0.1":       "",
+		"10.0.0.0/24":    "",
+		"fd00::1":        "",
+		"wan_blocklist2": "wan_blocklist2",
+	} {
+		if res := ruleAliasRef(network); res != expected {
+			t.Errorf("%s refers to alias %q, expected %q", network, res, expected)
+		}
+	}
+}
+
+func TestFindAliasByName(t *testing.T) {
+	f := newAliasAPI("host", []string{"10.0.0.1"})
+	fw := FirewallSession{OPN: newTestSession(t, f)}
+
+	a, err := fw.FindAliasByName("blocklist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.UUID != f.alias.UUID {
+		t.Errorf("found alias %s, expected %s", a.UUID, f.alias.UUID)
+	}
+
+	_, err = fw.FindAliasByName("blocklsit")
+	if err == nil || err.Error() != ErrNoSuchAlias+": blocklsit" {
+		t.Errorf("got %v, expected %s", err, ErrNoSuchAlias)
+	}
+}
+
+func TestCreateRuleNonexistentAlias(t *testing.T) {
+	f := newAliasAPI("host", []string{"10.0.0.1"})
+	fw := FirewallSession{OPN: newTestSession(t, f)}
+
+	r := FirewallRule{
+		Enabled:     true,
+		Sequence:    10,
+		Interface:   "lan",
+		Action:      "block",
+		Protocol:    "any",
+		Source:      "blocklsit",
+		Destination: "any",
+	}
+	err := fw.CreateRule(&r)
+	if err == nil || err.Error() != ErrNoSuchAlias+": blocklsit" {
+		t.Errorf("got %v, expected %s", err, ErrNoSuchAlias)
+	}
+	if len(f.rules) != 0 {
+		t.Errorf("got %d rules posted, expected none", len(f.rules))
+	}
+
+	// existing aliases, addresses and interfaces networks are accepted
+	for _, source := range []string{"blocklist", "10.0.0.0/24", "opt1"} {
+		r.Source = source
+		err = fw.CreateRule(&r)
+		if err != nil {
+			t.Errorf("%s: %v", source, err)
+		}
+	}
+	if len(f.rules) != 3 {
+		t.Errorf("got %d rules posted, expected 3", len(f.rules))
+	}
+}
+
+// benchmarkAlias returns a fake holding a 5000 entries host alias
+func benchmarkAlias() *aliasAPI {
+	content := []string{}
+	for i := 0; i < 5000; i++ {
+		content = append(content, fmt.Sprintf("10.%d.%d.%d", i/65536, (i/256)%256, i%256))
+	}
+	return newAliasAPI("host", content)
+}
+
+// BenchmarkUpdateAliasContent changes a single entry of a 5000 entries alias
+func BenchmarkUpdateAliasContent(b *testing.B) {
+	f := benchmarkAlias()
+	fw := FirewallSession{OPN: newTestSession(b, f)}
+	a := f.alias
+	a.Content = entries(f.saved)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entry := fmt.Sprintf("192.168.%d.%d", (i/256)%256, i%256)
+		err := fw.UpdateAliasContent(&a, []string{entry}, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		err = fw.UpdateAliasContent(&a, nil, []string{entry})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportAliasMetrics(b, f)
+}
+
+// BenchmarkUpdateAlias rewrites a 5000 entries alias to change a single entry
+func BenchmarkUpdateAlias(b *testing.B) {
+	f := benchmarkAlias()
+	fw := FirewallSession{OPN: newTestSession(b, f)}
+	a := f.alias
+	base := entries(f.saved)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.Content = append(base, fmt.Sprintf("192.168.%d.%d", (i/256)%256, i%256))
+		err := fw.UpdateAlias(&a)
+		if err != nil {
+			b.Fatal(err)
+		}
+		a.Content = base
+		err = fw.UpdateAlias(&a)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportAliasMetrics(b, f)
+}
+
+// reportAliasMetrics reports the aliases reloads, API round-trips and payload bytes taken
+// per benchmark iteration, as the fake answers right away: ns/op reflects neither the cost
+// of a reload nor the network one
+func reportAliasMetrics(b *testing.B, f *aliasAPI) {
+	b.ReportMetric(float64(f.reloads)/float64(b.N), "reloads/op")
+	b.ReportMetric(float64(f.requests)/float64(b.N), "requests/op")
+	b.ReportMetric(float64(f.payload)/float64(b.N), "payload-B/op")
+}
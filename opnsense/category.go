@@ -0,0 +1,105 @@
+package opnsense
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// FirewallCategoryAPI is the firewall category MVC API root
+	FirewallCategoryAPI = "/api/firewall/category"
+)
+
+var rxCategoryColor = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// Category abstracts a firewall category
+type Category struct {
+	UUID  string
+	Name  string
+	Color string
+}
+
+type apiCategory struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+func (c *Category) toAPI() map[string]apiCategory {
+	return map[string]apiCategory{
+		"category": {
+			Name:  c.Name,
+			Color: c.Color,
+		},
+	}
+}
+
+// CreateCategory creates a new firewall category
+func (s *FirewallSession) CreateCategory(c *Category) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/addItem", FirewallCategoryAPI), c.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+	c.UUID = res.UUID
+
+	return nil
+}
+
+// ReadCategory retrieves firewall category information for a specified UUID
+func (s *FirewallSession) ReadCategory(c *Category) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]apiCategory{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/getItem/%s", FirewallCategoryAPI, c.UUID), &res)
+	if err != nil {
+		return err
+	}
+	e, ok := res["category"]
+	if !ok {
+		return fmt.Errorf("firewall category %s doesn't exists", c.UUID)
+	}
+
+	// assign values accordingly
+	c.Name = e.Name
+	c.Color = e.Color
+
+	return nil
+}
+
+// UpdateCategory modifies an already existing firewall category
+func (s *FirewallSession) UpdateCategory(c *Category) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/setItem/%s", FirewallCategoryAPI, c.UUID), c.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// DeleteCategory destroy an existing firewall category
+func (s *FirewallSession) DeleteCategory(c *Category) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/delItem/%s", FirewallCategoryAPI, c.UUID), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
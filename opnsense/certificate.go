@@ -0,0 +1,110 @@
+package opnsense
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TrustCertAPI is the certificates MVC API root
+	TrustCertAPI = "/api/trust/cert"
+)
+
+const (
+	// ErrNoSuchCertificate is thrown if no certificate matches the lookup criteria
+	ErrNoSuchCertificate = "no certificate matches the lookup criteria"
+	// ErrAmbiguousCertificate is thrown if several certificates match the lookup criteria
+	ErrAmbiguousCertificate = "several certificates match the lookup criteria"
+)
+
+// TrustSession abstracts OPNSense trust (certificates) management
+type TrustSession struct {
+	OPN *OPNSession
+}
+
+// Certificate abstracts a certificate of OPNSense trust store
+type Certificate struct {
+	UUID        string
+	RefID       string
+	Description string
+	CommonName  string
+	// ValidTo is the certificate expiry date, zero if unknown
+	ValidTo time.Time
+}
+
+type apiCertificateSearch struct {
+	Rows []struct {
+		UUID        string `json:"uuid"`
+		RefID       string `json:"refid"`
+		Description string `json:"descr"`
+		CommonName  string `json:"commonname"`
+		ValidTo     string `json:"valid_to"`
+	} `json:"rows"`
+}
+
+// GetAllCertificates retrieves all certificates of OPNSense trust store
+func (s *TrustSession) GetAllCertificates() ([]Certificate, error) {
+
+	certs := []Certificate{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return certs, err
+	}
+
+	res := apiCertificateSearch{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/search", TrustCertAPI), keaSearchQuery, &res)
+	if err != nil {
+		return certs, err
+	}
+
+	for _, r := range res.Rows {
+		c := Certificate{
+			UUID:        r.UUID,
+			RefID:       r.RefID,
+			Description: r.Description,
+			CommonName:  r.CommonName,
+		}
+		// expiry is reported as a UNIX timestamp
+		ts, err := strconv.ParseInt(strings.TrimSpace(r.ValidTo), 10, 64)
+		if err == nil && ts > 0 {
+			c.ValidTo = time.Unix(ts, 0).UTC()
+		}
+		certs = append(certs, c)
+	}
+
+	return certs, nil
+}
+
+// FindCertificate retrieves the single certificate matching all non-empty
+// fields of the filter (description and/or common name)
+func (s *TrustSession) FindCertificate(filter *Certificate) (*Certificate, error) {
+
+	certs, err := s.GetAllCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := []Certificate{}
+	for _, c := range certs {
+		if filter.Description != "" && c.Description != filter.Description {
+			continue
+		}
+		if filter.CommonName != "" && c.CommonName != filter.CommonName {
+			continue
+		}
+		matches = append(matches, c)
+	}
+
+	if len(matches) == 1 {
+		return &matches[0], nil
+	}
+	if len(matches) == 0 {
+		return nil, s.OPN.Error(ErrNoSuchCertificate)
+	}
+
+	return nil, fmt.Errorf("%s (%d found)", ErrAmbiguousCertificate, len(matches))
+}
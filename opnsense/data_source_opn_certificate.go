@@ -0,0 +1,96 @@
+package opnsense
+
+import (
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+const (
+	// KeyCertDescription corresponds to the associated data source schema key
+	KeyCertDescription = "description"
+	// KeyCertCommonName corresponds to the associated data source schema key
+	KeyCertCommonName = "common_name"
+	// KeyCertRefID corresponds to the associated data source schema key
+	KeyCertRefID = "refid"
+	// KeyCertUUID corresponds to the associated data source schema key
+	KeyCertUUID = "uuid"
+	// KeyCertValidTo corresponds to the associated data source schema key
+	KeyCertValidTo = "valid_to"
+)
+
+// lookup criteria, at least one of them must be set
+var certCriteria = []string{
+	KeyCertDescription,
+	KeyCertCommonName,
+}
+
+func dataSourceOpnCertificate() *schema.Resource {
+	criterion := func() *schema.Schema {
+		return &schema.Schema{
+			Type:         schema.TypeString,
+			Optional:     true,
+			Computed:     true,
+			AtLeastOneOf: certCriteria,
+		}
+	}
+
+	return &schema.Resource{
+		Read: dataSourceCertificateRead,
+
+		Schema: map[string]*schema.Schema{
+			KeyCertDescription: criterion(),
+			KeyCertCommonName:  criterion(),
+			KeyCertRefID: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyCertUUID: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyCertValidTo: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Certificate expiry date (RFC 3339), empty if unknown",
+			},
+		},
+	}
+}
+
+func dataSourceCertificateRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	trust := pconf.Trust
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	filter := Certificate{
+		Description: d.Get(KeyCertDescription).(string),
+		CommonName:  d.Get(KeyCertCommonName).(string),
+	}
+
+	// lookup for exactly one matching certificate
+	c, err := trust.FindCertificate(&filter)
+	if err != nil {
+		return err
+	}
+
+	validTo := ""
+	if !c.ValidTo.IsZero() {
+		validTo = c.ValidTo.Format(time.RFC3339)
+	}
+
+	// set Terraform data source ID
+	d.SetId(c.RefID)
+
+	// set object params
+	d.Set(KeyCertDescription, c.Description)
+	d.Set(KeyCertCommonName, c.CommonName)
+	d.Set(KeyCertRefID, c.RefID)
+	d.Set(KeyCertUUID, c.UUID)
+	d.Set(KeyCertValidTo, validTo)
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package opnsense
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyLeaseActive corresponds to the associated data source schema key
+	KeyLeaseActive = "lease_active"
+	// KeyLeaseOnline corresponds to the associated data source schema key
+	KeyLeaseOnline = "online"
+	// KeyLeaseLastSeen corresponds to the associated data source schema key
+	KeyLeaseLastSeen = "last_seen"
+)
+
+func dataSourceOpnDHCPStaticMap() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDhcpStaticMapRead,
+
+		Schema: map[string]*schema.Schema{
+			KeyInterface: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyMAC: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.IsMACAddress,
+			},
+			KeyIP: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyName: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyLeaseActive: {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the device currently holds a non-expired lease",
+			},
+			KeyLeaseOnline: {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the device is currently seen online",
+			},
+			KeyLeaseLastSeen: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Start time of the device latest lease, as displayed by OPNsense",
+			},
+		},
+	}
+}
+
+func dataSourceDhcpStaticMapRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dhcp := pconf.DHCP
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	m := StaticMapping{
+		Interface: d.Get(KeyInterface).(string),
+		MAC:       d.Get(KeyMAC).(string),
+	}
+
+	// read out static mapping information
+	err := dhcp.ReadStaticMapping(&m)
+	if err != nil {
+		return err
+	}
+
+	// correlate with leases status by MAC
+	leases, err := dhcp.GetLeases()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve DHCP leases status: %v", err)
+	}
+	l := dhcp.FindLease(leases, m.MAC)
+	if l == nil {
+		l = &DHCPLease{}
+	}
+
+	// set Terraform data source ID
+	d.SetId(fmt.Sprintf("%s/%s", m.Interface, m.MAC))
+
+	// set object params
+	d.Set(KeyIP, m.IP)
+	d.Set(KeyName, m.Hostname)
+	d.Set(KeyLeaseActive, l.Active)
+	d.Set(KeyLeaseOnline, l.Online)
+	d.Set(KeyLeaseLastSeen, l.Start)
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package opnsense
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyDHCPEnabled corresponds to the associated data source schema key
+	KeyDHCPEnabled = "enabled"
+	// KeyDHCPSubnet corresponds to the associated data source schema key
+	KeyDHCPSubnet = "subnet"
+	// KeyDHCPRangeFrom corresponds to the associated data source schema key
+	KeyDHCPRangeFrom = "range_from"
+	// KeyDHCPRangeTo corresponds to the associated data source schema key
+	KeyDHCPRangeTo = "range_to"
+)
+
+func dataSourceOpnDHCPStatus() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDhcpStatusRead,
+
+		Schema: map[string]*schema.Schema{
+			KeyInterface: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyDHCPEnabled: {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			KeyDHCPSubnet: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyDHCPRangeFrom: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyDHCPRangeTo: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceDhcpStatusRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dhcp := pconf.DHCP
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	iface := d.Get(KeyInterface).(string)
+
+	// reject unknown interfaces, whose service page silently falls back to another one
+	ifaces, err := dhcp.ListDHCPInterfaces()
+	if err != nil {
+		return err
+	}
+	known := len(ifaces) == 0
+	for _, i := range ifaces {
+		if i == iface {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("%s: %s (available: %s)", iface, ErrNoSuchDHCPInterface, strings.Join(ifaces, ", "))
+	}
+
+	// read out DHCP server status
+	st, err := dhcp.GetStatus(iface)
+	if err != nil {
+		return err
+	}
+
+	// set Terraform data source ID
+	d.SetId(iface)
+
+	// set object params
+	d.Set(KeyDHCPEnabled, st.Enabled)
+	d.Set(KeyDHCPSubnet, st.Subnet)
+	d.Set(KeyDHCPRangeFrom, st.RangeFrom)
+	d.Set(KeyDHCPRangeTo, st.RangeTo)
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyDHCPPrefixFrom corresponds to the associated data source schema key
+	KeyDHCPPrefixFrom = "prefix_range_from"
+	// KeyDHCPPrefixTo corresponds to the associated data source schema key
+	KeyDHCPPrefixTo = "prefix_range_to"
+	// KeyDHCPPrefixLength corresponds to the associated data source schema key
+	KeyDHCPPrefixLength = "prefix_length"
+)
+
+func dataSourceOpnDHCPv6Prefix() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDhcpv6PrefixRead,
+
+		Schema: map[string]*schema.Schema{
+			KeyInterface: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyDHCPEnabled: {
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			KeyDHCPSubnet: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyDHCPRangeFrom: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyDHCPRangeTo: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyDHCPPrefixFrom: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyDHCPPrefixTo: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyDHCPPrefixLength: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Length (in bits) of the prefixes delegated from the prefix range",
+			},
+		},
+	}
+}
+
+func dataSourceDhcpv6PrefixRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dhcp := pconf.DHCP
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	iface := d.Get(KeyInterface).(string)
+
+	// read out DHCPv6 server status
+	st, err := dhcp.GetDHCPv6Status(iface)
+	if err != nil {
+		return err
+	}
+
+	// set Terraform data source ID
+	d.SetId(iface)
+
+	// set object params
+	d.Set(KeyDHCPEnabled, st.Enabled)
+	d.Set(KeyDHCPSubnet, st.Subnet)
+	d.Set(KeyDHCPRangeFrom, st.RangeFrom)
+	d.Set(KeyDHCPRangeTo, st.RangeTo)
+	d.Set(KeyDHCPPrefixFrom, st.PrefixFrom)
+	d.Set(KeyDHCPPrefixTo, st.PrefixTo)
+	d.Set(KeyDHCPPrefixLength, st.PrefixLength)
+
+	return nil
+}
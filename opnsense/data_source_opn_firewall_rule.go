@@ -0,0 +1,105 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+const (
+	// KeyRuleDescription corresponds to the associated data source schema key
+	KeyRuleDescription = "description"
+	// KeyRuleInterface corresponds to the associated data source schema key
+	KeyRuleInterface = "interface"
+	// KeyRuleAction corresponds to the associated data source schema key
+	KeyRuleAction = "action"
+	// KeyRuleProtocol corresponds to the associated data source schema key
+	KeyRuleProtocol = "protocol"
+	// KeyRuleSource corresponds to the associated data source schema key
+	KeyRuleSource = "source"
+	// KeyRuleDestination corresponds to the associated data source schema key
+	KeyRuleDestination = "destination"
+	// KeyRuleUUID corresponds to the associated data source schema key
+	KeyRuleUUID = "uuid"
+	// KeyRuleSequence corresponds to the associated data source schema key
+	KeyRuleSequence = "sequence"
+)
+
+// lookup criteria, at least one of them must be set
+var ruleCriteria = []string{
+	KeyRuleDescription,
+	KeyRuleInterface,
+	KeyRuleAction,
+	KeyRuleProtocol,
+	KeyRuleSource,
+	KeyRuleDestination,
+}
+
+func dataSourceOpnFirewallRule() *schema.Resource {
+	criterion := func() *schema.Schema {
+		return &schema.Schema{
+			Type:         schema.TypeString,
+			Optional:     true,
+			Computed:     true,
+			AtLeastOneOf: ruleCriteria,
+		}
+	}
+
+	return &schema.Resource{
+		Read: dataSourceFirewallRuleRead,
+
+		Schema: map[string]*schema.Schema{
+			KeyRuleDescription: criterion(),
+			KeyRuleInterface:   criterion(),
+			KeyRuleAction:      criterion(),
+			KeyRuleProtocol:    criterion(),
+			KeyRuleSource:      criterion(),
+			KeyRuleDestination: criterion(),
+			KeyRuleUUID: {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			KeyRuleSequence: {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceFirewallRuleRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	filter := FirewallRule{
+		Description: d.Get(KeyRuleDescription).(string),
+		Interface:   d.Get(KeyRuleInterface).(string),
+		Action:      d.Get(KeyRuleAction).(string),
+		Protocol:    d.Get(KeyRuleProtocol).(string),
+		Source:      d.Get(KeyRuleSource).(string),
+		Destination: d.Get(KeyRuleDestination).(string),
+	}
+
+	// lookup for exactly one matching rule
+	r, err := fw.FindRule(&filter)
+	if err != nil {
+		return err
+	}
+
+	// set Terraform data source ID
+	d.SetId(r.UUID)
+
+	// set object params
+	d.Set(KeyRuleDescription, r.Description)
+	d.Set(KeyRuleInterface, r.Interface)
+	d.Set(KeyRuleAction, r.Action)
+	d.Set(KeyRuleProtocol, r.Protocol)
+	d.Set(KeyRuleSource, r.Source)
+	d.Set(KeyRuleDestination, r.Destination)
+	d.Set(KeyRuleUUID, r.UUID)
+	d.Set(KeyRuleSequence, r.Sequence)
+
+	return nil
+}
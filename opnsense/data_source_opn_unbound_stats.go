@@ -0,0 +1,74 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+const (
+	// KeyStatsQueries corresponds to the associated data source schema key
+	KeyStatsQueries = "queries"
+	// KeyStatsCacheHits corresponds to the associated data source schema key
+	KeyStatsCacheHits = "cache_hits"
+	// KeyStatsCacheMisses corresponds to the associated data source schema key
+	KeyStatsCacheMisses = "cache_misses"
+	// KeyStatsPrefetches corresponds to the associated data source schema key
+	KeyStatsPrefetches = "prefetches"
+	// KeyStatsRecursiveReplies corresponds to the associated data source schema key
+	KeyStatsRecursiveReplies = "recursive_replies"
+)
+
+func dataSourceOpnUnboundStats() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceUnboundStatsRead,
+
+		Schema: map[string]*schema.Schema{
+			KeyStatsQueries: {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			KeyStatsCacheHits: {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			KeyStatsCacheMisses: {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			KeyStatsPrefetches: {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			KeyStatsRecursiveReplies: {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceUnboundStatsRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// read out Unbound counters
+	st, err := dns.ReadUnboundStats()
+	if err != nil {
+		return err
+	}
+
+	// set Terraform data source ID
+	d.SetId("unbound")
+
+	// set object params
+	d.Set(KeyStatsQueries, int(st.Queries))
+	d.Set(KeyStatsCacheHits, int(st.CacheHits))
+	d.Set(KeyStatsCacheMisses, int(st.CacheMisses))
+	d.Set(KeyStatsPrefetches, int(st.Prefetches))
+	d.Set(KeyStatsRecursiveReplies, int(st.RecursiveReplies))
+
+	return nil
+}
@@ -5,8 +5,15 @@ import (
 	"github.com/antchfx/htmlquery"
 	"github.com/asmcos/requests"
 	"golang.org/x/net/html"
+	"log"
+	"net"
+	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // DHCPEntryStartingRow exposes the HTML row where static maps actually start from
@@ -39,55 +46,175 @@ const (
 	ErrMACExists = "mapping for this MAC already exists"
 	// ErrNoSuchMAC is thrown if no mapping can be found for the specific Interface/MAC couple
 	ErrNoSuchMAC = "mapping doesn't exists for this MAC address"
+	// ErrDisableUnsupported is thrown if the OPNSense version doesn't allow disabling static mappings
+	ErrDisableUnsupported = "this OPNSense version doesn't support disabling static mappings"
+	// ErrPXEUnsupported is thrown if network boot settings are set on a backend which doesn't support them
+	ErrPXEUnsupported = "network boot settings are not supported by Kea reservations"
+	// ErrPoolUnsupported is thrown if a pool is requested while the static mapping edit page doesn't expose any
+	ErrPoolUnsupported = "this OPNSense version doesn't support binding static mappings to a DHCP pool"
+	// ErrNoSuchDHCPInterface is thrown if the DHCP service can't be configured on the requested interface
+	ErrNoSuchDHCPInterface = "DHCP service can't be configured on this interface"
+	// ErrIPExists is thrown when another mapping already holds this IP address
+	ErrIPExists = "IP address is already assigned to another mapping"
+	// ErrIPOutOfSubnet is thrown when a mapping IP address doesn't belong to the interface subnet
+	ErrIPOutOfSubnet = "IP address doesn't belong to the interface subnet"
+	// ErrInvalidMapping is thrown when a static mapping field is malformed
+	ErrInvalidMapping = "invalid static mapping"
+	// ErrNoSuchMapping is thrown if no mapping can be found for the specific Interface/IP couple
+	ErrNoSuchMapping = "mapping doesn't exists for this IP address"
+	// ErrMappingNotSaved is thrown if OPNSense keeps silently rejecting a static mapping submission
+	ErrMappingNotSaved = "static mapping submission has been rejected (stale form secret)"
+	// ErrDHCPOptionsUnsupported is thrown if additional DHCP options are set while the static mapping edit page doesn't expose any
+	ErrDHCPOptionsUnsupported = "this OPNSense version doesn't support additional DHCP options on static mappings"
+	// ErrMultiMACUnsupported is thrown if additional MAC addresses are set while the static mapping edit page doesn't expose any
+	ErrMultiMACUnsupported = "this OPNSense version doesn't support several MAC addresses per static mapping"
 )
 
+// DHCPPartialReadRetries is the number of times a partially rendered static mappings page is fetched again
+const DHCPPartialReadRetries = 2
+
+// DHCPPartialReadRetryDelay is the delay before fetching again a partially rendered static mappings page
+var DHCPPartialReadRetryDelay = time.Second
+
+// DHCPOptionMaxNumber is the highest DHCP option number which can be set
+const DHCPOptionMaxNumber = 254
+
+// DHCPOptionTypes lists the value types of additional DHCP options
+var DHCPOptionTypes = []string{
+	"text",
+	"string",
+	"boolean",
+	"unsigned integer 8",
+	"unsigned integer 16",
+	"unsigned integer 32",
+	"signed integer 8",
+	"signed integer 16",
+	"signed integer 32",
+	"ip-address",
+}
+
 // DHCPSession abstracts OPNSense DHCP Interface
 type DHCPSession struct {
-	OPN    *OPNSession
-	Fields []string
+	OPN     *OPNSession
+	Fields  []string
+	Index   map[string]int
+	Backend string
+	// Debug enables parsing diagnostics, when OPNSense markup doesn't match expectations
+	Debug bool
+	// Diagnostic describes the last suspicious static mappings page parsing, in debug mode only
+	Diagnostic *ParseDiagnostic
+	cache      map[string][]StaticMapping
+	// mu guards lazily discovered state (table fields, backend, cache)
+	// against concurrent resource operations
+	mu sync.Mutex
+	// leasesMu guards cached leases, held while fetching them so that it's done once
+	leasesMu sync.Mutex
+	leases   []DHCPLease
+	leasesAt time.Time
+}
+
+// ParseDiagnostic describes a WebUI table whose rows couldn't be parsed into entries
+type ParseDiagnostic struct {
+	URI     string
+	Headers []string
+	Rows    int
+	Entries int
+	HTML    string
+}
+
+// String formats a diagnostic so that it can be attached as is to a bug report
+func (d *ParseDiagnostic) String() string {
+	return fmt.Sprintf("page %s: %d table row(s) for %d parsed entries, headers %q, table HTML:\n%s",
+		d.URI, d.Rows, d.Entries, d.Headers, d.HTML)
 }
 
 // StaticMapping abstracts a static DHCP mapping entry
 type StaticMapping struct {
-	ID        int
+	ID         int
+	UUID       string
+	Interface  string
+	IP         string
+	MAC        string
+	Hostname   string
+	Disabled   bool
+	NextServer string
+	Filename   string
+	RootPath   string
+	Pool       string
+	// MACs are additional MAC addresses of the same device, if supported
+	MACs []string
+	// Options are additional numbered DHCP options (e.g. 66/67 for network boot), if supported
+	Options []DHCPOption
+}
+
+// DHCPOption abstracts an additional numbered DHCP option of a static mapping
+type DHCPOption struct {
+	Number int
+	Type   string
+	Value  string
+}
+
+// DHCPStatus abstracts the DHCP server configuration of a given interface
+type DHCPStatus struct {
 	Interface string
-	IP        string
-	MAC       string
-	Hostname  string
+	Enabled   bool
+	Subnet    string
+	RangeFrom string
+	RangeTo   string
 }
 
 // GetStaticFieldNames extracts the HTML page leases headers for creation/edition
-func (s *DHCPSession) GetStaticFieldNames(node *html.Node, start int) {
+func (s *DHCPSession) GetStaticFieldNames(node *html.Node, start int) error {
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(node, `//table[@class="table table-striped"]`) == nil {
+		return s.OPN.UnexpectedPage(node, "leases table")
+	}
+	q := fmt.Sprintf(`//table[@class="table table-striped"]//tr[%d]`, start)
+	headers := htmlquery.FindOne(node, q)
+	if headers == nil {
+		return s.OPN.UnexpectedPage(node, "leases table headers")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if len(s.Fields) > 0 {
 		// already filled-in, no need to go any further
-		return
+		return nil
 	}
 
-	q := fmt.Sprintf(`//table[@class="table table-striped"]//tr[%d]`, start)
-	headers := htmlquery.FindOne(node, q)
-	s.Fields = []string{}
+	// fields are only published once complete, concurrent parsers rely on them
+	fields := []string{}
+	index := map[string]int{}
 	for child := headers.FirstChild; child != nil; child = child.NextSibling {
 		if child.Type == html.ElementNode {
-			content := strings.TrimSpace(htmlquery.InnerText(child))
+			content := s.OPN.Canonical(NormalizeText(htmlquery.InnerText(child)))
 			if len(content) > 0 {
-				s.Fields = append(s.Fields, content)
+				index[content] = len(fields)
+				fields = append(fields, content)
 			}
 		}
 	}
+	s.Fields = fields
+	s.Index = index
+
+	return nil
 }
 
 // GetStaticMappingField extracts a given DHCP mapping from OPNsense DHCP interface web page
 func (s *DHCPSession) GetStaticMappingField(node *html.Node, f string) string {
 	res := ""
 
-	// find the requested field index in HTML table
-	id := index(s.Fields, f) + 1
-	if id == -1 {
+	// find the requested field index in HTML table, under the lock it's discovered with
+	s.mu.Lock()
+	id, ok := s.Index[f]
+	s.mu.Unlock()
+	if !ok {
 		return res
 	}
 
-	// XPath query to find the associated HTML node
-	q := fmt.Sprintf(`//td[%d]//text()`, id)
+	// XPath query to find the associated HTML node (XPath indexes start at 1)
+	q := fmt.Sprintf(`//td[%d]//text()`, id+1)
 	values, err := htmlquery.QueryAll(node, q)
 	if err != nil {
 		return res
@@ -98,13 +225,17 @@ func (s *DHCPSession) GetStaticMappingField(node *html.Node, f string) string {
 		if v.Type != html.TextNode {
 			continue
 		}
-		content := strings.TrimSpace(htmlquery.InnerText(v))
+		content := NormalizeText(htmlquery.InnerText(v))
 		if f == DHCPMAC {
 			re := "([0-9a-f]{2}(?::[0-9a-f]{2}){5})"
 			matched, err := regexp.Match(re, []byte(content))
 			if err != nil || !matched {
 				continue
 			}
+			// devices may be mapped through several MAC addresses
+			if res != "" {
+				res = res + ","
+			}
 		}
 		if f == DHCPHostname {
 			if content == "" {
@@ -117,6 +248,45 @@ func (s *DHCPSession) GetStaticMappingField(node *html.Node, f string) string {
 	return res
 }
 
+// GetAllStaticMappings retrieves the static mappings of all interfaces DHCP can be
+// configured on, per interface. Interfaces which can't be read are reported altogether
+// in the returned error, along with the mappings of all other ones.
+func (s *DHCPSession) GetAllStaticMappings() (map[string][]StaticMapping, error) {
+
+	all := map[string][]StaticMapping{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return all, err
+	}
+
+	ifaces, err := s.ListDHCPInterfaces()
+	if err != nil {
+		return all, err
+	}
+
+	// Kea reservations are fetched only once, and dispatched to the interfaces of their subnet
+	if s.IsKea() {
+		return s.keaGetReservations(ifaces)
+	}
+
+	failures := []string{}
+	for _, iface := range ifaces {
+		entries, err := s.GetAllInterfaceStaticMappings(iface)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", iface, err))
+			continue
+		}
+		all[iface] = entries
+	}
+	if len(failures) > 0 {
+		return all, fmt.Errorf("%s on %d interface(s): %s", ErrNoMappings, len(failures), strings.Join(failures, "; "))
+	}
+
+	return all, nil
+}
+
 // GetAllInterfaceStaticMappings retrieves the list of all configured static mappings for a given interface
 func (s *DHCPSession) GetAllInterfaceStaticMappings(iface string) ([]StaticMapping, error) {
 
@@ -128,22 +298,108 @@ func (s *DHCPSession) GetAllInterfaceStaticMappings(iface string) ([]StaticMappi
 		return entries, err
 	}
 
+	// Kea backend is driven through its API
+	if s.IsKea() {
+		return s.keaGetAllReservations(iface)
+	}
+
 	// read out the service page
-	dhcpURI := fmt.Sprintf("%s%s?if=%s", s.OPN.RootURI, DHCPServiceURI, iface)
-	resp, err := s.OPN.Session.Get(dhcpURI)
+	dhcpURI := s.OPN.URL(fmt.Sprintf("%s?if=%s", DHCPServiceURI, iface))
+	doc, err := s.OPN.GetPage(dhcpURI)
 	if err != nil {
 		return entries, err
 	}
 
-	// get HTML
-	page := strings.NewReader(resp.Text())
-	doc, err := htmlquery.Parse(page)
+	return s.ParseStaticMappings(doc, iface)
+}
+
+// ListDHCPInterfaces retrieves the sorted list of interfaces the DHCP service can be configured on,
+// as exposed by the service page interface tabs
+func (s *DHCPSession) ListDHCPInterfaces() ([]string, error) {
+
+	ifaces := []string{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
 	if err != nil {
-		return entries, err
+		return ifaces, err
+	}
+
+	// Kea backend is driven through its API
+	if s.IsKea() {
+		return s.keaListInterfaces()
+	}
+
+	// read out the service page
+	doc, err := s.OPN.GetPage(s.OPN.URL(DHCPServiceURI))
+	if err != nil {
+		return ifaces, err
+	}
+
+	// each interface tab links to its own service page
+	seen := map[string]bool{}
+	links := htmlquery.Find(doc, fmt.Sprintf(`//a[contains(@href, "%s?")]`, DHCPServiceURI))
+	for _, l := range links {
+		ref, err := url.Parse(htmlquery.SelectAttr(l, "href"))
+		if err != nil {
+			continue
+		}
+		iface := ref.Query().Get("if")
+		if iface != "" && !seen[iface] {
+			seen[iface] = true
+			ifaces = append(ifaces, iface)
+		}
+	}
+	sort.Strings(ifaces)
+
+	return ifaces, nil
+}
+
+// Refresh fetches all static mappings of a given interface once and keeps them for further use through Cached()
+func (s *DHCPSession) Refresh(iface string) error {
+	entries, err := s.GetAllInterfaceStaticMappings(iface)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache == nil {
+		s.cache = map[string][]StaticMapping{}
+	}
+	s.cache[iface] = entries
+
+	return nil
+}
+
+// Cached returns the static mappings retrieved by the latest Refresh() calls, per interface
+func (s *DHCPSession) Cached() map[string][]StaticMapping {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := map[string][]StaticMapping{}
+	for iface, entries := range s.cache {
+		res[iface] = append([]StaticMapping{}, entries...)
 	}
+	return res
+}
+
+// Invalidate drops cached static mappings of a given interface
+func (s *DHCPSession) Invalidate(iface string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, iface)
+}
+
+// ParseStaticMappings extracts all static mappings from an interface service page
+func (s *DHCPSession) ParseStaticMappings(doc *html.Node, iface string) ([]StaticMapping, error) {
+
+	entries := []StaticMapping{}
 
 	// lookup for static fields types
-	s.GetStaticFieldNames(doc, DHCPEntryStartingRow)
+	err := s.GetStaticFieldNames(doc, DHCPEntryStartingRow)
+	if err != nil {
+		return entries, err
+	}
 
 	// XPath query to find all table rows
 	q := fmt.Sprintf(`//table[@class="table table-striped"]//tr`)
@@ -155,86 +411,270 @@ func (s *DHCPSession) GetAllInterfaceStaticMappings(iface string) ([]StaticMappi
 	// retrieve all configured static DHCP mappings
 	for i := DHCPEntryStartingRow; i < len(rows); i++ {
 		r := rows[i]
+
+		// rely on OPNSense internal ID, table position being a fallback for older markups
+		id := RowID(r, DHCPServiceEditURI)
+		if id < 0 {
+			id = i - DHCPEntryStartingRow
+		}
+
+		// first MAC address is the primary one
+		macs := strings.Split(s.GetStaticMappingField(r, DHCPMAC), ",")
 		m := StaticMapping{
-			ID:        i - DHCPEntryStartingRow,
+			ID:        id,
 			Interface: iface,
 			IP:        s.GetStaticMappingField(r, DHCPIP),
-			MAC:       s.GetStaticMappingField(r, DHCPMAC),
+			MAC:       macs[0],
+			MACs:      macs[1:],
 			Hostname:  s.GetStaticMappingField(r, DHCPHostname),
 		}
 		entries = append(entries, m)
 	}
 
+	// rows have been found but nothing could be made out of them, markup likely drifted
+	if s.Debug {
+		parsed := 0
+		for _, m := range entries {
+			if m.MAC != "" || m.IP != "" {
+				parsed++
+			}
+		}
+		if parsed == 0 && len(rows) > DHCPEntryStartingRow {
+			table := htmlquery.FindOne(doc, `//table[@class="table table-striped"]`)
+			s.mu.Lock()
+			diag := &ParseDiagnostic{
+				URI:     s.OPN.URL(fmt.Sprintf("%s?if=%s", DHCPServiceURI, iface)),
+				Headers: s.Fields,
+				Rows:    len(rows) - DHCPEntryStartingRow,
+				Entries: parsed,
+				HTML:    htmlquery.OutputHTML(table, true),
+			}
+			s.Diagnostic = diag
+			s.mu.Unlock()
+			log.Printf("[WARN] OPNSense static mappings parsing mismatch, %s", diag)
+		}
+	}
+
 	return entries, nil
 }
 
-// Apply validates the configuration for a given interface and reload DHCP server
-func (s *DHCPSession) Apply(iface, formName, formValue string) error {
+// MappingIDFromPage looks up a mapping ID from the service page returned after its creation, -1 if not found
+func (s *DHCPSession) MappingIDFromPage(page string, m *StaticMapping) int {
+	doc, err := htmlquery.Parse(strings.NewReader(page))
+	if err != nil || htmlquery.FindOne(doc, `//table[@class="table table-striped"]`) == nil {
+		return -1
+	}
+
+	entries, err := s.ParseStaticMappings(doc, m.Interface)
+	if err != nil {
+		return -1
+	}
+
+	for _, e := range entries {
+		if e.HasMAC(m.MAC) {
+			return e.ID
+		}
+	}
+
+	return -1
+}
+
+// Apply validates the configuration for a given interface and reload DHCP server,
+// unless changes are deferred until an explicit Commit
+func (s *DHCPSession) Apply(iface, page string) (*ApplyResult, error) {
+	if s.OPN.DeferApply {
+		log.Printf("[DEBUG] OPNSense DHCP changes on %s deferred", iface)
+		return &ApplyResult{Pending: true}, nil
+	}
+	return s.Commit(iface, page)
+}
+
+// Commit validates the configuration for a given interface and reload DHCP server
+func (s *DHCPSession) Commit(iface, page string) (*ApplyResult, error) {
+	// Kea backend is driven through its API
+	if s.IsKea() {
+		err := s.keaApply()
+		return &ApplyResult{Pending: true, Applied: err == nil}, err
+	}
+
 	// apply changes
 	data := requests.Datas{
 		"apply": "Apply changes",
 		"if":    iface,
 	}
-	if formName != "" || formValue != "" {
-		data[formName] = formValue
-	}
 
-	applyURI := fmt.Sprintf("%s%s?if=%s", s.OPN.RootURI, DHCPServiceURI, iface)
-	_, err := s.OPN.Session.Post(applyURI, data)
-	if err != nil {
-		return err
-	}
-	return nil
+	applyURI := s.OPN.URL(fmt.Sprintf("%s?if=%s", DHCPServiceURI, iface))
+	return s.OPN.ApplyChanges(applyURI, page, data)
 }
 
-// CreateOrEdit creates or edit a static mapping
-func (s *DHCPSession) CreateOrEdit(m *StaticMapping) error {
+// postStaticMapping submits the static mapping edit form, returning the resulting page
+func (s *DHCPSession) postStaticMapping(m *StaticMapping) (string, error) {
 
 	// get the edit page to retrieve form secret values
-	editURI := fmt.Sprintf("%s%s?if=%s", s.OPN.RootURI, DHCPServiceEditURI, m.Interface)
+	editURI := s.OPN.URL(fmt.Sprintf("%s?if=%s", DHCPServiceEditURI, m.Interface))
 	if m.ID != -1 {
 		editURI = fmt.Sprintf("%s&id=%d", editURI, m.ID)
 	}
-	resp, err := s.OPN.Session.Get(editURI)
+	if m.Pool != "" {
+		editURI = fmt.Sprintf("%s&pool=%s", editURI, url.QueryEscape(m.Pool))
+	}
+	resp, err := s.OPN.Get(editURI)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// get HTML
-	page := strings.NewReader(resp.Text())
-	doc, err := htmlquery.Parse(page)
+	// not all OPNSense versions can disable a mapping without deleting it
+	doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
 	if err != nil {
-		return err
+		return "", err
+	}
+	canDisable := htmlquery.FindOne(doc, `//input[@name="disabled"]`) != nil
+	if m.Disabled && !canDisable {
+		return "", s.OPN.Error(ErrDisableUnsupported)
 	}
 
-	// get form runtime values
-	q := fmt.Sprintf(`//div[@class="content-box"]//form//input`)
-	n := htmlquery.FindOne(doc, q)
-	formName := htmlquery.SelectAttr(n, "name")
-	formValue := htmlquery.SelectAttr(n, "value")
+	// mappings are bound to the interface primary pool, unless the edit page allows otherwise
+	if m.Pool != "" && htmlquery.FindOne(doc, `//*[@name="pool"]`) == nil {
+		return "", s.OPN.Error(ErrPoolUnsupported)
+	}
+
+	// mappings hold a single MAC address, unless the edit page allows otherwise
+	canMultiMAC := htmlquery.FindOne(doc, `//*[@name="macs"]`) != nil
+	if len(m.MACs) > 0 && !canMultiMAC {
+		return "", s.OPN.Error(ErrMultiMACUnsupported)
+	}
+
+	// additional DHCP options are only posted if the edit page exposes them
+	canOptions := htmlquery.FindOne(doc, `//*[@name="numberoptions_number[]"]`) != nil
+	if len(m.Options) > 0 && !canOptions {
+		return "", s.OPN.Error(ErrDHCPOptionsUnsupported)
+	}
+
+	// keep all settings we don't manage as they currently are
+	data := FormValues(doc)
+	delete(data, "disabled")
+	for k := range data {
+		if strings.HasPrefix(k, "numberoptions_") {
+			delete(data, k)
+		}
+	}
 
 	// create a new DHCP entry
-	data := requests.Datas{
-		formName:   formValue,
-		"mac":      m.MAC,
-		"cid":      m.Hostname,
-		"ipaddr":   m.IP,
-		"hostname": m.Hostname,
-		"descr":    m.Hostname,
-		"Submit":   "Save",
-		"if":       m.Interface,
+	data["mac"] = m.MAC
+	data["cid"] = m.Hostname
+	data["ipaddr"] = m.IP
+	data["hostname"] = m.Hostname
+	data["descr"] = m.Hostname
+	data["Submit"] = "Save"
+	data["if"] = m.Interface
+	data["nextserver"] = m.NextServer
+	data["filename"] = m.Filename
+	data["rootpath"] = m.RootPath
+	if m.Pool != "" {
+		data["pool"] = m.Pool
+	}
+	if canMultiMAC {
+		data["macs"] = strings.Join(m.MACs, ",")
+	}
+	for i, o := range m.Options {
+		data[fmt.Sprintf("numberoptions_number[%d]", i)] = fmt.Sprintf("%d", o.Number)
+		data[fmt.Sprintf("numberoptions_type[%d]", i)] = o.Type
+		data[fmt.Sprintf("numberoptions_value[%d]", i)] = o.Value
 	}
 	if m.ID != -1 {
 		data["id"] = fmt.Sprintf("%d", m.ID)
 	}
+	if m.Disabled {
+		data["disabled"] = "yes"
+	}
 
-	resp, err = s.OPN.Session.Post(editURI, data)
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text(), nil
+}
+
+// mappingSaved checks whether a submitted static mapping has been saved, i.e. that we've
+// been redirected to the service page rather than shown the edit form again, and
+// that a newly created entry can be found (setting its ID accordingly)
+func (s *DHCPSession) mappingSaved(page string, m *StaticMapping) bool {
+	doc, err := htmlquery.Parse(strings.NewReader(page))
+	if err == nil && htmlquery.FindOne(doc, `//form//input[@name="mac"]`) != nil {
+		return false
+	}
+
+	if m.ID != -1 {
+		return true
+	}
+
+	// we've been redirected to the service page, which exposes the newly created entry
+	m.ID = s.MappingIDFromPage(page, m)
+	if m.ID != -1 {
+		return true
+	}
+
+	// otherwise, look it up
+	s.Invalidate(m.Interface)
+	e, _ := s.FindMappingByMAC(m)
+	if e == nil {
+		return false
+	}
+	m.ID = e.ID
+
+	return true
+}
+
+// CreateOrEdit creates or edit a static mapping
+func (s *DHCPSession) CreateOrEdit(m *StaticMapping) error {
+
+	// cached mappings are about to be outdated
+	s.Invalidate(m.Interface)
+
+	// Kea backend is driven through its API
+	if s.IsKea() {
+		if m.NextServer != "" || m.Filename != "" || m.RootPath != "" {
+			return s.OPN.Error(ErrPXEUnsupported)
+		}
+		if m.Pool != "" {
+			return s.OPN.Error(ErrPoolUnsupported)
+		}
+		if len(m.MACs) > 0 {
+			return s.OPN.Error(ErrMultiMACUnsupported)
+		}
+		if len(m.Options) > 0 {
+			return s.OPN.Error(ErrDHCPOptionsUnsupported)
+		}
+		return s.keaCreateOrEdit(m)
+	}
+
+	// the edit page form secret may be rotated (or the session refreshed) between
+	// both requests, in which case OPNSense silently redisplays the form: retry once
+	var page string
+	for attempt := 1; ; attempt++ {
+		var err error
+		page, err = s.postStaticMapping(m)
+		if err != nil {
+			return err
+		}
+		if s.mappingSaved(page, m) {
+			break
+		}
+		if attempt == 2 {
+			return s.OPN.Error(ErrMappingNotSaved)
+		}
+		log.Printf("[WARN] OPNSense silently rejected static mapping %s on %s, retrying", m.MAC, m.Interface)
 	}
 
 	// apply changes
-	err = s.Apply(m.Interface, formName, formValue)
+	_, err := s.Apply(m.Interface, page)
 	if err != nil {
 		return err
 	}
@@ -242,7 +682,93 @@ func (s *DHCPSession) CreateOrEdit(m *StaticMapping) error {
 	return nil
 }
 
-// FindMappingByMAC retrieves all entries for a given interface and select the one that matches
+// ReadDetails retrieves the mapping settings only exposed by its edit page
+func (s *DHCPSession) ReadDetails(m *StaticMapping) error {
+	if s.IsKea() {
+		// Kea reservations can't be disabled nor hold network boot settings
+		m.Disabled = false
+		return nil
+	}
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?if=%s&id=%d", DHCPServiceEditURI, m.Interface, m.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	m.Disabled = htmlquery.FindOne(doc, `//input[@name="disabled"][@checked]`) != nil
+	m.NextServer = InputValue(doc, "nextserver")
+	m.Filename = InputValue(doc, "filename")
+	m.RootPath = InputValue(doc, "rootpath")
+	m.Pool = SelectedValue(doc, "pool")
+	if m.Pool == "" {
+		m.Pool = InputValue(doc, "pool")
+	}
+	if htmlquery.FindOne(doc, `//*[@name="macs"]`) != nil {
+		m.MACs = strings.FieldsFunc(InputValue(doc, "macs"), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\n'
+		})
+	}
+	m.Options = dhcpOptions(doc)
+
+	return nil
+}
+
+// dhcpOptions extracts the additional DHCP options of a static mapping edit page,
+// skipping blank rows (e.g. the one used as a template for new options)
+func dhcpOptions(doc *html.Node) []DHCPOption {
+	options := []DHCPOption{}
+
+	numbers := htmlquery.Find(doc, `//input[@name="numberoptions_number[]"]`)
+	types := htmlquery.Find(doc, `//select[@name="numberoptions_type[]"]`)
+	values := htmlquery.Find(doc, `//input[@name="numberoptions_value[]"]`)
+	for i, n := range numbers {
+		number, err := strconv.Atoi(htmlquery.SelectAttr(n, "value"))
+		if err != nil {
+			continue
+		}
+		o := DHCPOption{Number: number}
+		if i < len(types) {
+			if t := htmlquery.FindOne(types[i], `./option[@selected]`); t != nil {
+				o.Type = htmlquery.SelectAttr(t, "value")
+			}
+		}
+		if i < len(values) {
+			o.Value = htmlquery.SelectAttr(values[i], "value")
+		}
+		options = append(options, o)
+	}
+
+	return options
+}
+
+// SameAs tells whether two mappings of the same MAC address bind it alike,
+// empty hostnames being displayed as "default" by OPNSense
+func (m *StaticMapping) SameAs(o *StaticMapping) bool {
+	hostname := func(h string) string {
+		if h == "" {
+			return "default"
+		}
+		return h
+	}
+	return strings.EqualFold(m.MAC, o.MAC) && m.IP == o.IP && hostname(m.Hostname) == hostname(o.Hostname)
+}
+
+// HasMAC tells whether a mapping binds a MAC address, be it its primary or an additional one
+func (m *StaticMapping) HasMAC(mac string) bool {
+	if strings.EqualFold(m.MAC, mac) {
+		return true
+	}
+	for _, o := range m.MACs {
+		if strings.EqualFold(o, mac) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindMappingByMAC retrieves all entries for a given interface and select the one that
+// matches any of the MAC addresses
 func (s *DHCPSession) FindMappingByMAC(m *StaticMapping) (*StaticMapping, error) {
 
 	// retrieves existing mappings
@@ -251,25 +777,125 @@ func (s *DHCPSession) FindMappingByMAC(m *StaticMapping) (*StaticMapping, error)
 		return nil, err
 	}
 
+	return s.findMappingByMAC(entries, m)
+}
+
+// findMappingByMAC selects among entries the one that matches any of the MAC addresses
+func (s *DHCPSession) findMappingByMAC(entries []StaticMapping, m *StaticMapping) (*StaticMapping, error) {
+
 	// check if an entry existing for this MAC
 	for _, e := range entries {
 		// we found it
-		if strings.EqualFold(e.MAC, m.MAC) {
+		if e.HasMAC(m.MAC) {
 			return &e, nil
 		}
+		for _, mac := range m.MACs {
+			if e.HasMAC(mac) {
+				return &e, nil
+			}
+		}
 	}
 
 	return nil, s.OPN.Error(ErrNoSuchMAC)
 }
 
+// FindMappingByIP retrieves all entries for a given interface and select the one that matches the IP address
+func (s *DHCPSession) FindMappingByIP(iface, ip string) (*StaticMapping, error) {
+
+	// retrieves existing mappings
+	entries, err := s.GetAllInterfaceStaticMappings(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	// check if an entry existing for this IP
+	for _, e := range entries {
+		// we found it
+		if e.IP == ip {
+			return &e, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchMapping)
+}
+
+// ValidateMapping checks, without writing anything, whether a static mapping can be created:
+// its fields must be well-formed, its MAC addresses not mapped differently, its IP address
+// neither held by another mapping nor out of the interface subnet. All problems are reported at once.
+func (s *DHCPSession) ValidateMapping(m *StaticMapping) []error {
+	errs := []error{}
+
+	// malformed fields
+	if strings.TrimSpace(m.Interface) == "" {
+		errs = append(errs, fmt.Errorf("%s: interface is empty", ErrInvalidMapping))
+	}
+	for _, mac := range append([]string{m.MAC}, m.MACs...) {
+		if _, err := net.ParseMAC(mac); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q isn't a MAC address", ErrInvalidMapping, mac))
+		}
+	}
+	ip := net.ParseIP(m.IP)
+	if ip == nil {
+		errs = append(errs, fmt.Errorf("%s: %q isn't an IP address", ErrInvalidMapping, m.IP))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	// conflicts with existing mappings
+	entries, err := s.GetAllInterfaceStaticMappings(m.Interface)
+	if err != nil {
+		return append(errs, err)
+	}
+	for _, e := range entries {
+		sameDevice := e.HasMAC(m.MAC)
+		for _, mac := range m.MACs {
+			sameDevice = sameDevice || e.HasMAC(mac)
+		}
+		if sameDevice && !e.SameAs(m) {
+			errs = append(errs, fmt.Errorf("%s: %s (mapped to %s)", ErrMACExists, e.MAC, e.IP))
+		}
+		if !sameDevice && net.ParseIP(e.IP).Equal(ip) {
+			errs = append(errs, fmt.Errorf("%s: %s (mapped to %s)", ErrIPExists, m.IP, e.MAC))
+		}
+	}
+
+	// Kea attaches reservations to the subnet containing their IP, which must be one of the interface's
+	if s.IsKea() {
+		_, err := s.keaSubnet(m.Interface, m.IP)
+		if err != nil && err.Error() == ErrKeaNoSubnet {
+			err = fmt.Errorf("%s: %s isn't in any Kea subnet of %s", ErrIPOutOfSubnet, m.IP, m.Interface)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return errs
+	}
+	st, err := s.GetStatus(m.Interface)
+	if err != nil {
+		return append(errs, err)
+	}
+	_, subnet, err := net.ParseCIDR(st.Subnet)
+	if err == nil && !subnet.Contains(ip) {
+		errs = append(errs, fmt.Errorf("%s: %s isn't in %s", ErrIPOutOfSubnet, m.IP, subnet))
+	}
+
+	return errs
+}
+
 // CreateStaticMapping creates a new static lease
 func (s *DHCPSession) CreateStaticMapping(m *StaticMapping) error {
 
 	e, err := s.FindMappingByMAC(m)
 
-	// check if the MAC address is not already registered
+	// check if the MAC address is not already registered, adopting identical mappings
 	if e != nil {
-		return s.OPN.Error(ErrMACExists)
+		if !e.SameAs(m) {
+			return s.OPN.Error(ErrMACExists)
+		}
+		m.ID = e.ID
+		m.UUID = e.UUID
+		return nil
 	}
 
 	// create the mapping entry
@@ -279,24 +905,49 @@ func (s *DHCPSession) CreateStaticMapping(m *StaticMapping) error {
 		return err
 	}
 
+	// fall back to a lookup if the ID couldn't be found out from creation
+	// (Kea reservations being identified by their UUID instead)
+	if m.ID == -1 && m.UUID == "" {
+		e, err = s.FindMappingByMAC(m)
+		if e == nil {
+			return err
+		}
+		m.ID = e.ID
+	}
+
 	return nil
 }
 
 // ReadStaticMapping retrieves mapping information for a specified Interface/MAC couple
 func (s *DHCPSession) ReadStaticMapping(m *StaticMapping) error {
 
-	// check if an entry existing for this Interface/MAC couple
-	e, err := s.FindMappingByMAC(m)
-	if e == nil {
-		return err
+	// OPNSense may transiently serve a partially rendered page, which must not be mistaken
+	// for the mapping being gone: fetch it again, until the mapping shows up or retries run
+	// out, as a page cut short between rows still looks complete
+	var e *StaticMapping
+	var err error
+	for attempt := 0; ; attempt++ {
+		var entries []StaticMapping
+		entries, err = s.GetAllInterfaceStaticMappings(m.Interface)
+		if err == nil {
+			// check if an entry existing for this Interface/MAC couple
+			e, err = s.findMappingByMAC(entries, m)
+		}
+		if e != nil {
+			break
+		}
+		retry := strings.HasPrefix(err.Error(), ErrUnexpectedPage) || err.Error() == ErrNoSuchMAC
+		if !retry || attempt == DHCPPartialReadRetries {
+			return err
+		}
+		log.Printf("[WARN] No static mapping for %s in %s page, fetching it again (%d/%d): %v", m.MAC, m.Interface, attempt+1, DHCPPartialReadRetries, err)
+		time.Sleep(DHCPPartialReadRetryDelay)
 	}
 
-	// assign values accordingly
-	m.ID = e.ID
-	m.IP = e.IP
-	m.Hostname = e.Hostname
+	// assign all values accordingly, so that every field reflects live state
+	*m = *e
 
-	return nil
+	return s.ReadDetails(m)
 }
 
 // UpdateStaticMapping modifies an already existing static mapping
@@ -310,6 +961,7 @@ func (s *DHCPSession) UpdateStaticMapping(m *StaticMapping) error {
 
 	// update the mapping entry
 	m.ID = e.ID
+	m.UUID = e.UUID
 	err = s.CreateOrEdit(m)
 	if err != nil {
 		return err
@@ -323,12 +975,46 @@ func (s *DHCPSession) DeleteStaticMapping(m *StaticMapping) error {
 
 	// check if an entry existing for this Interface/MAC couple
 	e, err := s.FindMappingByMAC(m)
+
+	// the MAC address may have changed out-of-band, fall back to the known IP address
+	if e == nil && err != nil && err.Error() == ErrNoSuchMAC && m.IP != "" {
+		log.Printf("[WARN] No static mapping for %s on %s, looking it up by IP address %s", m.MAC, m.Interface, m.IP)
+		e, err = s.FindMappingByIP(m.Interface, m.IP)
+	}
 	if e == nil {
 		return err
 	}
 
+	return s.deleteMapping(e)
+}
+
+// DeleteStaticMappingByIP destroy an existing static mapping for the specific Interface/IP couple
+func (s *DHCPSession) DeleteStaticMappingByIP(iface, ip string) error {
+
+	// check if an entry existing for this Interface/IP couple
+	e, err := s.FindMappingByIP(iface, ip)
+	if e == nil {
+		return err
+	}
+
+	return s.deleteMapping(e)
+}
+
+// deleteMapping destroys a static mapping found out from the live configuration
+func (s *DHCPSession) deleteMapping(e *StaticMapping) error {
+
+	// cached mappings are about to be outdated, and must not be refreshed
+	// with the deleted entry while it's being removed
+	s.Invalidate(e.Interface)
+	defer s.Invalidate(e.Interface)
+
+	// Kea backend is driven through its API
+	if s.IsKea() {
+		return s.keaDelete(e)
+	}
+
 	// get the edit page to retrieve form secret values
-	dhcpURI := fmt.Sprintf("%s%s?if=%s", s.OPN.RootURI, DHCPServiceURI, e.Interface)
+	dhcpURI := s.OPN.URL(fmt.Sprintf("%s?if=%s", DHCPServiceURI, e.Interface))
 
 	// destroy DHCP entry
 	data := requests.Datas{
@@ -337,16 +1023,79 @@ func (s *DHCPSession) DeleteStaticMapping(m *StaticMapping) error {
 		"act": "del",
 	}
 
-	_, err = s.OPN.Session.Post(dhcpURI, data)
+	resp, err := s.OPN.PostForm(dhcpURI, "", data)
 	if err != nil {
 		return err
 	}
 
 	// apply changes
-	err = s.Apply(e.Interface, "", "")
+	_, err = s.Apply(e.Interface, resp.Text())
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// GetStatus retrieves whether DHCP server is enabled on a given interface, with its subnet and range
+func (s *DHCPSession) GetStatus(iface string) (*DHCPStatus, error) {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return nil, err
+	}
+
+	// read out the service page
+	dhcpURI := s.OPN.URL(fmt.Sprintf("%s?if=%s", DHCPServiceURI, iface))
+	doc, err := s.OPN.GetPage(dhcpURI)
+	if err != nil {
+		return nil, err
+	}
+
+	st := DHCPStatus{
+		Interface: iface,
+	}
+
+	// DHCP server enablement checkbox
+	n := htmlquery.FindOne(doc, `//input[@name="enable"]`)
+	if n != nil {
+		for _, a := range n.Attr {
+			if a.Key == "checked" {
+				st.Enabled = true
+			}
+		}
+	}
+
+	// DHCP server range
+	n = htmlquery.FindOne(doc, `//input[@name="range_from"]`)
+	if n != nil {
+		st.RangeFrom = htmlquery.SelectAttr(n, "value")
+	}
+	n = htmlquery.FindOne(doc, `//input[@name="range_to"]`)
+	if n != nil {
+		st.RangeTo = htmlquery.SelectAttr(n, "value")
+	}
+
+	// interface subnet, as displayed by the WebUI
+	subnet := ""
+	n = htmlquery.FindOne(doc, fmt.Sprintf(`//td[normalize-space(.)="%s"]/following-sibling::td[1]`, s.OPN.Localize("Subnet")))
+	if n != nil {
+		subnet = NormalizeText(htmlquery.InnerText(n))
+	}
+	mask := ""
+	n = htmlquery.FindOne(doc, fmt.Sprintf(`//td[normalize-space(.)="%s"]/following-sibling::td[1]`, s.OPN.Localize("Subnet mask")))
+	if n != nil {
+		mask = NormalizeText(htmlquery.InnerText(n))
+	}
+	st.Subnet = subnet
+	if subnet != "" && mask != "" {
+		m := net.ParseIP(mask).To4()
+		if m != nil {
+			bits, _ := net.IPv4Mask(m[0], m[1], m[2], m[3]).Size()
+			st.Subnet = fmt.Sprintf("%s/%d", subnet, bits)
+		}
+	}
+
+	return &st, nil
+}
@@ -0,0 +1,313 @@
+package opnsense
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+)
+
+const (
+	// KeaAPI is the Kea DHCP MVC API root
+	KeaAPI = "/api/kea"
+)
+
+const (
+	// DHCPBackendISC refers to the legacy ISC dhcpd backend
+	DHCPBackendISC = "isc"
+	// DHCPBackendKea refers to the Kea DHCP backend
+	DHCPBackendKea = "kea"
+)
+
+const (
+	// ErrKeaNoSubnet is thrown when no Kea subnet of the interface contains the reservation IP address
+	ErrKeaNoSubnet = "no Kea DHCPv4 subnet of this interface contains this IP address"
+)
+
+type apiKeaGeneral struct {
+	DHCPv4 struct {
+		General struct {
+			Enabled    string               `json:"enabled"`
+			Interfaces map[string]APIOption `json:"interfaces"`
+		} `json:"general"`
+	} `json:"dhcpv4"`
+}
+
+type apiKeaSubnet struct {
+	UUID   string `json:"uuid"`
+	Subnet string `json:"subnet"`
+}
+
+type apiKeaReservation struct {
+	UUID        string `json:"uuid,omitempty"`
+	Subnet      string `json:"subnet"`
+	IP          string `json:"ip_address"`
+	MAC         string `json:"hw_address"`
+	Hostname    string `json:"hostname"`
+	Description string `json:"description"`
+}
+
+type apiKeaSearch struct {
+	Rows []apiKeaReservation `json:"rows"`
+}
+
+type apiKeaSubnetSearch struct {
+	Rows []apiKeaSubnet `json:"rows"`
+}
+
+var keaSearchQuery = map[string]interface{}{
+	"current":  1,
+	"rowCount": -1,
+}
+
+// DetectBackend finds out which DHCP backend is active, Kea being used only if enabled.
+// The backend is only remembered once OPNSense gave a definitive answer, so that a
+// transient failure doesn't tie the session to the legacy backend.
+func (s *DHCPSession) DetectBackend() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Backend != "" {
+		return s.Backend
+	}
+
+	resp, err := s.OPN.Get(s.OPN.URL(fmt.Sprintf("%s/dhcpv4/get", KeaAPI)))
+	if err != nil {
+		log.Printf("[WARN] Unable to detect OPNSense DHCP backend, assuming %s: %v", DHCPBackendISC, err)
+		return DHCPBackendISC
+	}
+
+	// OPNSense versions without Kea don't know about its API
+	backend := DHCPBackendISC
+	if resp.R.StatusCode != http.StatusNotFound {
+		res := apiKeaGeneral{}
+		err = s.OPN.decodeJSON(resp, &res)
+		if err != nil {
+			log.Printf("[WARN] Unable to detect OPNSense DHCP backend, assuming %s: %v", DHCPBackendISC, err)
+			return DHCPBackendISC
+		}
+		if res.DHCPv4.General.Enabled == "1" {
+			backend = DHCPBackendKea
+		}
+	}
+	s.Backend = backend
+	log.Printf("[INFO] OPNSense DHCP backend detected: %s", s.Backend)
+
+	return s.Backend
+}
+
+// IsKea tells whether the Kea DHCP backend is in use
+func (s *DHCPSession) IsKea() bool {
+	return s.DetectBackend() == DHCPBackendKea
+}
+
+// keaGetAllReservations retrieves the Kea reservations of an interface, i.e. those of
+// the subnets its address belongs to
+func (s *DHCPSession) keaGetAllReservations(iface string) ([]StaticMapping, error) {
+
+	all, err := s.keaGetReservations([]string{iface})
+	if err != nil {
+		return []StaticMapping{}, err
+	}
+
+	return all[iface], nil
+}
+
+// keaGetReservations retrieves the Kea reservations of the given interfaces, per interface.
+// Kea doesn't bind reservations to interfaces but to subnets: reservations are reported
+// for the interface whose address their subnet contains, others being left out.
+func (s *DHCPSession) keaGetReservations(ifaces []string) (map[string][]StaticMapping, error) {
+
+	all := map[string][]StaticMapping{}
+	for _, iface := range ifaces {
+		all[iface] = []StaticMapping{}
+	}
+
+	subnets, err := s.keaSubnets()
+	if err != nil {
+		return all, err
+	}
+	owned, err := s.keaInterfaceSubnets(subnets, ifaces)
+	if err != nil {
+		return all, err
+	}
+
+	res := apiKeaSearch{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/dhcpv4/searchReservation", KeaAPI), keaSearchQuery, &res)
+	if err != nil {
+		return all, err
+	}
+
+	for _, r := range res.Rows {
+		iface, ok := owned[r.Subnet]
+		if !ok {
+			continue
+		}
+		hostname := r.Hostname
+		if hostname == "" {
+			hostname = "default"
+		}
+
+		// reservations are identified by their UUID, they have no position-based ID
+		m := StaticMapping{
+			ID:        -1,
+			UUID:      r.UUID,
+			Interface: iface,
+			IP:        r.IP,
+			MAC:       r.MAC,
+			Hostname:  hostname,
+		}
+		all[iface] = append(all[iface], m)
+	}
+
+	return all, nil
+}
+
+// keaListInterfaces retrieves interfaces Kea DHCPv4 server listens on
+func (s *DHCPSession) keaListInterfaces() ([]string, error) {
+	res := apiKeaGeneral{}
+	err := s.OPN.GetJSON(fmt.Sprintf("%s/dhcpv4/get", KeaAPI), &res)
+	if err != nil {
+		return []string{}, err
+	}
+	return SelectedOptions(res.DHCPv4.General.Interfaces), nil
+}
+
+// keaSubnets retrieves all Kea DHCPv4 subnets
+func (s *DHCPSession) keaSubnets() ([]apiKeaSubnet, error) {
+	res := apiKeaSubnetSearch{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/dhcpv4/searchSubnet", KeaAPI), keaSearchQuery, &res)
+	if err != nil {
+		return []apiKeaSubnet{}, err
+	}
+	return res.Rows, nil
+}
+
+// keaInterfaceSubnets maps the UUIDs of Kea subnets to the interface, among the given ones,
+// whose address they contain. Interfaces without a static address don't hold any subnet.
+func (s *DHCPSession) keaInterfaceSubnets(subnets []apiKeaSubnet, ifaces []string) (map[string]string, error) {
+
+	res := map[string]string{}
+	is := InterfaceSession{
+		OPN: s.OPN,
+	}
+	for _, iface := range ifaces {
+		i := Interface{
+			Name: iface,
+		}
+		err := is.ReadInterface(&i)
+		if err != nil {
+			return res, err
+		}
+		addr := net.ParseIP(i.IPAddress)
+		if addr == nil {
+			continue
+		}
+		for _, sn := range subnets {
+			_, network, err := net.ParseCIDR(sn.Subnet)
+			if err == nil && network.Contains(addr) {
+				res[sn.UUID] = iface
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// keaSubnet finds out the UUID of the Kea subnet of an interface an IP address belongs to
+func (s *DHCPSession) keaSubnet(iface, ip string) (string, error) {
+	subnets, err := s.keaSubnets()
+	if err != nil {
+		return "", err
+	}
+	owned, err := s.keaInterfaceSubnets(subnets, []string{iface})
+	if err != nil {
+		return "", err
+	}
+
+	addr := net.ParseIP(ip)
+	for _, sn := range subnets {
+		if _, ok := owned[sn.UUID]; !ok {
+			continue
+		}
+		_, network, err := net.ParseCIDR(sn.Subnet)
+		if err == nil && network.Contains(addr) {
+			return sn.UUID, nil
+		}
+	}
+
+	return "", s.OPN.Error(ErrKeaNoSubnet)
+}
+
+// keaApply reconfigures Kea DHCP server
+func (s *DHCPSession) keaApply() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/service/reconfigure", KeaAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// keaCreateOrEdit creates or edit a Kea reservation
+func (s *DHCPSession) keaCreateOrEdit(m *StaticMapping) error {
+
+	// Kea reservations can't be disabled
+	if m.Disabled {
+		return s.OPN.Error(ErrDisableUnsupported)
+	}
+
+	subnet, err := s.keaSubnet(m.Interface, m.IP)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]apiKeaReservation{
+		"reservation": {
+			Subnet:      subnet,
+			IP:          m.IP,
+			MAC:         m.MAC,
+			Hostname:    m.Hostname,
+			Description: m.Hostname,
+		},
+	}
+
+	uri := fmt.Sprintf("%s/dhcpv4/addReservation", KeaAPI)
+	if m.UUID != "" {
+		uri = fmt.Sprintf("%s/dhcpv4/setReservation/%s", KeaAPI, m.UUID)
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(uri, data, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+	if res.UUID != "" {
+		m.UUID = res.UUID
+	}
+
+	// apply changes
+	_, err = s.Apply(m.Interface, "")
+	return err
+}
+
+// keaDelete destroy a Kea reservation
+func (s *DHCPSession) keaDelete(m *StaticMapping) error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/dhcpv4/delReservation/%s", KeaAPI, m.UUID), nil, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	_, err = s.Apply(m.Interface, "")
+	return err
+}
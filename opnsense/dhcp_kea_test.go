@@ -0,0 +1,219 @@
+package opnsense
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// keaAPI fakes the Kea DHCPv4 MVC API, along with the interfaces pages exposing the
+// addresses its subnets are matched against
+type keaAPI struct {
+	mu           sync.Mutex
+	addresses    map[string]string
+	subnets      []apiKeaSubnet
+	reservations []apiKeaReservation
+	nextID       int
+	// failures is the number of backend probes to fail before answering
+	failures int
+	probes   int
+}
+
+func newKeaAPI() *keaAPI {
+	return &keaAPI{
+		addresses: map[string]string{
+			"lan":  "192.168.1.1",
+			"opt1": "10.0.0.1",
+		},
+		subnets: []apiKeaSubnet{
+			{UUID: "s-lan", Subnet: "192.168.1.0/24"},
+			{UUID: "s-opt1", Subnet: "10.0.0.0/24"},
+		},
+		reservations: []apiKeaReservation{
+			{UUID: "r-0", Subnet: "s-opt1", IP: "10.0.0.10", MAC: "00:11:22:33:44:01", Hostname: "camera"},
+			{UUID: "r-1", Subnet: "s-lan", IP: "192.168.1.10", MAC: "00:11:22:33:44:02", Hostname: "printer"},
+			{UUID: "r-2", Subnet: "s-lan", IP: "192.168.1.11", MAC: "00:11:22:33:44:03", Hostname: "nas"},
+		},
+		nextID: 3,
+	}
+}
+
+func (f *keaAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == InterfaceServiceURI:
+		name := r.URL.Query().Get("if")
+		fmt.Fprintf(w, `<html><body><div class="content-box"><form method="post">`+
+			`<input type="text" name="descr" value="%s"/><input type="text" name="ipaddr" value="%s"/>`+
+			`<select name="subnet"><option value="24" selected="selected">24</option></select></form></div></body></html>`,
+			strings.ToUpper(name), f.addresses[name])
+	case r.URL.Path == KeaAPI+"/dhcpv4/get":
+		f.probes++
+		if f.failures > 0 {
+			f.failures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		res := apiKeaGeneral{}
+		res.DHCPv4.General.Enabled = "1"
+		res.DHCPv4.General.Interfaces = map[string]APIOption{
+			"lan":  {Value: "LAN", Selected: 1},
+			"opt1": {Value: "OPT1", Selected: 1},
+			"wan":  {Value: "WAN"},
+		}
+		writeJSON(w, res)
+	case r.URL.Path == KeaAPI+"/dhcpv4/searchSubnet":
+		writeJSON(w, apiKeaSubnetSearch{Rows: f.subnets})
+	case r.URL.Path == KeaAPI+"/dhcpv4/searchReservation":
+		writeJSON(w, apiKeaSearch{Rows: f.reservations})
+	case r.URL.Path == KeaAPI+"/dhcpv4/addReservation":
+		body := map[string]apiKeaReservation{}
+		json.NewDecoder(r.Body).Decode(&body)
+		res := body["reservation"]
+		res.UUID = fmt.Sprintf("r-%d", f.nextID)
+		f.nextID++
+		f.reservations = append(f.reservations, res)
+		writeJSON(w, APIResult{Result: "saved", UUID: res.UUID})
+	case strings.HasPrefix(r.URL.Path, KeaAPI+"/dhcpv4/delReservation/"):
+		uuid := strings.TrimPrefix(r.URL.Path, KeaAPI+"/dhcpv4/delReservation/")
+		for i, res := range f.reservations {
+			if res.UUID == uuid {
+				f.reservations = append(f.reservations[:i], f.reservations[i+1:]...)
+				break
+			}
+		}
+		writeJSON(w, APIResult{Result: "deleted"})
+	case r.URL.Path == KeaAPI+"/service/reconfigure":
+		writeJSON(w, APIResult{Status: "ok"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestKeaReservationsPerInterface(t *testing.T) {
+	f := newKeaAPI()
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// reservations of other subnets aren't reported, and are identified by their UUID
+	entries, err := dhcp.GetAllInterfaceStaticMappings("lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuids := []string{}
+	for _, e := range entries {
+		uuids = append(uuids, e.UUID)
+		if e.Interface != "lan" || e.ID != -1 {
+			t.Errorf("reservation %s reported on %s with ID %d", e.UUID, e.Interface, e.ID)
+		}
+	}
+	if strings.Join(uuids, ",") != "r-1,r-2" {
+		t.Errorf("got reservations %v, expected [r-1 r-2]", uuids)
+	}
+
+	// other interfaces reservations are neither found nor conflicting
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.20", Hostname: "camera"}
+	_, err = dhcp.FindMappingByMAC(&m)
+	if err == nil || err.Error() != ErrNoSuchMAC {
+		t.Errorf("unexpected lookup error %v", err)
+	}
+	if errs := dhcp.ValidateMapping(&m); len(errs) != 0 {
+		t.Errorf("unexpected validation errors %v", errs)
+	}
+
+	// addresses must belong to one of the interface subnets
+	m.IP = "10.0.0.20"
+	errs := dhcp.ValidateMapping(&m)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), ErrIPOutOfSubnet) {
+		t.Errorf("unexpected validation errors %v", errs)
+	}
+}
+
+func TestKeaCreateStaticMapping(t *testing.T) {
+	f := newKeaAPI()
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// the same device may have reservations on several interfaces
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.20", Hostname: "camera"}
+	err := dhcp.CreateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.UUID != "r-3" || f.reservations[3].Subnet != "s-lan" {
+		t.Errorf("got reservation %s in %+v", m.UUID, f.reservations)
+	}
+
+	err = dhcp.DeleteStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.reservations) != 3 || f.reservations[0].UUID != "r-0" {
+		t.Errorf("unexpected reservations %+v", f.reservations)
+	}
+}
+
+func TestDetectBackendRetriesFailedProbes(t *testing.T) {
+	f := newKeaAPI()
+	f.failures = 1
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// a failed probe isn't remembered
+	if backend := dhcp.DetectBackend(); backend != DHCPBackendISC {
+		t.Errorf("got backend %s while probe failed", backend)
+	}
+	if backend := dhcp.DetectBackend(); backend != DHCPBackendKea {
+		t.Errorf("got backend %s, expected %s", backend, DHCPBackendKea)
+	}
+	if backend := dhcp.DetectBackend(); backend != DHCPBackendKea || f.probes != 2 {
+		t.Errorf("got backend %s after %d probes, expected %s after 2", backend, f.probes, DHCPBackendKea)
+	}
+
+	// OPNSense versions without Kea don't serve its API, which is a definitive answer
+	legacy := DHCPSession{
+		OPN: newTestSession(t, http.NotFoundHandler()),
+	}
+	if backend := legacy.DetectBackend(); backend != DHCPBackendISC || legacy.Backend != DHCPBackendISC {
+		t.Errorf("got backend %s, remembered as %q", backend, legacy.Backend)
+	}
+}
+
+func TestKeaGetAllStaticMappings(t *testing.T) {
+	f := newKeaAPI()
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// each reservation is reported once, on the interface of its subnet
+	all, err := dhcp.GetAllStaticMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for iface, entries := range all {
+		got[iface] = []string{}
+		for _, e := range entries {
+			if e.Interface != iface {
+				t.Errorf("reservation %s reported on %s under %s", e.UUID, e.Interface, iface)
+			}
+			got[iface] = append(got[iface], e.UUID)
+		}
+	}
+	expected := map[string][]string{
+		"lan":  {"r-1", "r-2"},
+		"opt1": {"r-0"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got reservations %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,168 @@
+package opnsense
+
+import (
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// DHCPLeasesURI is the DHCP leases status page
+	DHCPLeasesURI = "/status_dhcp_leases.php?all=1"
+)
+
+// DHCPLeasesCacheDuration is the time leases retrieved through CachedLeases() are reused for
+const DHCPLeasesCacheDuration = 30 * time.Second
+
+const (
+	// DHCPLeaseInterface refers to the HTML table field for DHCP leases status
+	DHCPLeaseInterface = "Interface"
+	// DHCPLeaseIP refers to the HTML table field for DHCP leases status
+	DHCPLeaseIP = "IP address"
+	// DHCPLeaseMAC refers to the HTML table field for DHCP leases status
+	DHCPLeaseMAC = "MAC address"
+	// DHCPLeaseHostname refers to the HTML table field for DHCP leases status
+	DHCPLeaseHostname = "Hostname"
+	// DHCPLeaseStart refers to the HTML table field for DHCP leases status
+	DHCPLeaseStart = "Start"
+	// DHCPLeaseEnd refers to the HTML table field for DHCP leases status
+	DHCPLeaseEnd = "End"
+	// DHCPLeaseStatus refers to the HTML table field for DHCP leases status
+	DHCPLeaseStatus = "Status"
+	// DHCPLeaseType refers to the HTML table field for DHCP leases status
+	DHCPLeaseType = "Lease type"
+)
+
+const (
+	// ErrLeasesUnsupported is thrown when leases status is requested from the Kea backend
+	ErrLeasesUnsupported = "DHCP leases status isn't available with the Kea DHCP backend"
+)
+
+var rxLeaseMAC = regexp.MustCompile(`([0-9a-fA-F]{2}(?::[0-9a-fA-F]{2}){5})`)
+
+// DHCPLease abstracts a DHCP lease, as reported by the leases status page
+type DHCPLease struct {
+	Interface string
+	IP        string
+	MAC       string
+	Hostname  string
+	Start     string
+	End       string
+	Online    bool
+	Active    bool
+}
+
+// GetLeases retrieves all DHCP leases, active and expired ones
+func (s *DHCPSession) GetLeases() ([]DHCPLease, error) {
+
+	leases := []DHCPLease{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return leases, err
+	}
+
+	if s.IsKea() {
+		return leases, s.OPN.Error(ErrLeasesUnsupported)
+	}
+
+	// read out the status page
+	doc, err := s.OPN.GetPage(s.OPN.URL(DHCPLeasesURI))
+	if err != nil {
+		return leases, err
+	}
+	table := htmlquery.FindOne(doc, `//table[contains(@class, "table-striped")]`)
+	if table == nil {
+		return leases, s.OPN.UnexpectedPage(doc, "leases status table")
+	}
+
+	// lookup for columns, as their set depends on OPNSense version
+	index := map[string]int{}
+	headers := htmlquery.Find(table, `.//tr[th]/th`)
+	for i, h := range headers {
+		index[s.OPN.Canonical(NormalizeText(htmlquery.InnerText(h)))] = i
+	}
+
+	for _, r := range htmlquery.Find(table, `.//tr[td]`) {
+		cells := htmlquery.Find(r, `./td`)
+		field := func(f string) *html.Node {
+			i, ok := index[f]
+			if !ok || i >= len(cells) {
+				return nil
+			}
+			return cells[i]
+		}
+		text := func(f string) string {
+			n := field(f)
+			if n == nil {
+				return ""
+			}
+			return NormalizeText(htmlquery.InnerText(n))
+		}
+
+		l := DHCPLease{
+			Interface: text(DHCPLeaseInterface),
+			IP:        text(DHCPLeaseIP),
+			MAC:       strings.ToLower(rxLeaseMAC.FindString(text(DHCPLeaseMAC))),
+			Hostname:  text(DHCPLeaseHostname),
+			Start:     text(DHCPLeaseStart),
+			End:       text(DHCPLeaseEnd),
+		}
+		if l.MAC == "" {
+			continue
+		}
+
+		// online status may only be rendered as an icon title
+		if n := field(DHCPLeaseStatus); n != nil {
+			status := strings.ToLower(htmlquery.OutputHTML(n, true))
+			l.Online = strings.Contains(status, "online") && !strings.Contains(status, "offline")
+		}
+
+		// expired leases are listed along with active ones
+		kind := strings.ToLower(text(DHCPLeaseType))
+		l.Active = kind != "" && kind != "expired"
+
+		leases = append(leases, l)
+	}
+
+	return leases, nil
+}
+
+// CachedLeases retrieves all DHCP leases, as GetLeases does, fetching the status page
+// again only once the previously fetched leases are older than DHCPLeasesCacheDuration,
+// so that reading many static mappings doesn't fetch it for each of them
+func (s *DHCPSession) CachedLeases() ([]DHCPLease, error) {
+	s.leasesMu.Lock()
+	defer s.leasesMu.Unlock()
+
+	if s.leases != nil && time.Since(s.leasesAt) < DHCPLeasesCacheDuration {
+		return s.leases, nil
+	}
+
+	leases, err := s.GetLeases()
+	if err != nil {
+		return leases, err
+	}
+	s.leases = leases
+	s.leasesAt = time.Now()
+
+	return leases, nil
+}
+
+// FindLease retrieves the lease of a given MAC address, nil if none.
+// Active leases take precedence over expired ones of the same device.
+func (s *DHCPSession) FindLease(leases []DHCPLease, mac string) *DHCPLease {
+	var res *DHCPLease
+	for i, l := range leases {
+		if !strings.EqualFold(l.MAC, mac) {
+			continue
+		}
+		if res == nil || (l.Active && !res.Active) {
+			res = &leases[i]
+		}
+	}
+	return res
+}
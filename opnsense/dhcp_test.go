@@ -0,0 +1,875 @@
+package opnsense
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antchfx/htmlquery"
+)
+
+// dhcpFixtures serves the LAN interface service page fixture
+func dhcpFixtures() map[string]string {
+	return map[string]string{
+		DHCPServiceURI + "?if=lan": "dhcp_lan.html",
+	}
+}
+
+func TestFindMappingByIP(t *testing.T) {
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, fixtures(t, dhcpFixtures())),
+	}
+
+	tests := []struct {
+		ip  string
+		mac string
+		id  int
+	}{
+		{"192.168.1.10", "00:11:22:33:44:01", 0},
+		{"192.168.1.12", "00:11:22:33:44:04", 3},
+		{"192.168.1.20", "00:11:22:33:44:03", 2},
+	}
+	for _, tt := range tests {
+		m, err := dhcp.FindMappingByIP("lan", tt.ip)
+		if err != nil {
+			t.Errorf("%s: %v", tt.ip, err)
+			continue
+		}
+		if m.MAC != tt.mac || m.ID != tt.id || m.Interface != "lan" {
+			t.Errorf("%s: got mapping %s (ID %d) on %s, expected %s (ID %d)", tt.ip, m.MAC, m.ID, m.Interface, tt.mac, tt.id)
+		}
+	}
+
+	_, err := dhcp.FindMappingByIP("lan", "192.168.1.99")
+	if err == nil || err.Error() != ErrNoSuchMapping {
+		t.Errorf("unexpected error for an unmapped IP: %v", err)
+	}
+}
+
+// dhcpWebUI fakes the legacy DHCP WebUI pages of an interface, rendering its static mappings
+// table and edit form out of its state, and saving submitted forms as OPNSense does
+type dhcpWebUI struct {
+	mu       sync.Mutex
+	iface    string
+	mappings []StaticMapping
+	nextID   int
+	// multiMAC exposes additional MAC addresses on the edit form
+	multiMAC bool
+	// pools exposes a DHCP pool selection on the edit form
+	pools []string
+	// partial is the number of partially rendered service pages to serve next
+	partial int
+	// empty is the number of service pages to serve next with an empty, yet complete looking, table
+	empty int
+	// reject is an input error reported on every form submission, if any
+	reject string
+	// drop is the number of next form submissions silently redisplayed without being saved
+	drop    int
+	pending bool
+	applies int
+	reads   int
+}
+
+// shortDHCPRetries shortens the delay between static mappings page reads for the test duration
+func shortDHCPRetries(t *testing.T) {
+	delay := DHCPPartialReadRetryDelay
+	DHCPPartialReadRetryDelay = time.Millisecond
+	t.Cleanup(func() {
+		DHCPPartialReadRetryDelay = delay
+	})
+}
+
+func newDHCPWebUI(iface string, mappings ...StaticMapping) *dhcpWebUI {
+	f := dhcpWebUI{
+		iface: iface,
+	}
+	for _, m := range mappings {
+		m.ID = f.nextID
+		m.Interface = iface
+		f.mappings = append(f.mappings, m)
+		f.nextID++
+	}
+	return &f
+}
+
+// find returns the position of a mapping given its ID, -1 if none
+func (f *dhcpWebUI) find(id string) int {
+	for i, m := range f.mappings {
+		if strconv.Itoa(m.ID) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *dhcpWebUI) servicePage() string {
+	var b strings.Builder
+	b.WriteString(`<html><head><script>$.ajaxSetup({ beforeSend: function(xhr) { xhr.setRequestHeader("X-CSRFToken", "token" ); } });</script></head><body>`)
+	fmt.Fprintf(&b, `<ul class="nav nav-tabs"><li class="active"><a href="%s?if=%s">%s</a></li></ul>`, DHCPServiceURI, f.iface, f.iface)
+	b.WriteString(`<div class="content-box"><form method="post"><input type="hidden" name="csrf" value="token"/>`)
+	if f.pending {
+		b.WriteString(`<input type="submit" name="apply" value="Apply changes"/>`)
+	}
+	b.WriteString(`<input name="enable" type="checkbox" value="yes" checked="checked"/></form>`)
+	if f.partial > 0 {
+		// rendering got interrupted right after the static mappings table started
+		f.partial--
+		b.WriteString(`<table class="table table-striped"><tr><td colspan="6">DHCP Static Mappings for this interface.</td></tr></table>`)
+		return b.String()
+	}
+	b.WriteString(`<table class="table table-striped"><tr><td colspan="6">DHCP Static Mappings for this interface.</td></tr>`)
+	b.WriteString(`<tr><td>Static ARP</td><td>MAC address</td><td>IP address</td><td>Hostname</td><td>Description</td><td></td></tr>`)
+	mappings := f.mappings
+	if f.empty > 0 {
+		f.empty--
+		mappings = nil
+	}
+	for _, m := range mappings {
+		macs := html.EscapeString(m.MAC)
+		for _, mac := range m.MACs {
+			macs += "<br/>" + html.EscapeString(mac)
+		}
+		fmt.Fprintf(&b, `<tr><td></td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td><a href="%s?if=%s&amp;id=%d">edit</a></td></tr>`,
+			macs, html.EscapeString(m.IP), html.EscapeString(m.Hostname), html.EscapeString(m.Hostname), DHCPServiceEditURI, f.iface, m.ID)
+	}
+	b.WriteString(`</table></div></body></html>`)
+	return b.String()
+}
+
+func (f *dhcpWebUI) editPage(m *StaticMapping, inputErrors ...string) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><script>$.ajaxSetup({ beforeSend: function(xhr) { xhr.setRequestHeader("X-CSRFToken", "token" ); } });</script></head><body>`)
+	if len(inputErrors) > 0 {
+		b.WriteString(`<div class="alert alert-danger" role="alert"><p>The following input errors were detected:</p><ul>`)
+		for _, e := range inputErrors {
+			fmt.Fprintf(&b, `<li>%s</li>`, html.EscapeString(e))
+		}
+		b.WriteString(`</ul></div>`)
+	}
+	b.WriteString(`<div class="content-box"><form method="post"><input type="hidden" name="csrf" value="token"/>`)
+	disabled := ""
+	if m.Disabled {
+		disabled = ` checked="checked"`
+	}
+	fmt.Fprintf(&b, `<input type="checkbox" name="disabled" value="yes"%s/>`, disabled)
+	for name, value := range map[string]string{
+		"mac":        m.MAC,
+		"ipaddr":     m.IP,
+		"hostname":   m.Hostname,
+		"descr":      m.Hostname,
+		"nextserver": m.NextServer,
+		"filename":   m.Filename,
+		"rootpath":   m.RootPath,
+	} {
+		fmt.Fprintf(&b, `<input type="text" name="%s" value="%s"/>`, name, html.EscapeString(value))
+	}
+	if f.multiMAC {
+		fmt.Fprintf(&b, `<input type="text" name="macs" value="%s"/>`, html.EscapeString(strings.Join(m.MACs, ",")))
+	}
+	if len(f.pools) > 0 {
+		b.WriteString(`<select name="pool"><option value="">Primary pool</option>`)
+		for _, p := range f.pools {
+			selected := ""
+			if p == m.Pool {
+				selected = ` selected="selected"`
+			}
+			fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, p, selected, p)
+		}
+		b.WriteString(`</select>`)
+	}
+	b.WriteString(`<input type="submit" name="Submit" value="Save"/></form></div></body></html>`)
+	return b.String()
+}
+
+func (f *dhcpWebUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r.ParseForm()
+	switch {
+	case r.URL.Path == DHCPServiceURI && r.Method == http.MethodGet:
+		f.reads++
+		fmt.Fprint(w, f.servicePage())
+	case r.URL.Path == DHCPServiceURI:
+		if r.Form.Get("act") == "del" {
+			if i := f.find(r.Form.Get("id")); i != -1 {
+				f.mappings = append(f.mappings[:i], f.mappings[i+1:]...)
+				f.pending = true
+			}
+		}
+		if r.Form.Get("apply") != "" {
+			f.applies++
+			f.pending = false
+		}
+		fmt.Fprint(w, f.servicePage())
+	case r.URL.Path == DHCPServiceEditURI && r.Method == http.MethodGet:
+		m := StaticMapping{}
+		if i := f.find(r.Form.Get("id")); i != -1 {
+			m = f.mappings[i]
+		}
+		fmt.Fprint(w, f.editPage(&m))
+	case r.URL.Path == DHCPServiceEditURI:
+		m := StaticMapping{
+			Interface:  f.iface,
+			MAC:        r.Form.Get("mac"),
+			IP:         r.Form.Get("ipaddr"),
+			Hostname:   r.Form.Get("hostname"),
+			Disabled:   r.Form.Get("disabled") == "yes",
+			NextServer: r.Form.Get("nextserver"),
+			Filename:   r.Form.Get("filename"),
+			RootPath:   r.Form.Get("rootpath"),
+			Pool:       r.Form.Get("pool"),
+		}
+		if macs := r.Form.Get("macs"); macs != "" {
+			m.MACs = strings.Split(macs, ",")
+		}
+		if f.reject != "" {
+			fmt.Fprint(w, f.editPage(&m, f.reject))
+			return
+		}
+		if f.drop > 0 {
+			f.drop--
+			fmt.Fprint(w, f.editPage(&m))
+			return
+		}
+		if i := f.find(r.Form.Get("id")); i != -1 {
+			m.ID = f.mappings[i].ID
+			f.mappings[i] = m
+		} else {
+			m.ID = f.nextID
+			f.nextID++
+			f.mappings = append(f.mappings, m)
+		}
+		f.pending = true
+		fmt.Fprint(w, f.servicePage())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestCreateStaticMappingReportsInputErrors(t *testing.T) {
+	f := newDHCPWebUI("lan")
+	f.reject = "A valid IP address must be specified."
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	err := dhcp.CreateStaticMapping(&StaticMapping{
+		Interface: "lan",
+		MAC:       "00:11:22:33:44:55",
+		IP:        "192.168.1.300",
+		Hostname:  "printer",
+	})
+	if err == nil {
+		t.Fatal("rejected mapping has been reported as created")
+	}
+	expected := ErrFormInvalid + ": " + f.reject
+	if err.Error() != expected {
+		t.Errorf("got error %q, expected %q", err, expected)
+	}
+	if len(f.mappings) != 0 || f.applies != 0 {
+		t.Errorf("rejected mapping has been saved (%d mappings, %d applies)", len(f.mappings), f.applies)
+	}
+}
+
+func TestReadStaticMappingRefreshesAllFields(t *testing.T) {
+	live := StaticMapping{
+		MAC:        "00:11:22:33:44:55",
+		MACs:       []string{"00:11:22:33:44:66"},
+		IP:         "192.168.1.50",
+		Hostname:   "pxe-client",
+		Disabled:   true,
+		NextServer: "192.168.1.2",
+		Filename:   "pxelinux.0",
+		RootPath:   "/srv/nfs/root",
+		Pool:       "servers",
+	}
+	f := newDHCPWebUI("lan", live)
+	f.multiMAC = true
+	f.pools = []string{"servers"}
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// stale values must all be overwritten
+	m := StaticMapping{
+		Interface: "lan",
+		MAC:       "00:11:22:33:44:55",
+		IP:        "192.168.1.99",
+		Hostname:  "stale",
+		Filename:  "stale.efi",
+	}
+	err := dhcp.ReadStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := f.mappings[0]
+	expected.Options = []DHCPOption{}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("got mapping %+v, expected %+v", m, expected)
+	}
+}
+
+func TestStaticFieldNamesIndex(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "dhcp_lan.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := htmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dhcp := DHCPSession{
+		OPN: &OPNSession{},
+	}
+	err = dhcp.GetStaticFieldNames(doc, DHCPEntryStartingRow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range []string{DHCPStaticARP, DHCPMAC, DHCPIP, DHCPHostname, DHCPDescription} {
+		if dhcp.Index[f] != i || dhcp.Fields[i] != f {
+			t.Errorf("field %q has index %d, expected %d", f, dhcp.Index[f], i)
+		}
+	}
+	if _, ok := dhcp.Index[""]; ok {
+		t.Error("actions column has been indexed")
+	}
+}
+
+func BenchmarkParseStaticMappings(b *testing.B) {
+	f := newDHCPWebUI("lan")
+	for i := 0; i < 1000; i++ {
+		f.mappings = append(f.mappings, StaticMapping{
+			ID:       i,
+			MAC:      fmt.Sprintf("00:11:22:33:%02x:%02x", i/256, i%256),
+			IP:       fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+			Hostname: fmt.Sprintf("host-%d", i),
+		})
+	}
+	doc, err := htmlquery.Parse(strings.NewReader(f.servicePage()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	dhcp := DHCPSession{
+		OPN: &OPNSession{},
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries, err := dhcp.ParseStaticMappings(doc, "lan")
+		if err != nil || len(entries) != 1000 {
+			b.Fatalf("parsed %d mappings: %v", len(entries), err)
+		}
+	}
+}
+
+func TestStaticMappingFieldsNormalized(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "dhcp_lan.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := htmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dhcp := DHCPSession{
+		OPN: &OPNSession{},
+	}
+
+	entries, err := dhcp.ParseStaticMappings(doc, "lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[1].Hostname != "nas" {
+		t.Fatalf("got mappings %+v, expected nas as second one", entries)
+	}
+
+	// entities and non-breaking spaces are decoded, whitespaces collapsed
+	rows := htmlquery.Find(doc, `//table[@class="table table-striped"]//tr`)
+	for i, expected := range []string{"Office printer", "Storage & backups", "", "wired and wireless"} {
+		r := rows[DHCPEntryStartingRow+i]
+		if d := dhcp.GetStaticMappingField(r, DHCPDescription); d != expected {
+			t.Errorf("mapping %d has description %q, expected %q", i, d, expected)
+		}
+	}
+}
+
+func TestStaticMappingPool(t *testing.T) {
+	f := newDHCPWebUI("lan")
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// pools can't be picked unless the edit page offers them
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", IP: "192.168.1.50", Hostname: "web", Pool: "servers"}
+	err := dhcp.CreateStaticMapping(&m)
+	if err == nil || err.Error() != ErrPoolUnsupported {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(f.mappings) != 0 {
+		t.Errorf("mapping has been saved without its pool: %+v", f.mappings)
+	}
+
+	f.pools = []string{"servers", "guests"}
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", IP: "192.168.1.50", Hostname: "web", Pool: "servers"}
+	err = dhcp.CreateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.mappings) != 1 || f.mappings[0].Pool != "servers" {
+		t.Fatalf("unexpected mappings %+v", f.mappings)
+	}
+
+	// and are read back from the edit page
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55"}
+	err = dhcp.ReadStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Pool != "servers" {
+		t.Errorf("read mapping in pool %q, expected servers", m.Pool)
+	}
+}
+
+func TestListDHCPInterfaces(t *testing.T) {
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, fixtures(t, map[string]string{
+			DHCPServiceURI: "dhcp_lan.html",
+		})),
+	}
+
+	ifaces, err := dhcp.ListDHCPInterfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"lan", "opt1", "opt3"}
+	if !reflect.DeepEqual(ifaces, expected) {
+		t.Errorf("got interfaces %v, expected %v", ifaces, expected)
+	}
+}
+
+func TestStaticMappingsKeepTheirIDs(t *testing.T) {
+	f := newDHCPWebUI("lan",
+		StaticMapping{MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"},
+		StaticMapping{MAC: "00:11:22:33:44:02", IP: "192.168.1.11", Hostname: "nas"},
+		StaticMapping{MAC: "00:11:22:33:44:03", IP: "192.168.1.12", Hostname: "camera"},
+	)
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// mappings are no longer numbered by their table position once one is removed
+	err := dhcp.DeleteStaticMapping(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:03", IP: "192.168.1.13", Hostname: "camera"}
+	err = dhcp.UpdateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 2 {
+		t.Errorf("updated mapping with ID %d, expected 2", m.ID)
+	}
+	if len(f.mappings) != 2 || f.mappings[0].IP != "192.168.1.11" || f.mappings[1].IP != "192.168.1.13" {
+		t.Errorf("unexpected mappings after update: %+v", f.mappings)
+	}
+	err = dhcp.DeleteStaticMapping(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:03"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.mappings) != 1 || f.mappings[0].Hostname != "nas" {
+		t.Errorf("unexpected mappings after delete: %+v", f.mappings)
+	}
+}
+
+func TestStaticMappingFieldConcurrentDiscovery(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "dhcp_lan.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := htmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := htmlquery.Find(doc, `//table[@class="table table-striped"]//tr`)
+	dhcp := DHCPSession{
+		OPN: &OPNSession{},
+	}
+
+	// fields are read while another operation discovers them
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := dhcp.ParseStaticMappings(doc, "lan")
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			dhcp.GetStaticMappingField(rows[DHCPEntryStartingRow], DHCPHostname)
+		}
+	}()
+	wg.Wait()
+
+	if h := dhcp.GetStaticMappingField(rows[DHCPEntryStartingRow], DHCPHostname); h != "printer" {
+		t.Errorf("got hostname %q, expected printer", h)
+	}
+}
+
+func TestStaticMappingsPositionFallback(t *testing.T) {
+	doc, err := htmlquery.Parse(strings.NewReader(`<html><body><div class="content-box"><table class="table table-striped">
+<tr><td colspan="5">DHCP Static Mappings for this interface.</td></tr>
+<tr><td>Static ARP</td><td>MAC address</td><td>IP address</td><td>Hostname</td><td>Description</td></tr>
+<tr><td></td><td>00:11:22:33:44:01</td><td>192.168.1.10</td><td>printer</td><td></td></tr>
+<tr><td></td><td>00:11:22:33:44:02</td><td>192.168.1.11</td><td>nas</td><td></td></tr>
+</table></div></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dhcp := DHCPSession{
+		OPN: &OPNSession{},
+	}
+
+	// markups without action links can only be numbered by position
+	entries, err := dhcp.ParseStaticMappings(doc, "lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range entries {
+		if m.ID != i {
+			t.Errorf("mapping %s has ID %d, expected %d", m.MAC, m.ID, i)
+		}
+	}
+}
+
+func TestCreateStaticMappingAdoptsIdenticalOne(t *testing.T) {
+	f := newDHCPWebUI("lan",
+		StaticMapping{MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"},
+		StaticMapping{MAC: "00:11:22:33:44:02", IP: "192.168.1.11"},
+	)
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// re-applying after a partial failure adopts the mapping, whatever the MAC address case
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:02", IP: "192.168.1.11", Hostname: ""}
+	err := dhcp.CreateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:0A", IP: "192.168.1.12", Hostname: "camera"}
+	f.mappings = append(f.mappings, StaticMapping{ID: 5, Interface: "lan", MAC: "00:11:22:33:44:0a", IP: "192.168.1.12", Hostname: "camera"})
+	err = dhcp.CreateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 5 || len(f.mappings) != 3 || f.applies != 0 {
+		t.Errorf("adopted mapping %d, %d mappings after %d applies", m.ID, len(f.mappings), f.applies)
+	}
+
+	// but MAC addresses mapped differently are rejected
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.20", Hostname: "printer"}
+	err = dhcp.CreateStaticMapping(&m)
+	if err == nil || err.Error() != ErrMACExists {
+		t.Errorf("unexpected error %v", err)
+	}
+	if f.mappings[0].IP != "192.168.1.10" {
+		t.Errorf("existing mapping has been overwritten: %+v", f.mappings[0])
+	}
+}
+
+func TestCreateStaticMappingRetriesSilentRejection(t *testing.T) {
+	f := newDHCPWebUI("lan")
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// the form is redisplayed once, as with a rotated form secret
+	f.drop = 1
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", IP: "192.168.1.50", Hostname: "web"}
+	err := dhcp.CreateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.mappings) != 1 || m.ID != f.mappings[0].ID || f.applies != 1 {
+		t.Errorf("created mapping %d, %d mappings after %d applies", m.ID, len(f.mappings), f.applies)
+	}
+
+	// but isn't retried forever
+	f.drop = 2
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:66", IP: "192.168.1.51", Hostname: "db"}
+	err = dhcp.CreateStaticMapping(&m)
+	if err == nil || err.Error() != ErrMappingNotSaved {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(f.mappings) != 1 || f.applies != 1 {
+		t.Errorf("%d mappings after %d applies, expected the first one only", len(f.mappings), f.applies)
+	}
+}
+
+func TestDeleteStaticMappingByIP(t *testing.T) {
+	f := newDHCPWebUI("lan",
+		StaticMapping{MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"},
+		StaticMapping{MAC: "00:11:22:33:44:02", IP: "192.168.1.11", Hostname: "nas"},
+	)
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// the network card has been replaced out of band
+	f.mappings[0].MAC = "00:11:22:33:44:99"
+	err := dhcp.DeleteStaticMapping(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.mappings) != 1 || f.mappings[0].Hostname != "nas" {
+		t.Errorf("unexpected mappings after delete: %+v", f.mappings)
+	}
+
+	err = dhcp.DeleteStaticMappingByIP("lan", "192.168.1.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.mappings) != 0 {
+		t.Errorf("unexpected mappings after delete: %+v", f.mappings)
+	}
+
+	// nothing is removed when neither address matches
+	f.mappings = append(f.mappings, StaticMapping{ID: 2, Interface: "lan", MAC: "00:11:22:33:44:03", IP: "192.168.1.12", Hostname: "camera"})
+	err = dhcp.DeleteStaticMapping(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.10"})
+	if err == nil || err.Error() != ErrNoSuchMapping {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(f.mappings) != 1 {
+		t.Errorf("unexpected mappings after delete: %+v", f.mappings)
+	}
+}
+
+func TestDeleteStaticMappingInvalidatesCache(t *testing.T) {
+	shortDHCPRetries(t)
+	f := newDHCPWebUI("lan",
+		StaticMapping{MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"},
+	)
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+	err := dhcp.Refresh("lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dhcp.Cached()["lan"]) != 1 {
+		t.Fatalf("unexpected cache %+v", dhcp.Cached())
+	}
+
+	// deleted mappings must not be served from cache afterwards
+	err = dhcp.DeleteStaticMapping(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dhcp.Cached()["lan"]; ok {
+		t.Errorf("deleted mapping is still cached: %+v", dhcp.Cached())
+	}
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01"}
+	err = dhcp.ReadStaticMapping(&m)
+	if err == nil || err.Error() != ErrNoSuchMAC {
+		t.Errorf("unexpected error reading deleted mapping: %v", err)
+	}
+}
+
+func TestStaticMappingMultipleMACs(t *testing.T) {
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, fixtures(t, dhcpFixtures())),
+	}
+
+	// additional MAC addresses are listed below the primary one
+	m, err := dhcp.FindMappingByMAC(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 2 || m.MAC != "00:11:22:33:44:03" || !reflect.DeepEqual(m.MACs, []string{"00:11:22:33:44:13"}) {
+		t.Errorf("got mapping %d with MAC %s and additional ones %v", m.ID, m.MAC, m.MACs)
+	}
+	m, err = dhcp.FindMappingByMAC(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:99", MACs: []string{"00:11:22:33:44:01"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 0 || len(m.MACs) != 0 {
+		t.Errorf("got mapping %d with additional MACs %v", m.ID, m.MACs)
+	}
+}
+
+func TestCreateStaticMappingMultipleMACs(t *testing.T) {
+	f := newDHCPWebUI("lan")
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// additional MAC addresses can't be set unless the edit page offers them
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", MACs: []string{"00:11:22:33:44:66"}, IP: "192.168.1.50", Hostname: "laptop"}
+	err := dhcp.CreateStaticMapping(&m)
+	if err == nil || err.Error() != ErrMultiMACUnsupported {
+		t.Errorf("unexpected error %v", err)
+	}
+	if len(f.mappings) != 0 {
+		t.Errorf("mapping has been saved without its additional MACs: %+v", f.mappings)
+	}
+
+	f.multiMAC = true
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", MACs: []string{"00:11:22:33:44:66", "00:11:22:33:44:77"}, IP: "192.168.1.50", Hostname: "laptop"}
+	err = dhcp.CreateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.mappings) != 1 || !reflect.DeepEqual(f.mappings[0].MACs, m.MACs) {
+		t.Fatalf("unexpected mappings %+v", f.mappings)
+	}
+
+	// and are read back, whatever separators the edit page uses
+	f.mappings[0].MACs = []string{"00:11:22:33:44:66 ,\n00:11:22:33:44:77"}
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55"}
+	err = dhcp.ReadStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MAC != "00:11:22:33:44:55" || !reflect.DeepEqual(m.MACs, []string{"00:11:22:33:44:66", "00:11:22:33:44:77"}) {
+		t.Errorf("read mapping with MAC %s and additional ones %v", m.MAC, m.MACs)
+	}
+}
+
+func TestValidateMapping(t *testing.T) {
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, fixtures(t, dhcpFixtures())),
+	}
+
+	tests := []struct {
+		name    string
+		mapping StaticMapping
+		err     string
+	}{
+		{
+			name:    "new mapping",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", IP: "192.168.1.50", Hostname: "web"},
+		},
+		{
+			name:    "identical mapping",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"},
+		},
+		{
+			name:    "no interface",
+			mapping: StaticMapping{MAC: "00:11:22:33:44:55", IP: "192.168.1.50"},
+			err:     ErrInvalidMapping,
+		},
+		{
+			name:    "malformed MAC address",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44", IP: "192.168.1.50"},
+			err:     ErrInvalidMapping,
+		},
+		{
+			name:    "malformed additional MAC address",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", MACs: []string{"00-11"}, IP: "192.168.1.50"},
+			err:     ErrInvalidMapping,
+		},
+		{
+			name:    "malformed IP address",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", IP: "192.168.1.300"},
+			err:     ErrInvalidMapping,
+		},
+		{
+			name:    "MAC address mapped differently",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:13", IP: "192.168.1.50", Hostname: "laptop"},
+			err:     ErrMACExists,
+		},
+		{
+			name:    "IP address taken",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", IP: "192.168.1.12", Hostname: "web"},
+			err:     ErrIPExists,
+		},
+		{
+			name:    "IP address out of subnet",
+			mapping: StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:55", IP: "192.168.2.50", Hostname: "web"},
+			err:     ErrIPOutOfSubnet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := dhcp.ValidateMapping(&tt.mapping)
+			if tt.err == "" {
+				if len(errs) != 0 {
+					t.Errorf("unexpected errors %v", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), tt.err) {
+				t.Errorf("got errors %v, expected %q", errs, tt.err)
+			}
+		})
+	}
+
+	// all problems are reported at once
+	errs := dhcp.ValidateMapping(&StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.2.11", Hostname: "printer"})
+	if len(errs) != 2 {
+		t.Errorf("got errors %v, expected both MAC address and subnet ones", errs)
+	}
+}
+
+func TestReadStaticMappingRetriesPartialPage(t *testing.T) {
+	shortDHCPRetries(t)
+	f := newDHCPWebUI("lan",
+		StaticMapping{MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"},
+	)
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// a partially rendered page doesn't mean the mapping is gone
+	f.partial = 1
+	m := StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01"}
+	err := dhcp.ReadStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.IP != "192.168.1.10" || f.reads != 2 {
+		t.Errorf("read mapping to %s after %d page reads, expected 192.168.1.10 after 2", m.IP, f.reads)
+	}
+
+	// nor does a page cut short before its first row
+	f.empty = 1
+	f.reads = 0
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01"}
+	err = dhcp.ReadStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.IP != "192.168.1.10" || f.reads != 2 {
+		t.Errorf("read mapping to %s after %d page reads, expected 192.168.1.10 after 2", m.IP, f.reads)
+	}
+
+	// while a mapping missing from every read is gone
+	f.mappings = nil
+	f.reads = 0
+	m = StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01"}
+	err = dhcp.ReadStaticMapping(&m)
+	if err == nil || err.Error() != ErrNoSuchMAC {
+		t.Errorf("unexpected error %v", err)
+	}
+	if f.reads != 1+DHCPPartialReadRetries {
+		t.Errorf("%d page reads, expected %d", f.reads, 1+DHCPPartialReadRetries)
+	}
+}
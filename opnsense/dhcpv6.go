@@ -0,0 +1,83 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"strings"
+)
+
+const (
+	// DHCPv6ServiceURI is the WebUI DHCPv6 service URI
+	DHCPv6ServiceURI = "/services_dhcpv6.php"
+)
+
+// DHCPv6Status abstracts the DHCPv6 server configuration of a given interface,
+// including its prefix delegation range
+type DHCPv6Status struct {
+	Interface    string
+	Enabled      bool
+	Subnet       string
+	RangeFrom    string
+	RangeTo      string
+	PrefixFrom   string
+	PrefixTo     string
+	PrefixLength string
+}
+
+// GetDHCPv6Status retrieves the DHCPv6 server configuration of a given interface.
+// Interfaces DHCPv6 can't be configured on (e.g. without a static IPv6 address)
+// are reported as disabled, without any range.
+func (s *DHCPSession) GetDHCPv6Status(iface string) (*DHCPv6Status, error) {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return nil, err
+	}
+
+	// read out the service page
+	dhcpURI := s.OPN.URL(fmt.Sprintf("%s?if=%s", DHCPv6ServiceURI, iface))
+	doc, err := s.OPN.GetPage(dhcpURI)
+	if err != nil {
+		return nil, err
+	}
+
+	st := DHCPv6Status{
+		Interface: iface,
+	}
+
+	// no settings form is served for interfaces DHCPv6 can't be configured on
+	if htmlquery.FindOne(doc, `//input[@name="range_from"]`) == nil {
+		return &st, nil
+	}
+
+	st.Enabled = IsChecked(doc, "enable")
+	st.RangeFrom = InputValue(doc, "range_from")
+	st.RangeTo = InputValue(doc, "range_to")
+	st.PrefixFrom = InputValue(doc, "prefixrange_from")
+	st.PrefixTo = InputValue(doc, "prefixrange_to")
+	st.PrefixLength = SelectedValue(doc, "prefixrange_length")
+	if st.PrefixLength == "" {
+		st.PrefixLength = InputValue(doc, "prefixrange_length")
+	}
+
+	// interface subnet, as displayed by the WebUI, its mask being a number of bits
+	subnet := ""
+	n := htmlquery.FindOne(doc, fmt.Sprintf(`//td[normalize-space(.)="%s"]/following-sibling::td[1]`, s.OPN.Localize("Subnet")))
+	if n != nil {
+		subnet = NormalizeText(htmlquery.InnerText(n))
+	}
+	bits := ""
+	n = htmlquery.FindOne(doc, fmt.Sprintf(`//td[normalize-space(.)="%s"]/following-sibling::td[1]`, s.OPN.Localize("Subnet mask")))
+	if n != nil {
+		if f := strings.Fields(NormalizeText(htmlquery.InnerText(n))); len(f) > 0 {
+			bits = f[0]
+		}
+	}
+	st.Subnet = subnet
+	if subnet != "" && bits != "" && !strings.Contains(subnet, "/") {
+		st.Subnet = fmt.Sprintf("%s/%s", subnet, bits)
+	}
+
+	return &st, nil
+}
@@ -1,11 +1,20 @@
 package opnsense
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/antchfx/htmlquery"
 	"github.com/asmcos/requests"
 	"golang.org/x/net/html"
+	"log"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // DNSEntryStartingRow exposes the HTML row where static maps actually start from
@@ -20,17 +29,51 @@ const (
 	DNSType = "Type"
 	// DNSValue refers to the HTML table field for DNS host entry creation/edition
 	DNSValue = "Value"
+	// DNSIP refers to the HTML table field for Dnsmasq host entry creation/edition
+	DNSIP = "IP"
 	// DNSDescription refers to the HTML table field for DNS host entry creation/edition
 	DNSDescription = "Description"
 )
 
+// DNSNamePrefix marks the descriptions of the host overrides named by the provider: these
+// identify their entry, while other descriptions are mere comments
+const DNSNamePrefix = "terraform:"
+
+const (
+	// DNSWildcardHost is the host override value answering for any name of its domain
+	DNSWildcardHost = "*"
+	// DNSWildcardProbeHost is the host name resolved to check that a wildcard override is served
+	DNSWildcardProbeHost = "opnsense-wildcard-probe"
+)
+
 const (
 	// DNSServiceURI is the WebUI service URI
 	DNSServiceURI = "/services_unbound_overrides.php"
 	// DNSServiceEditURI is the WebUI service edit URI
 	DNSServiceEditURI = "/services_unbound_host_edit.php"
+	// DNSDnsmasqServiceURI is the WebUI service URI, when Dnsmasq is the DNS backend
+	DNSDnsmasqServiceURI = "/services_dnsmasq.php"
+	// DNSDnsmasqServiceEditURI is the WebUI service edit URI, when Dnsmasq is the DNS backend
+	DNSDnsmasqServiceEditURI = "/services_dnsmasq_edit.php"
+	// DNSServiceStatusURI is the API endpoint reporting whether Unbound is running
+	DNSServiceStatusURI = "/api/unbound/service/status"
+	// DNSDnsmasqServiceStatusURI is the API endpoint reporting whether Dnsmasq is running
+	DNSDnsmasqServiceStatusURI = "/api/dnsmasq/service/status"
+)
+
+const (
+	// DNSBackendUnbound is the Unbound DNS resolver backend
+	DNSBackendUnbound = "unbound"
+	// DNSBackendDnsmasq is the Dnsmasq DNS forwarder backend
+	DNSBackendDnsmasq = "dnsmasq"
 )
 
+// DNSBackends lists the supported DNS backends
+var DNSBackends = []string{
+	DNSBackendUnbound,
+	DNSBackendDnsmasq,
+}
+
 const (
 	// ErrDNSNoEntries is thrown when no entry can be found
 	ErrDNSNoEntries = "unable to retrieve list of DNS host overrides"
@@ -38,59 +81,154 @@ const (
 	ErrDNSHostExists = "DNS override for this host already exists"
 	// ErrDNSNoSuchEntry is thrown if no host override entry can be found
 	ErrDNSNoSuchEntry = "host override entry doesn't exists"
+	// ErrDNSAmbiguousEntry is thrown if several host override entries match, so that none can be told apart
+	ErrDNSAmbiguousEntry = "several host override entries match, refusing to pick one"
+	// ErrDNSViewUnsupported is thrown if a view is requested while the WebUI doesn't expose any
+	ErrDNSViewUnsupported = "this OPNSense version doesn't support targeting an Unbound view from host overrides"
+	// ErrDNSTTLUnsupported is thrown if a TTL is requested while the WebUI doesn't expose any
+	ErrDNSTTLUnsupported = "this OPNSense version doesn't support setting a TTL on host overrides"
+	// ErrDNSApplyTimeout is logged as a warning if an applied host override isn't served in time
+	ErrDNSApplyTimeout = "timed out waiting for host override to be served by Unbound"
+	// ErrDNSTXTUnsupported is thrown if a TXT record is requested while the WebUI doesn't expose TXT data
+	ErrDNSTXTUnsupported = "this OPNSense version doesn't support TXT host overrides"
+	// ErrDNSTypeUnsupported is thrown if a record type other than A/AAAA is requested from Dnsmasq
+	ErrDNSTypeUnsupported = "Dnsmasq host overrides only support A and AAAA records"
+)
+
+// DNSTXTChunkSize is the maximum length of a single TXT record character-string,
+// longer values being split into several ones
+const DNSTXTChunkSize = 255
+
+const (
+	// DNSApplyTimeout is the default time given to Unbound to serve an applied host override
+	DNSApplyTimeout = 30 * time.Second
+	// DNSApplyPollInterval is the delay between two checks of an applied host override
+	DNSApplyPollInterval = 500 * time.Millisecond
 )
 
 // DNSSession abstracts OPNSense UnboundDNS Overrides
 type DNSSession struct {
-	OPN    *OPNSession
-	Fields []string
+	OPN          *OPNSession
+	Fields       []string
+	Index        map[string]int
+	ApplyTimeout time.Duration
+	// Backend is the DNS service host overrides are managed with, Unbound when unset
+	Backend string
+	// mu guards lazily discovered table fields against concurrent resource operations
+	mu sync.Mutex
 }
 
 // DNSHostEntry abstracts a DNS Host override
 type DNSHostEntry struct {
-	ID     int
-	Type   string
-	Host   string
-	Domain string
-	IP     string
+	ID          int
+	Type        string
+	Host        string
+	Domain      string
+	IP          string
+	Description string
+	Disabled    bool
+	View        string
+	MXPriority  int
+	TTL         int
+	// Deferred leaves the entry saved but not applied, until a later change or commit reloads DNS server
+	Deferred bool
 }
 
 ///////////////////////
 // Private Functions //
 ///////////////////////
 
+// IsDnsmasq tells whether the Dnsmasq DNS backend is in use
+func (s *DNSSession) IsDnsmasq() bool {
+	return s.Backend == DNSBackendDnsmasq
+}
+
+// serviceURI returns the host overrides WebUI page of the DNS backend in use
+func (s *DNSSession) serviceURI() string {
+	if s.IsDnsmasq() {
+		return DNSDnsmasqServiceURI
+	}
+	return DNSServiceURI
+}
+
+// serviceStatusURI returns the status API endpoint of the DNS backend in use
+func (s *DNSSession) serviceStatusURI() string {
+	if s.IsDnsmasq() {
+		return DNSDnsmasqServiceStatusURI
+	}
+	return DNSServiceStatusURI
+}
+
+// serviceEditURI returns the host override edit WebUI page of the DNS backend in use
+func (s *DNSSession) serviceEditURI() string {
+	if s.IsDnsmasq() {
+		return DNSDnsmasqServiceEditURI
+	}
+	return DNSServiceEditURI
+}
+
+// dnsmasqType infers the record type of a Dnsmasq host override, which only maps names to addresses
+func dnsmasqType(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr != nil && addr.To4() == nil {
+		return "AAAA"
+	}
+	return "A"
+}
+
 // GetStaticFieldNames extracts the HTML page host overrides headers for creation/edition
-func (s *DNSSession) GetStaticFieldNames(node *html.Node, start int) {
+func (s *DNSSession) GetStaticFieldNames(node *html.Node, start int) error {
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(node, `//table[@class="table table-striped"]`) == nil {
+		return s.OPN.UnexpectedPage(node, "host overrides table")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if len(s.Fields) > 0 {
 		// already filled-in, no need to go any further
-		return
+		return nil
 	}
 
 	q := fmt.Sprintf(`//table[@class="table table-striped"]//tr[%d]`, start)
 	headers := htmlquery.FindOne(node, q)
-	s.Fields = []string{}
+	if headers == nil {
+		return s.OPN.UnexpectedPage(node, "host overrides table headers")
+	}
+
+	// fields are only published once complete, concurrent parsers rely on them
+	fields := []string{}
+	index := map[string]int{}
 	for child := headers.FirstChild; child != nil; child = child.NextSibling {
 		if child.Type == html.ElementNode {
-			content := strings.TrimSpace(htmlquery.InnerText(child))
+			content := s.OPN.Canonical(NormalizeText(htmlquery.InnerText(child)))
 			if len(content) > 0 {
-				s.Fields = append(s.Fields, content)
+				index[content] = len(fields)
+				fields = append(fields, content)
 			}
 		}
 	}
+	s.Fields = fields
+	s.Index = index
+
+	return nil
 }
 
 // GetStaticMappingField extracts a given DNS host override entry from OPNsense DNS overrides web page
 func (s *DNSSession) GetStaticMappingField(node *html.Node, f string) string {
 	res := ""
 
-	// find the requested field index in HTML table
-	id := index(s.Fields, f) + 1
-	if id == -1 {
+	// find the requested field index in HTML table, under the lock it's discovered with
+	s.mu.Lock()
+	id, ok := s.Index[f]
+	s.mu.Unlock()
+	if !ok {
 		return res
 	}
 
-	// XPath query to find the associated HTML node
-	q := fmt.Sprintf(`//td[%d]//text()`, id)
+	// XPath query to find the associated HTML node (XPath indexes start at 1)
+	q := fmt.Sprintf(`//td[%d]//text()`, id+1)
 	values, err := htmlquery.QueryAll(node, q)
 	if err != nil {
 		return res
@@ -101,7 +239,7 @@ func (s *DNSSession) GetStaticMappingField(node *html.Node, f string) string {
 		if v.Type != html.TextNode {
 			continue
 		}
-		content := strings.TrimSpace(htmlquery.InnerText(v))
+		content := NormalizeText(htmlquery.InnerText(v))
 		res = res + content
 	}
 
@@ -119,49 +257,192 @@ func (s *DNSSession) GetAllHostEntries() ([]DNSHostEntry, error) {
 		return entries, err
 	}
 
-	// read out the service page
-	dnsURI := fmt.Sprintf("%s%s", s.OPN.RootURI, DNSServiceURI)
-	resp, err := s.OPN.Session.Get(dnsURI)
+	// read out the service page, following up pagination if any
+	dnsURI := s.OPN.URL(s.serviceURI())
+	docs, err := s.OPN.GetAllPages(dnsURI)
 	if err != nil {
 		return entries, err
 	}
 
-	// get HTML
-	page := strings.NewReader(resp.Text())
-	doc, err := htmlquery.Parse(page)
-	if err != nil {
-		return entries, err
+	// retrieve all configured DNS host override entries, across all pages
+	id := 0
+	for _, doc := range docs {
+		// lookup for static fields types
+		err = s.GetStaticFieldNames(doc, DNSEntryStartingRow)
+		if err != nil {
+			return entries, err
+		}
+
+		// XPath query to find all table rows
+		q := fmt.Sprintf(`//table[@class="table table-striped"]//tr`)
+		rows, err := htmlquery.QueryAll(doc, q)
+		if err != nil {
+			return entries, err
+		}
+
+		for i := DNSEntryStartingRow; i < len(rows); i++ {
+			r := rows[i]
+
+			// rely on OPNSense internal ID, table position being a fallback for older markups
+			rowID := RowID(r, s.serviceEditURI())
+			if rowID < 0 {
+				rowID = id
+			}
+
+			e := DNSHostEntry{
+				ID:          rowID,
+				Type:        s.GetStaticMappingField(r, DNSType),
+				Host:        s.GetStaticMappingField(r, DNSHost),
+				Domain:      s.GetStaticMappingField(r, DNSDomain),
+				IP:          s.GetStaticMappingField(r, DNSValue),
+				Description: s.GetStaticMappingField(r, DNSDescription),
+			}
+
+			// Dnsmasq only lists addresses, without any record type column
+			if s.IsDnsmasq() {
+				if e.IP == "" {
+					e.IP = s.GetStaticMappingField(r, DNSIP)
+				}
+				if e.Type == "" {
+					e.Type = dnsmasqType(e.IP)
+				}
+			}
+
+			// TXT records value is displayed as quoted strings
+			if e.Type == "TXT" {
+				e.IP = DecodeTXT(e.IP)
+			}
+
+			// MX records value is displayed as "<priority> <host>"
+			if e.Type == "MX" {
+				v := strings.Fields(e.IP)
+				if len(v) == 2 {
+					prio, err := strconv.Atoi(v[0])
+					if err == nil {
+						e.MXPriority = prio
+						e.IP = v[1]
+					}
+				}
+			}
+
+			entries = append(entries, e)
+			id++
+		}
 	}
 
-	// lookup for static fields types
-	s.GetStaticFieldNames(doc, DNSEntryStartingRow)
+	return entries, nil
+}
 
-	// XPath query to find all table rows
-	q := fmt.Sprintf(`//table[@class="table table-striped"]//tr`)
-	rows, err := htmlquery.QueryAll(doc, q)
-	if err != nil {
-		return entries, err
+// Key identifies an host override by its values
+func (e *DNSHostEntry) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", e.Type, e.Host, e.Domain, e.IP)
+}
+
+// NormalizeDomain returns the canonical form of a domain name: lowercase, without trailing dot
+func NormalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+// ReverseName returns the PTR record name matching an IP address:
+// in-addr.arpa for IPv4 and nibble-format ip6.arpa for IPv6.
+func ReverseName(ip string) (string, error) {
+	addr := net.ParseIP(strings.TrimSpace(ip))
+	if addr == nil {
+		return "", fmt.Errorf("%q is not a valid IP address", ip)
+	}
+
+	if v4 := addr.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", v4[3], v4[2], v4[1], v4[0]), nil
 	}
 
-	// retrieve all configured DNS host override entries
-	for i := DNSEntryStartingRow; i < len(rows); i++ {
-		r := rows[i]
-		e := DNSHostEntry{
-			ID:     i - DNSEntryStartingRow,
-			Type:   s.GetStaticMappingField(r, DNSType),
-			Host:   s.GetStaticMappingField(r, DNSHost),
-			Domain: s.GetStaticMappingField(r, DNSDomain),
-			IP:     s.GetStaticMappingField(r, DNSValue),
+	const hex = "0123456789abcdef"
+	v6 := addr.To16()
+	nibbles := make([]string, 0, 2*len(v6)+1)
+	for i := len(v6) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, string(hex[v6[i]&0x0f]), string(hex[v6[i]>>4]))
+	}
+	nibbles = append(nibbles, "ip6.arpa")
+
+	return strings.Join(nibbles, "."), nil
+}
+
+// EncodeTXT converts a TXT record value into quoted character-strings, as expected by
+// Unbound: quotes and backslashes are escaped, and values longer than DNSTXTChunkSize
+// bytes are split into several strings (without breaking UTF-8 sequences)
+func EncodeTXT(value string) string {
+	chunks := []string{}
+	chunk := ""
+	for _, r := range value {
+		if len(chunk)+len(string(r)) > DNSTXTChunkSize {
+			chunks = append(chunks, chunk)
+			chunk = ""
 		}
-		entries = append(entries, e)
+		chunk += string(r)
 	}
+	chunks = append(chunks, chunk)
 
-	return entries, nil
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for i, c := range chunks {
+		chunks[i] = `"` + escaper.Replace(c) + `"`
+	}
+	return strings.Join(chunks, " ")
+}
+
+// DecodeTXT converts TXT record quoted character-strings back into their value,
+// joining split strings. Unquoted data is returned as is.
+func DecodeTXT(data string) string {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+
+	var value strings.Builder
+	quoted, escaped := false, false
+	for _, r := range data {
+		switch {
+		case escaped:
+			value.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+			value.WriteRune(r)
+		}
+	}
+	return value.String()
+}
+
+// dnsValuesMatch tells whether two record values are the same, TXT values being
+// compared regardless of whitespace the WebUI table collapses
+func dnsValuesMatch(rr, v1, v2 string) bool {
+	if rr == "TXT" {
+		return NormalizeText(v1) == NormalizeText(v2)
+	}
+	return v1 == v2
+}
+
+// DNSEntryName returns the name of a host override named by the provider, if any
+func DNSEntryName(e *DNSHostEntry) string {
+	if !strings.HasPrefix(e.Description, DNSNamePrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(e.Description, DNSNamePrefix)
+}
+
+// DNSNamedDescription returns the description identifying a host override by its name
+func DNSNamedDescription(name string) string {
+	return DNSNamePrefix + name
 }
 
-// HostsMatch compares if 2 host entries are alike
+// HostsMatch compares if 2 host entries are alike.
+// Entries named by the provider are uniquely identified by their name (see DNSEntryName).
 func (s *DNSSession) HostsMatch(e1, e2 *DNSHostEntry) bool {
-	if (e1.Host == e2.Host) && (e1.Domain == e2.Domain) && (e1.Type == e2.Type) && (e1.IP == e2.IP) {
+	if DNSEntryName(e1) != "" {
+		return e1.Description == e2.Description
+	}
+	if (e1.Host == e2.Host) && (NormalizeDomain(e1.Domain) == NormalizeDomain(e2.Domain)) && (e1.Type == e2.Type) && dnsValuesMatch(e1.Type, e1.IP, e2.IP) {
 		return true
 	}
 	return false
@@ -176,15 +457,50 @@ func (s *DNSSession) FindHostEntry(h *DNSHostEntry) (*DNSHostEntry, error) {
 		return nil, err
 	}
 
-	// check if an entry exists
+	// check if an entry exists, and only one
+	matches := []DNSHostEntry{}
 	for _, e := range entries {
-		// we found it
 		if s.HostsMatch(h, &e) {
-			return &e, nil
+			matches = append(matches, e)
 		}
 	}
 
-	return nil, s.OPN.Error(ErrDNSNoSuchEntry)
+	switch len(matches) {
+	case 0:
+		return nil, s.OPN.Error(ErrDNSNoSuchEntry)
+	case 1:
+		return &matches[0], nil
+	}
+
+	ids := []string{}
+	for _, e := range matches {
+		ids = append(ids, strconv.Itoa(e.ID))
+	}
+	return nil, fmt.Errorf("%s (entries %s)", ErrDNSAmbiguousEntry, strings.Join(ids, ", "))
+}
+
+// FindHostEntries retrieves all entries matching host, domain and type, whatever their IP
+func (s *DNSSession) FindHostEntries(h *DNSHostEntry) ([]DNSHostEntry, error) {
+
+	// retrieves existing host entries
+	entries, err := s.GetAllHostEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	// collect all round-robin entries
+	matches := []DNSHostEntry{}
+	for _, e := range entries {
+		if (e.Host == h.Host) && (NormalizeDomain(e.Domain) == NormalizeDomain(h.Domain)) && (e.Type == h.Type) {
+			matches = append(matches, e)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, s.OPN.Error(ErrDNSNoSuchEntry)
+	}
+
+	return matches, nil
 }
 
 // FindHostEntryByID retrieves all entries select the one that matches the ID
@@ -207,71 +523,288 @@ func (s *DNSSession) FindHostEntryByID(id int) (*DNSHostEntry, error) {
 	return nil, s.OPN.Error(ErrDNSNoSuchEntry)
 }
 
-// Apply validates the configuration and reload DNS server
-func (s *DNSSession) Apply(formName, formValue string) error {
+// Apply validates the configuration and reload DNS server,
+// unless changes are deferred until an explicit Commit
+func (s *DNSSession) Apply(page string) (*ApplyResult, error) {
+	if s.OPN.DeferApply {
+		log.Printf("[DEBUG] OPNSense DNS changes deferred")
+		return &ApplyResult{Pending: true}, nil
+	}
+	return s.Commit(page)
+}
+
+// Commit validates the configuration and reload DNS server
+func (s *DNSSession) Commit(page string) (*ApplyResult, error) {
 	// apply changes
 	data := requests.Datas{
 		"apply": "Apply changes",
 	}
-	if formName != "" || formValue != "" {
-		data[formName] = formValue
+
+	applyURI := s.OPN.URL(s.serviceURI())
+	return s.OPN.ApplyChanges(applyURI, page, data)
+}
+
+// dnsResolvable tells whether served records of a type can be checked through a resolver
+func dnsResolvable(rr string) bool {
+	switch rr {
+	case "A", "AAAA", "CNAME", "MX", "TXT":
+		return true
 	}
+	return false
+}
 
-	applyURI := fmt.Sprintf("%s%s", s.OPN.RootURI, DNSServiceURI)
-	_, err := s.OPN.Session.Post(applyURI, data)
+// resolve checks whether OPNSense DNS server serves a host override with its value.
+// It returns an error if the DNS server can't be queried at all.
+func (s *DNSSession) resolve(h *DNSHostEntry) (bool, error) {
+	u, err := url.Parse(s.OPN.RootURI)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return nil
+
+	// query OPNSense own DNS server, where overrides are served from
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{}
+			return d.DialContext(ctx, network, net.JoinHostPort(u.Hostname(), "53"))
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// a wildcard isn't a valid name to look up, any name of its domain is served alike
+	host := h.Host
+	if host == DNSWildcardHost {
+		host = DNSWildcardProbeHost
+	}
+	name := dnsFQDN(host, h.Domain)
+
+	values := []string{}
+	switch h.Type {
+	case "CNAME":
+		var cname string
+		cname, err = r.LookupCNAME(ctx, name)
+		values = append(values, cname)
+	case "MX":
+		var mxs []*net.MX
+		mxs, err = r.LookupMX(ctx, name)
+		for _, mx := range mxs {
+			if int(mx.Pref) == h.MXPriority {
+				values = append(values, mx.Host)
+			}
+		}
+	case "TXT":
+		values, err = r.LookupTXT(ctx, name)
+	default:
+		values, err = r.LookupHost(ctx, name)
+	}
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, v := range values {
+		switch h.Type {
+		case "CNAME", "MX":
+			if NormalizeDomain(v) == NormalizeDomain(h.IP) {
+				return true, nil
+			}
+		case "TXT":
+			if dnsValuesMatch(h.Type, v, h.IP) {
+				return true, nil
+			}
+		default:
+			if net.ParseIP(v).Equal(net.ParseIP(h.IP)) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
 }
 
-// CreateOrEdit creates or edit an host override entry
-func (s *DNSSession) CreateOrEdit(e *DNSHostEntry) error {
+// serviceRunning checks whether OPNSense reports the DNS service as running
+func (s *DNSSession) serviceRunning() (bool, error) {
+	res := APIResult{}
+	err := s.OPN.GetJSON(s.serviceStatusURI(), &res)
+	if err != nil {
+		return false, err
+	}
+	return res.Status == "running", nil
+}
+
+// WaitApplied polls until an applied host override is served, i.e. it resolves to its value
+// through OPNSense DNS server. Reloads are slow, so that dependent resources may fail otherwise.
+// Only A, AAAA, CNAME, MX and TXT records can be checked this way, and only if the DNS server
+// can be queried from here: otherwise it merely waits for OPNSense to report the DNS service
+// as running again, which doesn't tell whether the override itself is served.
+// Overrides still not served after the apply timeout are only reported as a warning.
+// It only reads from OPNSense, callers are expected to release the provider semaphore beforehand.
+func (s *DNSSession) WaitApplied(h *DNSHostEntry) error {
+	// nothing will be served until changes get committed
+	if s.OPN.DeferApply || h.Deferred {
+		return nil
+	}
+
+	// disabled overrides are never served, and view-scoped ones possibly not to us
+	if h.Disabled || h.View != "" {
+		log.Printf("[DEBUG] OPNSense DNS host override %s.%s can't be checked, not waiting for it", h.Host, h.Domain)
+		return nil
+	}
+
+	timeout := s.ApplyTimeout
+	if timeout == 0 {
+		timeout = DNSApplyTimeout
+	}
+
+	canResolve := dnsResolvable(h.Type)
+	if !canResolve {
+		log.Printf("[DEBUG] OPNSense DNS host override %s.%s is of type %s, only waiting for DNS service to run", h.Host, h.Domain, h.Type)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if canResolve {
+			ok, err := s.resolve(h)
+			if err != nil {
+				// DNS server isn't reachable from here
+				log.Printf("[DEBUG] OPNSense DNS server can't be queried, only waiting for DNS service to run: %v", err)
+				canResolve = false
+				continue
+			}
+			if ok {
+				return nil
+			}
+		} else {
+			ok, err := s.serviceRunning()
+			if err != nil {
+				log.Printf("[DEBUG] OPNSense DNS service status is unavailable, not waiting for %s.%s: %v", h.Host, h.Domain, err)
+				return nil
+			}
+			if ok {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			log.Printf("[WARN] %s: %s.%s", ErrDNSApplyTimeout, h.Host, h.Domain)
+			return nil
+		}
+		time.Sleep(DNSApplyPollInterval)
+	}
+}
+
+// Save creates or edit an host override entry, without applying changes.
+// It returns the most recent page, to be used for applying.
+func (s *DNSSession) Save(e *DNSHostEntry) (string, error) {
+
+	// Dnsmasq only maps names to addresses
+	if s.IsDnsmasq() && e.Type != "" && e.Type != "A" && e.Type != "AAAA" {
+		return "", s.OPN.Error(ErrDNSTypeUnsupported)
+	}
 
 	// get the edit page to retrieve form secret values
-	editURI := fmt.Sprintf("%s%s", s.OPN.RootURI, DNSServiceEditURI)
+	editURI := s.OPN.URL(s.serviceEditURI())
 	if e.ID != -1 {
-		editURI = fmt.Sprintf("%s&id=%d", editURI, e.ID)
+		editURI = fmt.Sprintf("%s?id=%d", editURI, e.ID)
 	}
-	resp, err := s.OPN.Session.Get(editURI)
+	resp, err := s.OPN.Get(editURI)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// get HTML
-	page := strings.NewReader(resp.Text())
-	doc, err := htmlquery.Parse(page)
-	if err != nil {
-		return err
+	// split-horizon views can only be targeted, and TTL set, if the edit page exposes them
+	if e.View != "" || e.TTL != 0 {
+		doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
+		if err != nil {
+			return "", err
+		}
+		if e.View != "" && htmlquery.FindOne(doc, `//*[@name="view"]`) == nil {
+			return "", s.OPN.Error(ErrDNSViewUnsupported)
+		}
+		if e.TTL != 0 && htmlquery.FindOne(doc, `//*[@name="ttl"]`) == nil {
+			return "", s.OPN.Error(ErrDNSTTLUnsupported)
+		}
 	}
 
-	// get form runtime values
-	q := fmt.Sprintf(`//div[@class="content-box"]//form//input`)
-	n := htmlquery.FindOne(doc, q)
-	formName := htmlquery.SelectAttr(n, "name")
-	formValue := htmlquery.SelectAttr(n, "value")
+	// TXT records can only be set if the edit page exposes TXT data
+	if e.Type == "TXT" {
+		doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
+		if err != nil {
+			return "", err
+		}
+		if htmlquery.FindOne(doc, `//*[@name="txtdata"]`) == nil {
+			return "", s.OPN.Error(ErrDNSTXTUnsupported)
+		}
+	}
 
-	// create a new DHCP entry
+	// create a new DNS entry
 	data := requests.Datas{
-		formName: formValue,
 		"host":   e.Host,
 		"domain": e.Domain,
 		"rr":     e.Type,
 		"ip":     e.IP,
-		"descr":  "",
+		"descr":  e.Description,
 		"Submit": "Save",
 	}
 	if e.ID != -1 {
 		data["id"] = fmt.Sprintf("%d", e.ID)
 	}
+	if e.Disabled {
+		data["disabled"] = "yes"
+	}
+	if e.View != "" {
+		data["view"] = e.View
+	}
+	if e.TTL != 0 {
+		data["ttl"] = fmt.Sprintf("%d", e.TTL)
+	}
+	if s.IsDnsmasq() {
+		// record type is implied by the address
+		delete(data, "rr")
+	}
+	if e.Type == "TXT" {
+		// TXT records have their own, quoted, data field
+		delete(data, "ip")
+		data["txtdata"] = EncodeTXT(e.IP)
+	}
+	if e.Type == "MX" {
+		// MX records have their own target host and priority fields
+		delete(data, "ip")
+		data["mx"] = e.IP
+		data["mxprio"] = fmt.Sprintf("%d", e.MXPriority)
+	}
 
-	resp, err = s.OPN.Session.Post(editURI, data)
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return "", err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text(), nil
+}
+
+// CreateOrEdit creates or edit an host override entry
+func (s *DNSSession) CreateOrEdit(e *DNSHostEntry) error {
+
+	page, err := s.Save(e)
 	if err != nil {
 		return err
 	}
+	if e.Deferred {
+		log.Printf("[DEBUG] OPNSense DNS host override %s.%s left to be applied", e.Host, e.Domain)
+		return nil
+	}
 
 	// apply changes
-	err = s.Apply(formName, formValue)
+	_, err = s.Apply(page)
 	if err != nil {
 		return err
 	}
@@ -288,10 +821,13 @@ func (s *DNSSession) CreateHostOverride(h *DNSHostEntry) error {
 
 	e, err := s.FindHostEntry(h)
 
-	// check if the host override is not already registered
+	// check if the host override is not already registered, nor ambiguous
 	if e != nil {
 		return s.OPN.Error(ErrDNSHostExists)
 	}
+	if err != nil && err.Error() != ErrDNSNoSuchEntry {
+		return err
+	}
 
 	// create the mapping entry
 	h.ID = -1
@@ -303,6 +839,123 @@ func (s *DNSSession) CreateHostOverride(h *DNSHostEntry) error {
 	return nil
 }
 
+// CreateOrAdoptHostOverride creates a new host override, unless an entry of the same type,
+// host and domain already exists, in which case it's overwritten with the new values
+func (s *DNSSession) CreateOrAdoptHostOverride(h *DNSHostEntry) error {
+
+	// look for the entry to adopt, whatever its value
+	entries, err := s.FindHostEntries(h)
+	if err != nil && err.Error() != ErrDNSNoSuchEntry {
+		return err
+	}
+
+	switch len(entries) {
+	case 0:
+		return s.CreateHostOverride(h)
+	case 1:
+		log.Printf("[INFO] OPNSense DNS host override %s.%s already exists, adopting entry %d", h.Host, h.Domain, entries[0].ID)
+		h.ID = entries[0].ID
+		return s.CreateOrEdit(h)
+	}
+
+	ids := []string{}
+	for _, e := range entries {
+		ids = append(ids, strconv.Itoa(e.ID))
+	}
+	return fmt.Errorf("%s (entries %s)", ErrDNSAmbiguousEntry, strings.Join(ids, ", "))
+}
+
+// UpdateRoundRobin adds and removes IPs of a round-robin host override, i.e. entries sharing
+// the same type, host and domain, h holding their other settings. Changes are applied with
+// a single DNS server reload.
+func (s *DNSSession) UpdateRoundRobin(h *DNSHostEntry, added, removed []string) error {
+
+	// retrieves the current round-robin entries
+	entries, err := s.FindHostEntries(h)
+	if err != nil && err.Error() != ErrDNSNoSuchEntry {
+		return err
+	}
+	live := map[string]DNSHostEntry{}
+	for _, e := range entries {
+		live[e.IP] = e
+	}
+
+	// check that nothing is added twice before changing anything
+	for _, ip := range added {
+		if _, ok := live[ip]; ok {
+			return fmt.Errorf("%s: %s", ErrDNSHostExists, ip)
+		}
+	}
+
+	// legacy IDs are config positions, renumbered on every removal: remove the last ones first
+	obsolete := []DNSHostEntry{}
+	for _, ip := range removed {
+		if e, ok := live[ip]; ok {
+			obsolete = append(obsolete, e)
+		}
+	}
+	sort.Slice(obsolete, func(i, j int) bool {
+		return obsolete[i].ID > obsolete[j].ID
+	})
+
+	page := ""
+	for _, e := range obsolete {
+		page, err = s.Remove(&e)
+		if err != nil {
+			return err
+		}
+	}
+	for _, ip := range added {
+		rr := *h
+		rr.ID = -1
+		rr.IP = ip
+		page, err = s.Save(&rr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if page == "" {
+		return nil
+	}
+	if h.Deferred {
+		log.Printf("[DEBUG] OPNSense DNS host override %s.%s left to be applied", h.Host, h.Domain)
+		return nil
+	}
+
+	// apply all changes at once
+	_, err = s.Apply(page)
+	return err
+}
+
+// ReadDetails retrieves an host override settings only shown on its edit page
+// (i.e. disabled flag, view and TTL, left empty when the page doesn't expose them,
+// and verbatim TXT values)
+func (s *DNSSession) ReadDetails(e *DNSHostEntry) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?id=%d", s.serviceEditURI(), e.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	e.Disabled = IsChecked(doc, "disabled")
+	e.TTL, _ = strconv.Atoi(InputValue(doc, "ttl"))
+
+	// views are offered as a selection, or as a free-form input on older markups
+	e.View = SelectedValue(doc, "view")
+	if e.View == "" {
+		e.View = InputValue(doc, "view")
+	}
+
+	// the overrides table collapses TXT values whitespace, the edit page keeps them verbatim
+	if e.Type == "TXT" && htmlquery.FindOne(doc, `//*[@name="txtdata"]`) != nil {
+		e.IP = DecodeTXT(InputValue(doc, "txtdata"))
+	}
+
+	return nil
+}
+
 // ReadHostOverride retrieves DNS information for a specified host
 func (s *DNSSession) ReadHostOverride(h *DNSHostEntry) error {
 
@@ -312,8 +965,8 @@ func (s *DNSSession) ReadHostOverride(h *DNSHostEntry) error {
 		return err
 	}
 
-	// assign values accordingly
-	h.ID = e.ID
+	// assign all values accordingly, so that every field reflects live state
+	*h = *e
 
 	return nil
 }
@@ -321,12 +974,23 @@ func (s *DNSSession) ReadHostOverride(h *DNSHostEntry) error {
 // UpdateHostOverride modifies an already existing host override
 func (s *DNSSession) UpdateHostOverride(h *DNSHostEntry) error {
 
-	// check if an entry exists for this specific ID
-	e, err := s.FindHostEntryByID(h.ID)
+	// check if an entry exists for this specific name or ID
+	var e *DNSHostEntry
+	var err error
+	if DNSEntryName(h) != "" {
+		e, err = s.FindHostEntry(&DNSHostEntry{Description: h.Description})
+	} else {
+		e, err = s.FindHostEntryByID(h.ID)
+	}
 	if e == nil {
 		return err
 	}
 
+	// comments set from the WebUI are kept
+	if h.Description == "" && DNSEntryName(e) == "" {
+		h.Description = e.Description
+	}
+
 	// update the mapping entry
 	h.ID = e.ID
 	err = s.CreateOrEdit(h)
@@ -337,17 +1001,12 @@ func (s *DNSSession) UpdateHostOverride(h *DNSHostEntry) error {
 	return nil
 }
 
-// DeleteHostOverride destroy an existing DNS host entry
-func (s *DNSSession) DeleteHostOverride(h *DNSHostEntry) error {
-
-	// check if an entry exists
-	e, err := s.FindHostEntry(h)
-	if e == nil {
-		return err
-	}
+// Remove destroys an host override entry, without applying changes.
+// It returns the most recent page, to be used for applying.
+func (s *DNSSession) Remove(e *DNSHostEntry) (string, error) {
 
 	// get the DNS page to retrieve form secret values
-	dnsURI := fmt.Sprintf("%s%s", s.OPN.RootURI, DNSServiceURI)
+	dnsURI := s.OPN.URL(s.serviceURI())
 
 	// destroy DNS host entry
 	data := requests.Datas{
@@ -355,16 +1014,262 @@ func (s *DNSSession) DeleteHostOverride(h *DNSHostEntry) error {
 		"act": "del",
 	}
 
-	_, err = s.OPN.Session.Post(dnsURI, data)
+	resp, err := s.OPN.PostForm(dnsURI, "", data)
+	if err != nil {
+		return "", err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text(), nil
+}
+
+// DeleteHostOverride destroy an existing DNS host entry
+func (s *DNSSession) DeleteHostOverride(h *DNSHostEntry) error {
+
+	// check if an entry exists
+	e, err := s.FindHostEntry(h)
+	if e == nil {
+		return err
+	}
+
+	page, err := s.Remove(e)
 	if err != nil {
 		return err
 	}
 
 	// apply changes
-	err = s.Apply("", "")
+	_, err = s.Apply(page)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// GetHostEntriesByDomain retrieves all host overrides of a given domain, whatever its case or trailing dot
+func (s *DNSSession) GetHostEntriesByDomain(domain string) ([]DNSHostEntry, error) {
+
+	// retrieves existing host entries
+	entries, err := s.GetAllHostEntries()
+	if err != nil {
+		return entries, err
+	}
+
+	// only keep the domain ones
+	res := []DNSHostEntry{}
+	for _, e := range entries {
+		if NormalizeDomain(e.Domain) == NormalizeDomain(domain) {
+			res = append(res, e)
+		}
+	}
+
+	return res, nil
+}
+
+// SetEnabledByDomain enables or disables all host overrides of a given domain, whatever its case or trailing dot,
+// with a single DNS server reload. It returns the number of affected entries.
+func (s *DNSSession) SetEnabledByDomain(domain string, enabled bool) (int, error) {
+
+	// retrieves existing host entries
+	entries, err := s.GetAllHostEntries()
+	if err != nil {
+		return 0, err
+	}
+
+	// flip every matching entry, keeping the settings the overrides table doesn't show
+	count := 0
+	page := ""
+	for _, e := range entries {
+		if NormalizeDomain(e.Domain) != NormalizeDomain(domain) {
+			continue
+		}
+		err = s.ReadDetails(&e)
+		if err != nil {
+			return count, err
+		}
+		e.Disabled = !enabled
+		page, err = s.Save(&e)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if count == 0 {
+		return 0, nil
+	}
+
+	// apply all changes at once
+	_, err = s.Apply(page)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// DiffAgainst compares live host overrides with a desired set of entries, e.g. for
+// external DNS sync tooling. Desired entries alike (see HostsMatch) a live one, values
+// included, are left aside. Others are to be created unless a live entry shares their name
+// (see DNSEntryName) or type/host/domain, in which case it's to be updated: its ID
+// is then carried by the returned entry. Remaining live entries are to be deleted.
+func (s *DNSSession) DiffAgainst(entries []DNSHostEntry) (toCreate, toUpdate, toDelete []DNSHostEntry, err error) {
+
+	toCreate = []DNSHostEntry{}
+	toUpdate = []DNSHostEntry{}
+	toDelete = []DNSHostEntry{}
+
+	// retrieves existing host entries
+	live, err := s.GetAllHostEntries()
+	if err != nil {
+		return toCreate, toUpdate, toDelete, err
+	}
+	matched := make([]bool, len(live))
+
+	// named entries match whatever their values, compare these as well
+	unchanged := func(e, l DNSHostEntry) bool {
+		if !s.HostsMatch(&e, &l) {
+			return false
+		}
+		e.Description = ""
+		l.Description = ""
+		return s.HostsMatch(&e, &l)
+	}
+
+	// desired entries already served as is
+	pending := []DNSHostEntry{}
+	for _, e := range entries {
+		found := false
+		for i := range live {
+			if !matched[i] && unchanged(e, live[i]) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			pending = append(pending, e)
+		}
+	}
+
+	// desired entries whose values changed, or brand new ones
+	for _, e := range pending {
+		e.ID = -1
+		for i, l := range live {
+			if matched[i] {
+				continue
+			}
+			same := l.Type == e.Type && l.Host == e.Host && NormalizeDomain(l.Domain) == NormalizeDomain(e.Domain)
+			if DNSEntryName(&e) != "" {
+				same = l.Description == e.Description
+			}
+			if same {
+				matched[i] = true
+				e.ID = l.ID
+				break
+			}
+		}
+		if e.ID == -1 {
+			toCreate = append(toCreate, e)
+		} else {
+			toUpdate = append(toUpdate, e)
+		}
+	}
+
+	// live entries no longer desired
+	for i, l := range live {
+		if !matched[i] {
+			toDelete = append(toDelete, l)
+		}
+	}
+
+	return toCreate, toUpdate, toDelete, nil
+}
+
+// SyncHostOverrides turns the current set of host overrides into the desired one,
+// with a single DNS server reload. Entries that couldn't be applied are returned,
+// indexed by their key, along with the reason why.
+func (s *DNSSession) SyncHostOverrides(current, desired []DNSHostEntry) (map[string]error, error) {
+
+	failures := map[string]error{}
+
+	// retrieves existing host entries
+	entries, err := s.GetAllHostEntries()
+	if err != nil {
+		return failures, err
+	}
+	live := map[string]DNSHostEntry{}
+	for _, e := range entries {
+		live[e.Key()] = e
+	}
+	wanted := map[string]bool{}
+	for _, e := range desired {
+		wanted[e.Key()] = true
+	}
+
+	// live entries no longer desired
+	obsolete := []DNSHostEntry{}
+	for _, e := range current {
+		l, ok := live[e.Key()]
+		if ok && !wanted[e.Key()] {
+			obsolete = append(obsolete, l)
+		}
+	}
+
+	page := ""
+	changes := 0
+	for _, e := range desired {
+		if _, ok := live[e.Key()]; ok {
+			continue
+		}
+
+		// edit in place an obsolete entry for the same host, if any, otherwise create a new one
+		e.ID = -1
+		for i, o := range obsolete {
+			if o.Type == e.Type && o.Host == e.Host && NormalizeDomain(o.Domain) == NormalizeDomain(e.Domain) {
+				e.ID = o.ID
+				obsolete = append(obsolete[:i], obsolete[i+1:]...)
+				break
+			}
+		}
+
+		p, err := s.Save(&e)
+		if err != nil {
+			failures[e.Key()] = err
+			continue
+		}
+		page = p
+		changes++
+	}
+
+	// legacy IDs are config positions, renumbered on every removal: remove the last ones first
+	sort.Slice(obsolete, func(i, j int) bool {
+		return obsolete[i].ID > obsolete[j].ID
+	})
+	for _, e := range obsolete {
+		p, err := s.Remove(&e)
+		if err != nil {
+			failures[e.Key()] = err
+			continue
+		}
+		page = p
+		changes++
+	}
+
+	if changes == 0 {
+		return failures, nil
+	}
+
+	// apply all changes at once
+	_, err = s.Apply(page)
+	if err != nil {
+		return failures, err
+	}
+
+	return failures, nil
+}
@@ -0,0 +1,830 @@
+package opnsense
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestGetAllHostEntriesFollowsPagination(t *testing.T) {
+	dns := DNSSession{
+		OPN: newTestSession(t, fixtures(t, map[string]string{
+			DNSServiceURI:             "unbound_overrides_page1.html",
+			DNSServiceURI + "?page=1": "unbound_overrides_page1.html",
+			DNSServiceURI + "?page=2": "unbound_overrides_page2.html",
+		})),
+	}
+
+	entries, err := dns.GetAllHostEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first page is read once, whatever the URI it's linked with
+	keys := []string{}
+	for i, e := range entries {
+		keys = append(keys, e.Key())
+		if e.ID != i {
+			t.Errorf("entry %s has ID %d, expected %d", e.Key(), e.ID, i)
+		}
+	}
+	expected := []string{
+		"A/www/acme.local/192.168.0.10",
+		"MX/mail/acme.local/mx.acme.local",
+		"TXT/_dmarc/acme.local/v=DMARC1; p=reject",
+		"A/ftp/acme.local/192.168.0.21",
+		"AAAA/www/acme.local/2001:db8::10",
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("got entries %q, expected %q", keys, expected)
+	}
+}
+
+// dnsWebUI fakes the legacy Unbound host overrides WebUI pages, rendering the overrides
+// table and edit form out of its state, and saving submitted forms as OPNSense does
+type dnsWebUI struct {
+	mu      sync.Mutex
+	entries []DNSHostEntry
+	nextID  int
+	// views exposes an Unbound view selection on the edit form
+	views []string
+	// dnsmasq serves the Dnsmasq host overrides pages instead, which only map names to addresses
+	dnsmasq bool
+	// positional gives entries their config position as ID, as legacy pages do,
+	// entries following a removed one being renumbered
+	positional bool
+	pending    bool
+	applies    int
+	saves      int
+}
+
+func newDNSWebUI(entries ...DNSHostEntry) *dnsWebUI {
+	f := dnsWebUI{}
+	for _, e := range entries {
+		e.ID = f.nextID
+		f.entries = append(f.entries, e)
+		f.nextID++
+	}
+	return &f
+}
+
+// find returns the position of an entry given its ID, -1 if none
+func (f *dnsWebUI) find(id string) int {
+	for i, e := range f.entries {
+		if strconv.Itoa(e.ID) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *dnsWebUI) servicePage() string {
+	var b strings.Builder
+	b.WriteString(`<html><head><script>$.ajaxSetup({ beforeSend: function(xhr) { xhr.setRequestHeader("X-CSRFToken", "token" ); } });</script></head><body>`)
+	b.WriteString(`<div class="content-box"><form method="post"><input type="hidden" name="csrf" value="token"/>`)
+	if f.pending {
+		b.WriteString(`<input type="submit" name="apply" value="Apply changes"/>`)
+	}
+	b.WriteString(`<table class="table table-striped"><tr><td colspan="6"><strong>Host Overrides</strong></td></tr>`)
+	if f.dnsmasq {
+		b.WriteString(`<tr><td>Host</td><td>Domain</td><td>IP</td><td>Description</td><td></td></tr>`)
+	} else {
+		b.WriteString(`<tr><td>Host</td><td>Domain</td><td>Type</td><td>Value</td><td>Description</td><td></td></tr>`)
+	}
+	for _, e := range f.entries {
+		if f.dnsmasq {
+			fmt.Fprintf(&b, `<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td>`,
+				html.EscapeString(e.Host), html.EscapeString(e.Domain), html.EscapeString(e.IP), html.EscapeString(e.Description))
+			fmt.Fprintf(&b, `<td><a href="%s?id=%d">edit</a></td></tr>`, strings.TrimPrefix(DNSDnsmasqServiceEditURI, "/"), e.ID)
+			continue
+		}
+		value := e.IP
+		switch e.Type {
+		case "MX":
+			value = fmt.Sprintf("%d %s", e.MXPriority, e.IP)
+		case "TXT":
+			value = EncodeTXT(e.IP)
+		}
+		fmt.Fprintf(&b, `<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td>`,
+			html.EscapeString(e.Host), html.EscapeString(e.Domain), e.Type, html.EscapeString(value), html.EscapeString(e.Description))
+		fmt.Fprintf(&b, `<td><a href="%s?id=%d">edit</a><a data-id="%d" class="act_delete_host">delete</a></td></tr>`,
+			strings.TrimPrefix(DNSServiceEditURI, "/"), e.ID, e.ID)
+	}
+	b.WriteString(`</table></form></div></body></html>`)
+	return b.String()
+}
+
+func (f *dnsWebUI) editPage(e *DNSHostEntry) string {
+	var b strings.Builder
+	b.WriteString(`<html><head><script>$.ajaxSetup({ beforeSend: function(xhr) { xhr.setRequestHeader("X-CSRFToken", "token" ); } });</script></head><body>`)
+	b.WriteString(`<div class="content-box"><form method="post"><input type="hidden" name="csrf" value="token"/>`)
+	disabled := ""
+	if e.Disabled {
+		disabled = ` checked="checked"`
+	}
+	fmt.Fprintf(&b, `<input type="checkbox" name="disabled" value="yes"%s/>`, disabled)
+	if f.dnsmasq {
+		for name, value := range map[string]string{"host": e.Host, "domain": e.Domain, "ip": e.IP, "descr": e.Description} {
+			fmt.Fprintf(&b, `<input type="text" name="%s" value="%s"/>`, name, html.EscapeString(value))
+		}
+		b.WriteString(`<input type="submit" name="Submit" value="Save"/></form></div></body></html>`)
+		return b.String()
+	}
+	b.WriteString(`<select name="rr">`)
+	for _, rr := range []string{"A", "AAAA", "CNAME", "MX", "TXT"} {
+		selected := ""
+		if rr == e.Type {
+			selected = ` selected="selected"`
+		}
+		fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, rr, selected, rr)
+	}
+	b.WriteString(`</select>`)
+	values := map[string]string{
+		"host":   e.Host,
+		"domain": e.Domain,
+		"descr":  e.Description,
+	}
+	switch e.Type {
+	case "MX":
+		values["mx"] = e.IP
+		values["mxprio"] = strconv.Itoa(e.MXPriority)
+	case "TXT":
+		values["txtdata"] = EncodeTXT(e.IP)
+	default:
+		values["ip"] = e.IP
+	}
+	if e.TTL != 0 {
+		values["ttl"] = strconv.Itoa(e.TTL)
+	}
+	for _, name := range []string{"host", "domain", "ip", "mx", "mxprio", "txtdata", "ttl", "descr"} {
+		fmt.Fprintf(&b, `<input type="text" name="%s" value="%s"/>`, name, html.EscapeString(values[name]))
+	}
+	if len(f.views) > 0 {
+		b.WriteString(`<select name="view"><option value="">All clients</option>`)
+		for _, v := range f.views {
+			selected := ""
+			if v == e.View {
+				selected = ` selected="selected"`
+			}
+			fmt.Fprintf(&b, `<option value="%s"%s>%s</option>`, v, selected, v)
+		}
+		b.WriteString(`</select>`)
+	}
+	b.WriteString(`<input type="submit" name="Submit" value="Save"/></form></div></body></html>`)
+	return b.String()
+}
+
+func (f *dnsWebUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	serviceURI, editURI := DNSServiceURI, DNSServiceEditURI
+	if f.dnsmasq {
+		serviceURI, editURI = DNSDnsmasqServiceURI, DNSDnsmasqServiceEditURI
+	}
+
+	r.ParseForm()
+	switch {
+	case r.URL.Path == serviceURI && r.Method == http.MethodGet:
+		fmt.Fprint(w, f.servicePage())
+	case r.URL.Path == serviceURI:
+		if r.Form.Get("act") == "del" {
+			if i := f.find(r.Form.Get("id")); i != -1 {
+				f.entries = append(f.entries[:i], f.entries[i+1:]...)
+				f.pending = true
+				if f.positional {
+					for j := range f.entries {
+						f.entries[j].ID = j
+					}
+					f.nextID = len(f.entries)
+				}
+			}
+		}
+		if r.Form.Get("apply") != "" {
+			f.applies++
+			f.pending = false
+		}
+		fmt.Fprint(w, f.servicePage())
+	case r.URL.Path == editURI && r.Method == http.MethodGet:
+		e := DNSHostEntry{}
+		if i := f.find(r.Form.Get("id")); i != -1 {
+			e = f.entries[i]
+		}
+		fmt.Fprint(w, f.editPage(&e))
+	case r.URL.Path == editURI:
+		e := DNSHostEntry{
+			Type:        r.Form.Get("rr"),
+			Host:        r.Form.Get("host"),
+			Domain:      r.Form.Get("domain"),
+			IP:          r.Form.Get("ip"),
+			Description: r.Form.Get("descr"),
+			Disabled:    r.Form.Get("disabled") == "yes",
+			View:        r.Form.Get("view"),
+		}
+		e.TTL, _ = strconv.Atoi(r.Form.Get("ttl"))
+		if f.dnsmasq {
+			e.Type = dnsmasqType(e.IP)
+		}
+		switch e.Type {
+		case "MX":
+			e.IP = r.Form.Get("mx")
+			e.MXPriority, _ = strconv.Atoi(r.Form.Get("mxprio"))
+		case "TXT":
+			e.IP = DecodeTXT(r.Form.Get("txtdata"))
+		}
+		if i := f.find(r.Form.Get("id")); i != -1 {
+			e.ID = f.entries[i].ID
+			f.entries[i] = e
+		} else {
+			e.ID = f.nextID
+			f.nextID++
+			f.entries = append(f.entries, e)
+		}
+		f.saves++
+		f.pending = true
+		fmt.Fprint(w, f.servicePage())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestNamedHostOverrideSurvivesIPChange(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "api", Domain: "acme.local", IP: "192.168.0.2", Description: DNSNamedDescription("api-frontend")},
+		DNSHostEntry{Type: "A", Host: "api", Domain: "acme.local", IP: "192.168.0.3"},
+	)
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// the address changed out of band: the entry is still found through its name
+	f.entries[0].IP = "192.168.0.4"
+	h := DNSHostEntry{Type: "A", Host: "api", Domain: "acme.local", IP: "192.168.0.2", Description: DNSNamedDescription("api-frontend")}
+	err := dns.ReadHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != 0 || h.IP != "192.168.0.4" {
+		t.Errorf("read entry %d with IP %s, expected entry 0 with its live IP", h.ID, h.IP)
+	}
+
+	// and edited in place, whatever the ID it's been known with
+	h = DNSHostEntry{ID: 1, Type: "A", Host: "api", Domain: "acme.local", IP: "192.168.0.5", Description: DNSNamedDescription("api-frontend")}
+	err = dns.UpdateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.entries) != 2 || f.entries[0].IP != "192.168.0.5" || f.entries[1].IP != "192.168.0.3" {
+		t.Errorf("unexpected entries after update: %+v", f.entries)
+	}
+	if f.applies != 1 {
+		t.Errorf("changes applied %d times, expected once", f.applies)
+	}
+}
+
+func TestHostOverrideCommentsAreNotNames(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1", Description: "managed by hand"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.2", Description: "managed by hand"},
+	)
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// descriptions the provider didn't write don't identify entries, their values do
+	h := DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1", Description: "managed by hand"}
+	err := dns.ReadHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != 0 || DNSEntryName(&h) != "" {
+		t.Errorf("read entry %d named %q, expected unnamed entry 0", h.ID, DNSEntryName(&h))
+	}
+
+	// and are kept when editing the entry
+	h = DNSHostEntry{ID: 0, Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.3"}
+	err = dns.UpdateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.entries[0].IP != "192.168.0.3" || f.entries[0].Description != "managed by hand" {
+		t.Errorf("unexpected entry after update: %+v", f.entries[0])
+	}
+}
+
+func TestWaitAppliedUnresolvableType(t *testing.T) {
+	statuses := []string{"stopped", "running"}
+	polls := 0
+	dns := DNSSession{
+		OPN: newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != DNSServiceStatusURI {
+				t.Errorf("unexpected request %s", r.URL)
+				http.NotFound(w, r)
+				return
+			}
+			writeJSON(w, APIResult{Status: statuses[polls]})
+			polls++
+		})),
+		ApplyTimeout: 5 * time.Second,
+	}
+
+	// records which can't be resolved only wait for the DNS service to run again
+	err := dns.WaitApplied(&DNSHostEntry{Type: "SRV", Host: "_sip._tcp", Domain: "acme.local", IP: "10 5060 sip.acme.local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if polls != 2 {
+		t.Errorf("DNS service status polled %d times, expected until running", polls)
+	}
+
+	// and don't wait at all when its status is unavailable
+	unavailable := DNSSession{
+		OPN: newTestSession(t, http.NotFoundHandler()),
+	}
+	start := time.Now()
+	err = unavailable.WaitApplied(&DNSHostEntry{Type: "SRV", Host: "_sip._tcp", Domain: "acme.local", IP: "10 5060 sip.acme.local"})
+	if err != nil || time.Since(start) > DNSApplyPollInterval {
+		t.Errorf("waited %v for an unavailable DNS service status, got error %v", time.Since(start), err)
+	}
+}
+
+func TestDNSResolvable(t *testing.T) {
+	for rr, expected := range map[string]bool{
+		"A":     true,
+		"AAAA":  true,
+		"CNAME": true,
+		"MX":    true,
+		"TXT":   true,
+		"SRV":   false,
+		"PTR":   false,
+	} {
+		if dnsResolvable(rr) != expected {
+			t.Errorf("%s records resolvable is %v, expected %v", rr, !expected, expected)
+		}
+	}
+}
+
+func TestHostsMatchNormalizesDomains(t *testing.T) {
+	dns := DNSSession{}
+	e1 := DNSHostEntry{Type: "A", Host: "www", Domain: "Example.COM.", IP: "192.168.0.1"}
+	e2 := DNSHostEntry{Type: "A", Host: "www", Domain: "example.com", IP: "192.168.0.1"}
+	if NormalizeDomain(e1.Domain) != "example.com" {
+		t.Errorf("%q normalized as %q", e1.Domain, NormalizeDomain(e1.Domain))
+	}
+	if !dns.HostsMatch(&e1, &e2) || !dns.HostsMatch(&e2, &e1) {
+		t.Error("entries of the same domain don't match")
+	}
+	e2.Domain = "example.org"
+	if dns.HostsMatch(&e1, &e2) {
+		t.Error("entries of different domains match")
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	tests := map[string]string{
+		"192.168.0.10":       "10.0.168.192.in-addr.arpa",
+		" 10.0.0.1 ":         "1.0.0.10.in-addr.arpa",
+		"2001:db8::567:89ab": "b.a.9.8.7.6.5.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+		"::1":                "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa",
+	}
+	for ip, expected := range tests {
+		ptr, err := ReverseName(ip)
+		if err != nil {
+			t.Errorf("%s: %v", ip, err)
+			continue
+		}
+		if ptr != expected {
+			t.Errorf("%s reversed as %s, expected %s", ip, ptr, expected)
+		}
+	}
+
+	_, err := ReverseName("www.acme.local")
+	if err == nil {
+		t.Error("host name has been reversed")
+	}
+}
+
+func TestHostOverridesKeepTheirIDs(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+		DNSHostEntry{Type: "A", Host: "mail", Domain: "acme.local", IP: "192.168.0.2"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"},
+	)
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// entries are no longer numbered by their table position once one is removed
+	err := dns.DeleteHostOverride(&DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"}
+	err = dns.ReadHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != 2 {
+		t.Errorf("read entry with ID %d, expected 2", h.ID)
+	}
+
+	// so that updates edit the intended entry
+	h.IP = "192.168.0.4"
+	err = dns.UpdateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.entries[0].IP != "192.168.0.2" || f.entries[1].IP != "192.168.0.4" {
+		t.Errorf("unexpected entries after update: %+v", f.entries)
+	}
+}
+
+func TestUpdateRoundRobinRenumbering(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.1"},
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.10"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.2"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"},
+	)
+	f.positional = true
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// removing an entry shifts the IDs of the following ones
+	h := DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local"}
+	err := dns.UpdateRoundRobin(&h, []string{"192.168.0.4"}, []string{"192.168.0.1", "192.168.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, e := range f.entries {
+		keys = append(keys, e.Key())
+	}
+	expected := []string{"A/www/acme.local/192.168.0.10", "A/ftp/acme.local/192.168.0.2", "A/ftp/acme.local/192.168.0.4"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("got entries %q, expected %q", keys, expected)
+	}
+	if f.applies != 1 {
+		t.Errorf("got %d applies, expected 1", f.applies)
+	}
+}
+
+func TestMXHostOverride(t *testing.T) {
+	f := newDNSWebUI()
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// target host and priority have their own fields
+	h := DNSHostEntry{Type: "MX", Host: "", Domain: "acme.local", IP: "mx1.acme.local", MXPriority: 20}
+	err := dns.CreateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.entries) != 1 || f.entries[0].IP != "mx1.acme.local" || f.entries[0].MXPriority != 20 {
+		t.Fatalf("unexpected entries %+v", f.entries)
+	}
+
+	// and are read back out of the "<priority> <host>" table value
+	h = DNSHostEntry{Type: "MX", Host: "", Domain: "acme.local", IP: "mx1.acme.local"}
+	err = dns.ReadHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.IP != "mx1.acme.local" || h.MXPriority != 20 {
+		t.Errorf("read MX %d %s, expected 20 mx1.acme.local", h.MXPriority, h.IP)
+	}
+}
+
+func TestDiffAgainst(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+		DNSHostEntry{Type: "A", Host: "mail", Domain: "acme.local", IP: "192.168.0.2"},
+		DNSHostEntry{Type: "A", Host: "api", Domain: "acme.local", IP: "192.168.0.3", Description: DNSNamedDescription("api-frontend")},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.4"},
+	)
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	toCreate, toUpdate, toDelete, err := dns.DiffAgainst([]DNSHostEntry{
+		{Type: "A", Host: "www", Domain: "Acme.Local.", IP: "192.168.0.1"},
+		{Type: "A", Host: "mail", Domain: "acme.local", IP: "192.168.0.12"},
+		{Type: "A", Host: "gateway", Domain: "acme.local", IP: "192.168.0.13", Description: DNSNamedDescription("api-frontend")},
+		{Type: "AAAA", Host: "www", Domain: "acme.local", IP: "2001:db8::1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := func(entries []DNSHostEntry) []string {
+		res := []string{}
+		for _, e := range entries {
+			res = append(res, fmt.Sprintf("%d:%s", e.ID, e.Key()))
+		}
+		return res
+	}
+	if expected := []string{"-1:AAAA/www/acme.local/2001:db8::1"}; !reflect.DeepEqual(keys(toCreate), expected) {
+		t.Errorf("got entries to create %q, expected %q", keys(toCreate), expected)
+	}
+	if expected := []string{"1:A/mail/acme.local/192.168.0.12", "2:A/gateway/acme.local/192.168.0.13"}; !reflect.DeepEqual(keys(toUpdate), expected) {
+		t.Errorf("got entries to update %q, expected %q", keys(toUpdate), expected)
+	}
+	if expected := []string{"3:A/ftp/acme.local/192.168.0.4"}; !reflect.DeepEqual(keys(toDelete), expected) {
+		t.Errorf("got entries to delete %q, expected %q", keys(toDelete), expected)
+	}
+	if f.saves != 0 {
+		t.Errorf("%d entries have been saved while diffing", f.saves)
+	}
+}
+
+func TestAmbiguousHostOverrides(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1", Description: DNSNamedDescription("frontend")},
+		DNSHostEntry{Type: "A", Host: "web", Domain: "acme.local", IP: "192.168.0.2", Description: DNSNamedDescription("frontend")},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"},
+	)
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// several entries share the same name, none of them is picked
+	expected := ErrDNSAmbiguousEntry + " (entries 0, 1)"
+	h := DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1", Description: DNSNamedDescription("frontend")}
+	err := dns.ReadHostOverride(&h)
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected read error %v", err)
+	}
+	err = dns.UpdateHostOverride(&h)
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected update error %v", err)
+	}
+	err = dns.DeleteHostOverride(&h)
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected delete error %v", err)
+	}
+	err = dns.CreateHostOverride(&h)
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected create error %v", err)
+	}
+	if len(f.entries) != 3 || f.saves != 0 {
+		t.Errorf("entries have been changed: %+v", f.entries)
+	}
+
+	// same goes for entries alike
+	f.entries = append(f.entries, DNSHostEntry{ID: 7, Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"})
+	h = DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"}
+	err = dns.DeleteHostOverride(&h)
+	if err == nil || err.Error() != ErrDNSAmbiguousEntry+" (entries 2, 7)" {
+		t.Errorf("unexpected delete error %v", err)
+	}
+	if len(f.entries) != 4 {
+		t.Errorf("entries have been removed: %+v", f.entries)
+	}
+}
+
+func TestWildcardHostOverride(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+	)
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	h := DNSHostEntry{Type: "A", Host: DNSWildcardHost, Domain: "acme.local", IP: "192.168.0.2"}
+	err := dns.CreateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.entries) != 2 || f.entries[1].Host != "*" {
+		t.Fatalf("unexpected entries %+v", f.entries)
+	}
+
+	// the wildcard is an entry of its own, apart from other hosts of the domain
+	h = DNSHostEntry{Type: "A", Host: DNSWildcardHost, Domain: "acme.local", IP: "192.168.0.2"}
+	err = dns.ReadHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != 1 {
+		t.Errorf("read wildcard entry %d, expected 1", h.ID)
+	}
+	entries, err := dns.FindHostEntries(&DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != 0 {
+		t.Errorf("wildcard has been taken for www: %+v", entries)
+	}
+}
+
+func TestDnsmasqHostOverrides(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+	)
+	f.dnsmasq = true
+	dns := DNSSession{
+		OPN:     newTestSession(t, f),
+		Backend: DNSBackendDnsmasq,
+	}
+
+	// record types are implied by addresses
+	h := DNSHostEntry{Type: "AAAA", Host: "www", Domain: "acme.local", IP: "2001:db8::1"}
+	err := dns.CreateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := dns.GetAllHostEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{}
+	for _, e := range entries {
+		keys = append(keys, e.Key())
+	}
+	expected := []string{"A/www/acme.local/192.168.0.1", "AAAA/www/acme.local/2001:db8::1"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("got entries %q, expected %q", keys, expected)
+	}
+
+	err = dns.DeleteHostOverride(&DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.entries) != 1 || f.entries[0].ID != 1 || f.applies != 2 {
+		t.Errorf("unexpected entries %+v after %d applies", f.entries, f.applies)
+	}
+
+	// nothing but addresses can be mapped
+	err = dns.CreateHostOverride(&DNSHostEntry{Type: "CNAME", Host: "web", Domain: "acme.local", IP: "www.acme.local"})
+	if err == nil || err.Error() != ErrDNSTypeUnsupported {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// dkimKey is a DKIM public key record, longer than a single TXT character-string
+const dkimKey = `v=DKIM1; k=rsa; p=MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAu5oIUrFDWZK7F4thFxpZa2or6jBEX3cETxY5ZiMOAVlJ1V8hJ5Lh3qOOvDk0ESHfYEKkkNW4wJvHmFVbXRSb` +
+	`iG7WZN1Xc+b1HpQqE8mxgsSOWp9bRtlYvT+Nv2Kp2Pxa1+GUpPGlVnmNYmGQ9MeGkHALGkN+Crf0Ir5Mv1JVvz3JVoqdBGZ/6hXG8aHw3mnAPBlPR1ErYZ+Ek4OXfAxNc9Ukg+dk` +
+	`dOz+uGpdMsfC5Bq8NRiBeT3/5mcyvBTD7GZJS4vBW9VPRxxaUO2V47iFkPHqJbUaGRRdhRhVyDk2gXNfcMxU4mD5nIc1fxIQ8TxFQDKbYUL9hNNJrCr1oZ0eGQIDAQAB; n="a \"quoted\" note"`
+
+func TestTXTEncoding(t *testing.T) {
+	// long values are split into several strings, without exceeding their maximum length
+	data := EncodeTXT(strings.Repeat("a", 2*DNSTXTChunkSize+10))
+	expected := fmt.Sprintf(`"%s" "%s" "%s"`, strings.Repeat("a", DNSTXTChunkSize), strings.Repeat("a", DNSTXTChunkSize), strings.Repeat("a", 10))
+	if data != expected {
+		t.Errorf("got %s, expected %s", data, expected)
+	}
+
+	// nor breaking UTF-8 sequences
+	data = EncodeTXT(strings.Repeat("é", DNSTXTChunkSize))
+	for _, s := range strings.Split(data, " ") {
+		if !utf8.ValidString(s) || len(s) > DNSTXTChunkSize+2 {
+			t.Errorf("invalid character-string %s", s)
+		}
+	}
+
+	// quotes and backslashes are escaped
+	if data := EncodeTXT(`a "quoted" \ note`); data != `"a \"quoted\" \\ note"` {
+		t.Errorf("got %s", data)
+	}
+
+	for _, value := range []string{dkimKey, `a "quoted" \\ note`, "", "v=spf1  -all"} {
+		if res := DecodeTXT(EncodeTXT(value)); res != value {
+			t.Errorf("got back %q, expected %q", res, value)
+		}
+	}
+
+	// unquoted data is kept as is
+	if res := DecodeTXT(" v=spf1 -all "); res != "v=spf1 -all" {
+		t.Errorf("got %q", res)
+	}
+}
+
+func TestTXTHostOverrideRoundTrip(t *testing.T) {
+	f := newDNSWebUI()
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// the overrides table collapses whitespaces, which the edit page keeps
+	value := "v=DMARC1;  p=reject;\trua=mailto:dmarc@acme.local"
+	h := DNSHostEntry{Type: "TXT", Host: "_dmarc", Domain: "acme.local", IP: value}
+	err := dns.CreateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = DNSHostEntry{Type: "TXT", Host: "_dmarc", Domain: "acme.local", IP: value}
+	err = dns.ReadHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dns.ReadDetails(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.IP != value {
+		t.Errorf("read back %q, expected %q", h.IP, value)
+	}
+
+	h = DNSHostEntry{Type: "TXT", Host: "selector._domainkey", Domain: "acme.local", IP: dkimKey}
+	err = dns.CreateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h = DNSHostEntry{Type: "TXT", Host: "selector._domainkey", Domain: "acme.local", IP: dkimKey}
+	err = dns.ReadHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dns.ReadDetails(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.IP != dkimKey {
+		t.Errorf("read back %q, expected %q", h.IP, dkimKey)
+	}
+}
+
+func TestCreateOrAdoptHostOverride(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1", Description: "manual"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.4"},
+	)
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// the existing entry is overwritten with the new values, whatever its value
+	h := DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.2", Description: "frontend"}
+	err := dns.CreateOrAdoptHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != 0 {
+		t.Errorf("got ID %d, expected the adopted entry one", h.ID)
+	}
+	if len(f.entries) != 3 || f.entries[0].IP != "192.168.0.2" || f.entries[0].Description != "frontend" {
+		t.Errorf("entry hasn't been adopted: %+v", f.entries)
+	}
+
+	// missing entries are created
+	h = DNSHostEntry{Type: "A", Host: "mail", Domain: "acme.local", IP: "192.168.0.5"}
+	err = dns.CreateOrAdoptHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.entries) != 4 || f.entries[3].Host != "mail" {
+		t.Errorf("entry hasn't been created: %+v", f.entries)
+	}
+
+	// round-robin records can't be told apart
+	saves := f.saves
+	h = DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.6"}
+	err = dns.CreateOrAdoptHostOverride(&h)
+	if err == nil || err.Error() != ErrDNSAmbiguousEntry+" (entries 1, 2)" {
+		t.Errorf("unexpected create error %v", err)
+	}
+	if f.saves != saves {
+		t.Errorf("entries have been changed: %+v", f.entries)
+	}
+}
+
+func TestSyncHostOverridesRenumbering(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+		DNSHostEntry{Type: "A", Host: "web", Domain: "acme.local", IP: "192.168.0.2"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"},
+		DNSHostEntry{Type: "A", Host: "mail", Domain: "acme.local", IP: "192.168.0.4"},
+	)
+	f.positional = true
+	dns := DNSSession{
+		OPN: newTestSession(t, f),
+	}
+
+	// removing an entry shifts the IDs of the following ones
+	current := append([]DNSHostEntry{}, f.entries...)
+	desired := []DNSHostEntry{f.entries[1], f.entries[3]}
+	failures, err := dns.SyncHostOverrides(current, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("unexpected failures %v", failures)
+	}
+	hosts := []string{}
+	for _, e := range f.entries {
+		hosts = append(hosts, e.Host)
+	}
+	if !reflect.DeepEqual(hosts, []string{"web", "mail"}) {
+		t.Errorf("got hosts %v, expected [web mail]", hosts)
+	}
+}
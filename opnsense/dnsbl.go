@@ -0,0 +1,129 @@
+package opnsense
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// UnboundAPI is the Unbound DNS MVC API root
+	UnboundAPI = "/api/unbound"
+)
+
+// DNSBL abstracts Unbound DNS blocklist settings
+type DNSBL struct {
+	Enabled    bool
+	Types      []string
+	Lists      []string
+	Whitelists []string
+	Blocklists []string
+}
+
+type apiDNSBL struct {
+	Enabled    string `json:"enabled"`
+	Type       string `json:"type"`
+	Lists      string `json:"lists"`
+	Whitelists string `json:"whitelists"`
+	Blocklists string `json:"blocklists"`
+}
+
+type apiDNSBLRead struct {
+	Enabled    string               `json:"enabled"`
+	Type       map[string]APIOption `json:"type"`
+	Lists      map[string]APIOption `json:"lists"`
+	Whitelists map[string]APIOption `json:"whitelists"`
+	Blocklists map[string]APIOption `json:"blocklists"`
+}
+
+type apiUnboundSettings struct {
+	Unbound struct {
+		DNSBL apiDNSBLRead `json:"dnsbl"`
+	} `json:"unbound"`
+}
+
+func (b *DNSBL) toAPI() map[string]map[string]apiDNSBL {
+	enabled := "0"
+	if b.Enabled {
+		enabled = "1"
+	}
+	return map[string]map[string]apiDNSBL{
+		"unbound": {
+			"dnsbl": {
+				Enabled:    enabled,
+				Type:       strings.Join(b.Types, ","),
+				Lists:      strings.Join(b.Lists, ","),
+				Whitelists: strings.Join(b.Whitelists, ","),
+				Blocklists: strings.Join(b.Blocklists, ","),
+			},
+		},
+	}
+}
+
+// ApplyDNSBL refreshes blocklists and reloads DNS server
+func (s *DNSSession) ApplyDNSBL() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/service/dnsbl", UnboundAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	res = APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/service/reconfigure", UnboundAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// ReadDNSBL retrieves Unbound DNS blocklist settings
+func (s *DNSSession) ReadDNSBL() (*DNSBL, error) {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return nil, err
+	}
+
+	res := apiUnboundSettings{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/get", UnboundAPI), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	r := res.Unbound.DNSBL
+	b := DNSBL{
+		Enabled:    r.Enabled == "1",
+		Types:      SelectedOptions(r.Type),
+		Lists:      SelectedOptions(r.Lists),
+		Whitelists: SelectedOptions(r.Whitelists),
+		Blocklists: SelectedOptions(r.Blocklists),
+	}
+
+	return &b, nil
+}
+
+// UpdateDNSBL modifies Unbound DNS blocklist settings
+func (s *DNSSession) UpdateDNSBL(b *DNSBL) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/settings/set", UnboundAPI), b.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	return s.ApplyDNSBL()
+}
@@ -0,0 +1,34 @@
+package fake_test
+
+import (
+	"fmt"
+
+	"github.com/gxben/terraform-provider-opnsense/opnsense"
+	"github.com/gxben/terraform-provider-opnsense/opnsense/fake"
+)
+
+func ExampleDNS() {
+	dns := fake.NewDNS(
+		opnsense.DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+		opnsense.DNSHostEntry{Type: "A", Host: "mail", Domain: "acme.local", IP: "192.168.0.2"},
+	)
+
+	// entries keep their ID when others are removed
+	_ = dns.DeleteHostOverride(&opnsense.DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"})
+	h := opnsense.DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.2"}
+	_ = dns.CreateHostOverride(&h)
+	entries, _ := dns.GetAllHostEntries()
+	for _, e := range entries {
+		fmt.Println(e.ID, e.Host)
+	}
+
+	// lookups matching several entries fail as a real session would
+	dup := opnsense.DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.2"}
+	dns.Entries = append(dns.Entries, opnsense.DNSHostEntry{ID: 7, Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.2"})
+	fmt.Println(dns.ReadHostOverride(&dup))
+
+	// Output:
+	// 1 mail
+	// 2 ftp
+	// several host override entries match, refusing to pick one (entries 2, 7)
+}
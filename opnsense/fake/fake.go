@@ -0,0 +1,265 @@
+// Package fake provides in-memory implementations of the opnsense package
+// session interfaces, mimicking OPNSense behavior, for use in consumers tests.
+package fake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gxben/terraform-provider-opnsense/opnsense"
+)
+
+// DHCP is an in-memory opnsense.DHCPManager
+type DHCP struct {
+	mutex    sync.Mutex
+	nextID   int
+	Mappings []opnsense.StaticMapping
+}
+
+// DNS is an in-memory opnsense.DNSManager
+type DNS struct {
+	mutex   sync.Mutex
+	nextID  int
+	Entries []opnsense.DNSHostEntry
+}
+
+var (
+	_ opnsense.DHCPManager = (*DHCP)(nil)
+	_ opnsense.DNSManager  = (*DNS)(nil)
+)
+
+// NewDHCP creates an in-memory DHCP session holding the given static mappings,
+// numbered in order as OPNSense does with its internal IDs
+func NewDHCP(mappings ...opnsense.StaticMapping) *DHCP {
+	for i := range mappings {
+		mappings[i].ID = i
+	}
+	return &DHCP{
+		nextID:   len(mappings),
+		Mappings: mappings,
+	}
+}
+
+// NewDNS creates an in-memory DNS session holding the given host overrides,
+// numbered in order as OPNSense does with its internal IDs
+func NewDNS(entries ...opnsense.DNSHostEntry) *DNS {
+	for i := range entries {
+		entries[i].ID = i
+	}
+	return &DNS{
+		nextID:  len(entries),
+		Entries: entries,
+	}
+}
+
+// find returns the position of a mapping for the specific Interface/MAC couple,
+// matching any of the MAC addresses as OPNSense DHCPSession.FindMappingByMAC does
+func (s *DHCP) find(m *opnsense.StaticMapping) int {
+	for i, e := range s.Mappings {
+		if e.Interface != m.Interface {
+			continue
+		}
+		if e.HasMAC(m.MAC) {
+			return i
+		}
+		for _, mac := range m.MACs {
+			if e.HasMAC(mac) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// GetAllInterfaceStaticMappings returns the static mappings of an interface
+func (s *DHCP) GetAllInterfaceStaticMappings(iface string) ([]opnsense.StaticMapping, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	mappings := []opnsense.StaticMapping{}
+	for _, e := range s.Mappings {
+		if e.Interface == iface {
+			mappings = append(mappings, e)
+		}
+	}
+	return mappings, nil
+}
+
+// CreateStaticMapping adds a new static mapping
+func (s *DHCP) CreateStaticMapping(m *opnsense.StaticMapping) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if i := s.find(m); i != -1 {
+		if !s.Mappings[i].SameAs(m) {
+			return fmt.Errorf(opnsense.ErrMACExists)
+		}
+		m.ID = s.Mappings[i].ID
+		return nil
+	}
+	m.ID = s.nextID
+	s.nextID++
+	s.Mappings = append(s.Mappings, *m)
+	return nil
+}
+
+// ReadStaticMapping retrieves a static mapping for a specified Interface/MAC couple
+func (s *DHCP) ReadStaticMapping(m *opnsense.StaticMapping) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.find(m)
+	if i == -1 {
+		return fmt.Errorf(opnsense.ErrNoSuchMAC)
+	}
+	*m = s.Mappings[i]
+	return nil
+}
+
+// UpdateStaticMapping modifies an already existing static mapping
+func (s *DHCP) UpdateStaticMapping(m *opnsense.StaticMapping) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.find(m)
+	if i == -1 {
+		return fmt.Errorf(opnsense.ErrNoSuchMAC)
+	}
+	m.ID = s.Mappings[i].ID
+	s.Mappings[i] = *m
+	return nil
+}
+
+// DeleteStaticMapping destroys an existing static mapping
+func (s *DHCP) DeleteStaticMapping(m *opnsense.StaticMapping) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.find(m)
+	if i == -1 && m.IP != "" {
+		// fall back to the IP address, as OPNSense DHCPSession does
+		for j, e := range s.Mappings {
+			if e.Interface == m.Interface && e.IP == m.IP {
+				i = j
+				break
+			}
+		}
+	}
+	if i == -1 {
+		return fmt.Errorf(opnsense.ErrNoSuchMAC)
+	}
+	s.Mappings = append(s.Mappings[:i], s.Mappings[i+1:]...)
+	return nil
+}
+
+// find returns the position of the only entry alike the given one, as OPNSense DNSSession.FindHostEntry
+// does, failing the same way when none or several of them match
+func (s *DNS) find(h *opnsense.DNSHostEntry) (int, error) {
+	dns := opnsense.DNSSession{}
+	matches := []int{}
+	for i := range s.Entries {
+		if dns.HostsMatch(h, &s.Entries[i]) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf(opnsense.ErrDNSNoSuchEntry)
+	case 1:
+		return matches[0], nil
+	}
+
+	ids := []string{}
+	for _, i := range matches {
+		ids = append(ids, strconv.Itoa(s.Entries[i].ID))
+	}
+	return -1, fmt.Errorf("%s (entries %s)", opnsense.ErrDNSAmbiguousEntry, strings.Join(ids, ", "))
+}
+
+// GetAllHostEntries returns all host overrides
+func (s *DNS) GetAllHostEntries() ([]opnsense.DNSHostEntry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries := make([]opnsense.DNSHostEntry, len(s.Entries))
+	copy(entries, s.Entries)
+	return entries, nil
+}
+
+// CreateHostOverride adds a new host override, given the next free ID
+func (s *DNS) CreateHostOverride(h *opnsense.DNSHostEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i, err := s.find(h)
+	if i != -1 {
+		return fmt.Errorf(opnsense.ErrDNSHostExists)
+	}
+	if err.Error() != opnsense.ErrDNSNoSuchEntry {
+		return err
+	}
+	h.ID = s.nextID
+	s.nextID++
+	s.Entries = append(s.Entries, *h)
+	return nil
+}
+
+// ReadHostOverride retrieves a host override alike the given one
+func (s *DNS) ReadHostOverride(h *opnsense.DNSHostEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i, err := s.find(h)
+	if i == -1 {
+		return err
+	}
+	*h = s.Entries[i]
+	return nil
+}
+
+// UpdateHostOverride modifies an already existing host override, identified by its name or ID
+func (s *DNS) UpdateHostOverride(h *opnsense.DNSHostEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := -1
+	if opnsense.DNSEntryName(h) != "" {
+		var err error
+		i, err = s.find(&opnsense.DNSHostEntry{Description: h.Description})
+		if i == -1 {
+			return err
+		}
+	} else {
+		for j, e := range s.Entries {
+			if e.ID == h.ID {
+				i = j
+				break
+			}
+		}
+		if i == -1 {
+			return fmt.Errorf(opnsense.ErrDNSNoSuchEntry)
+		}
+	}
+	h.ID = s.Entries[i].ID
+	if h.Description == "" && opnsense.DNSEntryName(&s.Entries[i]) == "" {
+		h.Description = s.Entries[i].Description
+	}
+	s.Entries[i] = *h
+	return nil
+}
+
+// DeleteHostOverride destroys an existing host override, other entries keeping their ID
+func (s *DNS) DeleteHostOverride(h *opnsense.DNSHostEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i, err := s.find(h)
+	if i == -1 {
+		return err
+	}
+	s.Entries = append(s.Entries[:i], s.Entries[i+1:]...)
+	return nil
+}
@@ -0,0 +1,111 @@
+package fake_test
+
+import (
+	"testing"
+
+	"github.com/gxben/terraform-provider-opnsense/opnsense"
+	"github.com/gxben/terraform-provider-opnsense/opnsense/fake"
+)
+
+func TestDHCPCreateStaticMapping(t *testing.T) {
+	dhcp := fake.NewDHCP(
+		opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"},
+		opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:02", IP: "192.168.1.11", Hostname: "nas"},
+	)
+
+	// creating the very same mapping again is a no-op, whatever the MAC address case
+	m := opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:0A", IP: "192.168.1.12", Hostname: "camera"}
+	for i := 0; i < 2; i++ {
+		err := dhcp.CreateStaticMapping(&m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.MAC = "00:11:22:33:44:0a"
+	}
+	if len(dhcp.Mappings) != 3 || m.ID != 2 {
+		t.Errorf("got %d mappings, new one with ID %d", len(dhcp.Mappings), m.ID)
+	}
+
+	// a MAC address is only mapped once per interface
+	err := dhcp.CreateStaticMapping(&opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.20"})
+	if err == nil || err.Error() != opnsense.ErrMACExists {
+		t.Errorf("unexpected error %v", err)
+	}
+	err = dhcp.CreateStaticMapping(&opnsense.StaticMapping{Interface: "opt1", MAC: "00:11:22:33:44:01", IP: "10.0.0.10"})
+	if err != nil {
+		t.Errorf("mapping on another interface has been rejected: %v", err)
+	}
+
+	// IDs aren't reused once mappings are removed
+	err = dhcp.DeleteStaticMapping(&opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:02"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:05", IP: "192.168.1.13"}
+	err = dhcp.CreateStaticMapping(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != 4 {
+		t.Errorf("new mapping has ID %d, expected 4", m.ID)
+	}
+}
+
+func TestDHCPDeleteStaticMappingByIP(t *testing.T) {
+	dhcp := fake.NewDHCP(
+		opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01", IP: "192.168.1.10"},
+	)
+
+	// the MAC address has been replaced out of band
+	err := dhcp.DeleteStaticMapping(&opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:99", IP: "192.168.1.10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dhcp.Mappings) != 0 {
+		t.Errorf("mapping has been kept: %+v", dhcp.Mappings)
+	}
+
+	err = dhcp.DeleteStaticMapping(&opnsense.StaticMapping{Interface: "lan", MAC: "00:11:22:33:44:01"})
+	if err == nil || err.Error() != opnsense.ErrNoSuchMAC {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDNSUpdateHostOverride(t *testing.T) {
+	dns := fake.NewDNS(
+		opnsense.DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+		opnsense.DNSHostEntry{Type: "A", Host: "api", Domain: "acme.local", IP: "192.168.0.2", Description: opnsense.DNSNamedDescription("api-frontend")},
+	)
+
+	// named entries are found through their name, whatever their ID
+	h := opnsense.DNSHostEntry{ID: 0, Type: "A", Host: "api", Domain: "acme.local", IP: "192.168.0.3", Description: opnsense.DNSNamedDescription("api-frontend")}
+	err := dns.UpdateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ID != 1 || dns.Entries[1].IP != "192.168.0.3" || dns.Entries[0].IP != "192.168.0.1" {
+		t.Errorf("unexpected entries after update: %+v", dns.Entries)
+	}
+
+	// others through their ID
+	h = opnsense.DNSHostEntry{ID: 0, Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.4"}
+	err = dns.UpdateHostOverride(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dns.Entries[0].IP != "192.168.0.4" {
+		t.Errorf("unexpected entries after update: %+v", dns.Entries)
+	}
+
+	h = opnsense.DNSHostEntry{ID: 5, Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.5"}
+	err = dns.UpdateHostOverride(&h)
+	if err == nil || err.Error() != opnsense.ErrDNSNoSuchEntry {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// creating an existing entry fails
+	err = dns.CreateHostOverride(&opnsense.DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.4"})
+	if err == nil || err.Error() != opnsense.ErrDNSHostExists {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+package opnsense
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FirewallRuleActions are the actions a firewall filter rule can take on matching traffic
+var FirewallRuleActions = []string{"pass", "block", "reject"}
+
+const (
+	// FirewallRuleMaxSequence is the highest position a firewall filter rule can be given
+	FirewallRuleMaxSequence = 999999
+)
+
+const (
+	// FirewallFilterAPI is the firewall filter MVC API root
+	FirewallFilterAPI = "/api/firewall/filter"
+)
+
+const (
+	// ErrNoSuchRule is thrown if no firewall rule matches the lookup criteria
+	ErrNoSuchRule = "no firewall rule matches the lookup criteria"
+	// ErrAmbiguousRule is thrown if several firewall rules match the lookup criteria
+	ErrAmbiguousRule = "several firewall rules match the lookup criteria"
+)
+
+// rxRuleInterfaceNetwork matches the interfaces networks and addresses a rule can
+// refer to (e.g. lan, opt1ip), which aren't aliases
+var rxRuleInterfaceNetwork = regexp.MustCompile(`^(lan|wan|opt[0-9]+)(ip)?$`)
+
+// FirewallSession abstracts OPNSense Firewall
+type FirewallSession struct {
+	OPN *OPNSession
+}
+
+// FirewallRule abstracts a firewall filter rule
+type FirewallRule struct {
+	UUID        string
+	Enabled     bool
+	Sequence    int
+	Description string
+	Interface   string
+	Action      string
+	Protocol    string
+	Source      string
+	Destination string
+}
+
+type apiFirewallRule struct {
+	UUID        string `json:"uuid"`
+	Enabled     string `json:"enabled"`
+	Sequence    string `json:"sequence"`
+	Description string `json:"description"`
+	Interface   string `json:"interface"`
+	Action      string `json:"action"`
+	Protocol    string `json:"protocol"`
+	Source      string `json:"source_net"`
+	Destination string `json:"destination_net"`
+}
+
+type apiFirewallRuleSearch struct {
+	Rows []apiFirewallRule `json:"rows"`
+}
+
+type apiFirewallRuleWrite struct {
+	Enabled     string `json:"enabled"`
+	Sequence    string `json:"sequence"`
+	Description string `json:"description"`
+	Interface   string `json:"interface"`
+	Action      string `json:"action"`
+	Protocol    string `json:"protocol"`
+	Source      string `json:"source_net"`
+	Destination string `json:"destination_net"`
+}
+
+type apiFirewallRuleRead struct {
+	Enabled     string               `json:"enabled"`
+	Sequence    string               `json:"sequence"`
+	Description string               `json:"description"`
+	Interface   map[string]APIOption `json:"interface"`
+	Action      map[string]APIOption `json:"action"`
+	Protocol    map[string]APIOption `json:"protocol"`
+	Source      string               `json:"source_net"`
+	Destination string               `json:"destination_net"`
+}
+
+func (r *FirewallRule) toAPI() map[string]apiFirewallRuleWrite {
+	enabled := "0"
+	if r.Enabled {
+		enabled = "1"
+	}
+	return map[string]apiFirewallRuleWrite{
+		"rule": {
+			Enabled:     enabled,
+			Sequence:    strconv.Itoa(r.Sequence),
+			Description: r.Description,
+			Interface:   r.Interface,
+			Action:      r.Action,
+			Protocol:    r.Protocol,
+			Source:      r.Source,
+			Destination: r.Destination,
+		},
+	}
+}
+
+// GetAllRules retrieves the list of all configured firewall filter rules
+func (s *FirewallSession) GetAllRules() ([]FirewallRule, error) {
+
+	rules := []FirewallRule{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return rules, err
+	}
+
+	query := map[string]interface{}{
+		"current":  1,
+		"rowCount": -1,
+	}
+	res := apiFirewallRuleSearch{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/searchRule", FirewallFilterAPI), query, &res)
+	if err != nil {
+		return rules, err
+	}
+
+	for _, r := range res.Rows {
+		rule := FirewallRule{
+			UUID:        r.UUID,
+			Enabled:     r.Enabled == "1",
+			Description: r.Description,
+			Interface:   r.Interface,
+			Action:      r.Action,
+			Protocol:    r.Protocol,
+			Source:      r.Source,
+			Destination: r.Destination,
+		}
+		rule.Sequence, _ = strconv.Atoi(r.Sequence)
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// RuleMatches checks whether a rule matches all non-empty criteria of a filter rule
+func (s *FirewallSession) RuleMatches(r, filter *FirewallRule) bool {
+	criteria := [][2]string{
+		{filter.Description, r.Description},
+		{filter.Interface, r.Interface},
+		{filter.Action, r.Action},
+		{filter.Protocol, r.Protocol},
+		{filter.Source, r.Source},
+		{filter.Destination, r.Destination},
+	}
+	for _, c := range criteria {
+		if c[0] != "" && c[0] != c[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// FindRule retrieves the single firewall rule matching all non-empty criteria of a filter rule
+func (s *FirewallSession) FindRule(filter *FirewallRule) (*FirewallRule, error) {
+
+	// retrieves existing rules
+	rules, err := s.GetAllRules()
+	if err != nil {
+		return nil, err
+	}
+
+	// check that exactly one rule matches
+	matches := []FirewallRule{}
+	for _, r := range rules {
+		if s.RuleMatches(&r, filter) {
+			matches = append(matches, r)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, s.OPN.Error(ErrNoSuchRule)
+	case 1:
+		return &matches[0], nil
+	}
+
+	return nil, fmt.Errorf("%s (%d found)", ErrAmbiguousRule, len(matches))
+}
+
+// ApplyRules reloads the firewall filter rules
+func (s *FirewallSession) ApplyRules() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/apply", FirewallFilterAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// nextRuleSequence returns the position right after the last firewall filter rule
+func (s *FirewallSession) nextRuleSequence() (int, error) {
+	rules, err := s.GetAllRules()
+	if err != nil {
+		return 0, err
+	}
+
+	last := 0
+	for _, r := range rules {
+		if r.Sequence > last {
+			last = r.Sequence
+		}
+	}
+	if last >= FirewallRuleMaxSequence {
+		return FirewallRuleMaxSequence, nil
+	}
+
+	return last + 1, nil
+}
+
+// ruleAliasRef returns the alias name a rule source or destination refers to, if any
+func ruleAliasRef(network string) string {
+	// addresses and networks can't be mistaken for alias names, which hold no dot, colon or slash
+	if network == "any" || rxRuleInterfaceNetwork.MatchString(network) || !rxAliasName.MatchString(network) {
+		return ""
+	}
+	return network
+}
+
+// ValidateRuleAliases makes sure the aliases a rule source and destination refer to exist,
+// as OPNSense would otherwise take a misspelled alias name as a literal
+func (s *FirewallSession) ValidateRuleAliases(r *FirewallRule) error {
+	for _, network := range []string{r.Source, r.Destination} {
+		name := ruleAliasRef(network)
+		if name == "" {
+			continue
+		}
+		_, err := s.FindAliasByName(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateRule creates a new firewall filter rule, at its requested position or
+// after all others whenever no sequence is set
+func (s *FirewallSession) CreateRule(r *FirewallRule) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	err = s.ValidateRuleAliases(r)
+	if err != nil {
+		return err
+	}
+
+	if r.Sequence == 0 {
+		r.Sequence, err = s.nextRuleSequence()
+		if err != nil {
+			return err
+		}
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/addRule", FirewallFilterAPI), r.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+	r.UUID = res.UUID
+
+	// apply changes
+	return s.ApplyRules()
+}
+
+// ReadRule retrieves firewall filter rule information for a specified UUID
+func (s *FirewallSession) ReadRule(r *FirewallRule) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]apiFirewallRuleRead{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/getRule/%s", FirewallFilterAPI, r.UUID), &res)
+	if err != nil {
+		return err
+	}
+	e, ok := res["rule"]
+	if !ok {
+		return fmt.Errorf("firewall rule %s doesn't exists", r.UUID)
+	}
+
+	// assign values accordingly
+	r.Enabled = e.Enabled == "1"
+	r.Sequence, _ = strconv.Atoi(e.Sequence)
+	r.Description = e.Description
+	r.Interface = strings.Join(SelectedOptions(e.Interface), ",")
+	r.Action = SelectedOption(e.Action)
+	r.Protocol = SelectedOption(e.Protocol)
+	r.Source = e.Source
+	r.Destination = e.Destination
+
+	return nil
+}
+
+// UpdateRule modifies an already existing firewall filter rule, moving it
+// to its requested position
+func (s *FirewallSession) UpdateRule(r *FirewallRule) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	err = s.ValidateRuleAliases(r)
+	if err != nil {
+		return err
+	}
+
+	// keep the rule where it is, unless told otherwise
+	if r.Sequence == 0 {
+		e := FirewallRule{UUID: r.UUID}
+		err = s.ReadRule(&e)
+		if err != nil {
+			return err
+		}
+		r.Sequence = e.Sequence
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/setRule/%s", FirewallFilterAPI, r.UUID), r.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplyRules()
+}
+
+// DeleteRule destroy an existing firewall filter rule
+func (s *FirewallSession) DeleteRule(r *FirewallRule) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/delRule/%s", FirewallFilterAPI, r.UUID), nil, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplyRules()
+}
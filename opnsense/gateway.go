@@ -0,0 +1,318 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// GatewayGroupServiceURI is the WebUI gateway groups URI
+	GatewayGroupServiceURI = "/system_gateway_groups.php"
+	// GatewayGroupServiceEditURI is the WebUI gateway group edit URI
+	GatewayGroupServiceEditURI = "/system_gateway_groups_edit.php"
+)
+
+const (
+	// GatewayMaxTier is the lowest priority tier a gateway can be assigned to within a group
+	GatewayMaxTier = 5
+)
+
+// GatewayGroupTriggers are the conditions a gateway group member is considered down on
+var GatewayGroupTriggers = []string{"down", "downloss", "downlatency", "downlosslatency"}
+
+const (
+	// ErrGatewayGroupExists is thrown when a gateway group with the same name is already configured
+	ErrGatewayGroupExists = "gateway group with this name already exists"
+	// ErrNoSuchGatewayGroup is thrown if no gateway group can be found for the specific name
+	ErrNoSuchGatewayGroup = "gateway group doesn't exists"
+	// ErrNoSuchGateway is thrown if a gateway group member isn't a configured gateway
+	ErrNoSuchGateway = "gateway doesn't exists"
+)
+
+// GatewaySession abstracts OPNSense gateways routing
+type GatewaySession struct {
+	OPN *OPNSession
+}
+
+// GatewayGroup abstracts a failover/load-balancing group of gateways,
+// members of the lowest tier being used first (load-balanced if several)
+type GatewayGroup struct {
+	ID          int
+	Name        string
+	Trigger     string
+	Description string
+	// Tiers maps member gateway names to their tier (1 to GatewayMaxTier)
+	Tiers map[string]int
+}
+
+// Gateways returns the group member gateway names, sorted
+func (g *GatewayGroup) Gateways() []string {
+	names := []string{}
+	for name := range g.Tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply reloads routing so that gateway groups changes take effect
+func (s *GatewaySession) Apply(page string) error {
+	data := requests.Datas{
+		"apply": "Apply changes",
+	}
+
+	_, err := s.OPN.ApplyChanges(s.OPN.URL(GatewayGroupServiceURI), page, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAllGatewayGroups retrieves the list of all configured gateway groups (without details)
+func (s *GatewaySession) GetAllGatewayGroups() ([]GatewayGroup, error) {
+
+	groups := []GatewayGroup{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return groups, err
+	}
+
+	// read out the service page
+	doc, err := s.OPN.GetPage(s.OPN.URL(GatewayGroupServiceURI))
+	if err != nil {
+		return groups, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table`) == nil {
+		return groups, s.OPN.UnexpectedPage(doc, "gateway groups table")
+	}
+
+	// XPath query to find all table rows with an edit link
+	q := fmt.Sprintf(`//table//tr[.//a[contains(@href, "%s")]]`, strings.TrimPrefix(GatewayGroupServiceEditURI, "/"))
+	rows, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return groups, err
+	}
+
+	// retrieve all configured groups
+	for _, r := range rows {
+		td := htmlquery.FindOne(r, `//td[1]`)
+		if td == nil {
+			continue
+		}
+		g := GatewayGroup{
+			ID:   RowID(r, GatewayGroupServiceEditURI),
+			Name: strings.TrimSpace(htmlquery.InnerText(td)),
+		}
+		if g.ID == -1 {
+			continue
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
+
+// FindGatewayGroup retrieves all gateway groups and select the one that matches the name
+func (s *GatewaySession) FindGatewayGroup(name string) (*GatewayGroup, error) {
+
+	// retrieves existing groups
+	groups, err := s.GetAllGatewayGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if a group exists
+	for _, g := range groups {
+		// we found it
+		if g.Name == name {
+			return &g, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchGatewayGroup)
+}
+
+// ReadDetails retrieves a gateway group trigger, description and member tiers from its edit page,
+// which exposes a tier selection for every configured gateway (0 when not a member)
+func (s *GatewaySession) ReadDetails(g *GatewayGroup) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?id=%d", GatewayGroupServiceEditURI, g.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	g.Trigger = SelectedValue(doc, "trigger")
+	g.Description = InputValue(doc, "descr")
+	g.Tiers = map[string]int{}
+	for _, n := range htmlquery.Find(doc, `//select[@name]`) {
+		name := htmlquery.SelectAttr(n, "name")
+		if name == "trigger" || strings.HasSuffix(name, "_vip") {
+			continue
+		}
+		o := htmlquery.FindOne(n, `./option[@selected]`)
+		if o == nil {
+			continue
+		}
+		tier, err := strconv.Atoi(htmlquery.SelectAttr(o, "value"))
+		if err == nil && tier > 0 {
+			g.Tiers[name] = tier
+		}
+	}
+
+	return nil
+}
+
+// CreateOrEdit creates or edit a gateway group
+func (s *GatewaySession) CreateOrEdit(g *GatewayGroup) error {
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(GatewayGroupServiceEditURI)
+	if g.ID != -1 {
+		editURI = fmt.Sprintf("%s?id=%d", editURI, g.ID)
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+	doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
+	if err != nil {
+		return err
+	}
+
+	// members must be configured gateways, each of them having a tier selection
+	for _, name := range g.Gateways() {
+		if htmlquery.FindOne(doc, fmt.Sprintf(`//select[@name="%s"]`, name)) == nil {
+			return fmt.Errorf("%s: %s", ErrNoSuchGateway, name)
+		}
+	}
+
+	// keep all settings we don't manage (e.g. virtual IPs) as they currently are
+	data := FormValues(doc)
+
+	// gateways which aren't members are left out of the group
+	for _, n := range htmlquery.Find(doc, `//select[@name]`) {
+		name := htmlquery.SelectAttr(n, "name")
+		if name != "trigger" && !strings.HasSuffix(name, "_vip") {
+			data[name] = "0"
+		}
+	}
+
+	// create a new gateway group entry
+	data["name"] = g.Name
+	data["trigger"] = g.Trigger
+	data["descr"] = g.Description
+	data["Submit"] = "Save"
+	for name, tier := range g.Tiers {
+		data[name] = fmt.Sprintf("%d", tier)
+	}
+	if g.ID != -1 {
+		data["id"] = fmt.Sprintf("%d", g.ID)
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply(resp.Text())
+}
+
+// CreateGatewayGroup creates a new gateway group
+func (s *GatewaySession) CreateGatewayGroup(g *GatewayGroup) error {
+
+	e, err := s.FindGatewayGroup(g.Name)
+
+	// check if the group is not already configured
+	if e != nil {
+		return s.OPN.Error(ErrGatewayGroupExists)
+	}
+	if err != nil && err.Error() != ErrNoSuchGatewayGroup {
+		return err
+	}
+
+	// create the group entry
+	g.ID = -1
+	return s.CreateOrEdit(g)
+}
+
+// ReadGatewayGroup retrieves gateway group information for a specified name
+func (s *GatewaySession) ReadGatewayGroup(g *GatewayGroup) error {
+
+	// check if a group exists
+	e, err := s.FindGatewayGroup(g.Name)
+	if e == nil {
+		return err
+	}
+
+	// assign values accordingly
+	g.ID = e.ID
+
+	return s.ReadDetails(g)
+}
+
+// UpdateGatewayGroup modifies an already existing gateway group
+func (s *GatewaySession) UpdateGatewayGroup(g *GatewayGroup) error {
+
+	// check if a group exists
+	e, err := s.FindGatewayGroup(g.Name)
+	if e == nil {
+		return err
+	}
+
+	// update the group entry
+	g.ID = e.ID
+	return s.CreateOrEdit(g)
+}
+
+// DeleteGatewayGroup destroy an existing gateway group
+func (s *GatewaySession) DeleteGatewayGroup(g *GatewayGroup) error {
+
+	// check if a group exists
+	e, err := s.FindGatewayGroup(g.Name)
+	if e == nil {
+		return err
+	}
+
+	// get the service page to retrieve form secret values
+	groupURI := s.OPN.URL(GatewayGroupServiceURI)
+	resp, err := s.OPN.Get(groupURI)
+	if err != nil {
+		return err
+	}
+
+	// destroy group entry
+	data := requests.Datas{
+		"act": "del",
+		"id":  fmt.Sprintf("%d", e.ID),
+	}
+
+	resp, err = s.OPN.PostForm(groupURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input (e.g. group still referenced by a rule)
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply(resp.Text())
+}
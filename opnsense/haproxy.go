@@ -0,0 +1,412 @@
+package opnsense
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HAProxyAPI is the HAProxy plugin MVC API root
+	HAProxyAPI = "/api/haproxy"
+)
+
+// HAProxyBackendModes are the proxy modes of an HAProxy backend
+var HAProxyBackendModes = []string{"http", "tcp"}
+
+// HAProxyFrontendModes are the proxy modes of an HAProxy frontend
+var HAProxyFrontendModes = []string{"http", "ssl", "tcp"}
+
+// HAProxySession abstracts OPNSense HAProxy plugin
+type HAProxySession struct {
+	OPN *OPNSession
+}
+
+// HAProxyServer abstracts a real server of an HAProxy backend pool
+type HAProxyServer struct {
+	UUID    string
+	Name    string
+	Address string
+	Port    int
+}
+
+// HAProxyBackend abstracts an HAProxy backend pool, its servers being
+// stored as separate OPNSense entries owned by the backend
+type HAProxyBackend struct {
+	UUID        string
+	Enabled     bool
+	Name        string
+	Mode        string
+	Servers     []HAProxyServer
+	Description string
+}
+
+// HAProxyFrontend abstracts an HAProxy public service
+type HAProxyFrontend struct {
+	UUID           string
+	Enabled        bool
+	Name           string
+	Bind           []string
+	Mode           string
+	DefaultBackend string
+	Description    string
+}
+
+type apiHAProxyServer struct {
+	Enabled     string `json:"enabled"`
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	Port        string `json:"port"`
+	Description string `json:"description"`
+}
+
+type apiHAProxyBackend struct {
+	Enabled       string `json:"enabled"`
+	Name          string `json:"name"`
+	Mode          string `json:"mode"`
+	LinkedServers string `json:"linkedServers"`
+	Description   string `json:"description"`
+}
+
+type apiHAProxyBackendRead struct {
+	Enabled       string               `json:"enabled"`
+	Name          string               `json:"name"`
+	Mode          map[string]APIOption `json:"mode"`
+	LinkedServers map[string]APIOption `json:"linkedServers"`
+	Description   string               `json:"description"`
+}
+
+type apiHAProxyFrontend struct {
+	Enabled        string `json:"enabled"`
+	Name           string `json:"name"`
+	Bind           string `json:"bind"`
+	Mode           string `json:"mode"`
+	DefaultBackend string `json:"defaultBackend"`
+	Description    string `json:"description"`
+}
+
+type apiHAProxyFrontendRead struct {
+	Enabled        string               `json:"enabled"`
+	Name           string               `json:"name"`
+	Bind           map[string]APIOption `json:"bind"`
+	Mode           map[string]APIOption `json:"mode"`
+	DefaultBackend map[string]APIOption `json:"defaultBackend"`
+	Description    string               `json:"description"`
+}
+
+func (b *HAProxyBackend) toAPI(servers []string) map[string]apiHAProxyBackend {
+	enabled := "0"
+	if b.Enabled {
+		enabled = "1"
+	}
+	return map[string]apiHAProxyBackend{
+		"backend": {
+			Enabled:       enabled,
+			Name:          b.Name,
+			Mode:          b.Mode,
+			LinkedServers: strings.Join(servers, ","),
+			Description:   b.Description,
+		},
+	}
+}
+
+func (f *HAProxyFrontend) toAPI() map[string]apiHAProxyFrontend {
+	enabled := "0"
+	if f.Enabled {
+		enabled = "1"
+	}
+
+	// bind addresses are a set, write them in a stable order
+	bind := append([]string{}, f.Bind...)
+	sort.Strings(bind)
+
+	return map[string]apiHAProxyFrontend{
+		"frontend": {
+			Enabled:        enabled,
+			Name:           f.Name,
+			Bind:           strings.Join(bind, ","),
+			Mode:           f.Mode,
+			DefaultBackend: f.DefaultBackend,
+			Description:    f.Description,
+		},
+	}
+}
+
+// Apply reconfigures and reloads HAProxy
+func (s *HAProxySession) Apply() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/service/reconfigure", HAProxyAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// post submits an HAProxy settings write operation
+func (s *HAProxySession) post(op string, data interface{}) (string, error) {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/%s", HAProxyAPI, op), data, &res)
+	if err != nil {
+		return "", err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return "", err
+	}
+	return res.UUID, nil
+}
+
+// addServers creates the servers of a backend, returning their UUIDs
+func (s *HAProxySession) addServers(b *HAProxyBackend) ([]string, error) {
+	uuids := []string{}
+	for _, srv := range b.Servers {
+		data := map[string]apiHAProxyServer{
+			"server": {
+				Enabled:     "1",
+				Name:        srv.Name,
+				Address:     srv.Address,
+				Port:        strconv.Itoa(srv.Port),
+				Description: fmt.Sprintf("%s backend server", b.Name),
+			},
+		}
+		uuid, err := s.post("addServer", data)
+		if err != nil {
+			// don't leave orphan servers behind
+			s.deleteServers(uuids)
+			return nil, err
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	return uuids, nil
+}
+
+// deleteServers removes servers formerly owned by a backend
+func (s *HAProxySession) deleteServers(uuids []string) error {
+	for _, uuid := range uuids {
+		_, err := s.post(fmt.Sprintf("delServer/%s", uuid), nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBackend retrieves a backend settings, along with its linked servers UUIDs
+func (s *HAProxySession) readBackend(uuid string) (*apiHAProxyBackendRead, error) {
+	res := map[string]apiHAProxyBackendRead{}
+	err := s.OPN.GetJSON(fmt.Sprintf("%s/settings/getBackend/%s", HAProxyAPI, uuid), &res)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := res["backend"]
+	if !ok {
+		return nil, fmt.Errorf("HAProxy backend %s doesn't exists", uuid)
+	}
+	return &e, nil
+}
+
+// CreateBackend creates a new HAProxy backend, along with its servers
+func (s *HAProxySession) CreateBackend(b *HAProxyBackend) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	servers, err := s.addServers(b)
+	if err != nil {
+		return err
+	}
+
+	b.UUID, err = s.post("addBackend", b.toAPI(servers))
+	if err != nil {
+		s.deleteServers(servers)
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// ReadBackend retrieves HAProxy backend information for a specified UUID
+func (s *HAProxySession) ReadBackend(b *HAProxyBackend) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	e, err := s.readBackend(b.UUID)
+	if err != nil {
+		return err
+	}
+
+	// assign values accordingly
+	b.Enabled = e.Enabled == "1"
+	b.Name = e.Name
+	b.Mode = SelectedOption(e.Mode)
+	b.Description = e.Description
+
+	// retrieve linked servers
+	b.Servers = []HAProxyServer{}
+	for _, uuid := range SelectedOptions(e.LinkedServers) {
+		res := map[string]apiHAProxyServer{}
+		err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/getServer/%s", HAProxyAPI, uuid), &res)
+		if err != nil {
+			return err
+		}
+		srv, ok := res["server"]
+		if !ok {
+			continue
+		}
+		port, _ := strconv.Atoi(srv.Port)
+		b.Servers = append(b.Servers, HAProxyServer{
+			UUID:    uuid,
+			Name:    srv.Name,
+			Address: srv.Address,
+			Port:    port,
+		})
+	}
+
+	return nil
+}
+
+// UpdateBackend modifies an already existing HAProxy backend, its servers being replaced
+func (s *HAProxySession) UpdateBackend(b *HAProxyBackend) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	e, err := s.readBackend(b.UUID)
+	if err != nil {
+		return err
+	}
+	previous := SelectedOptions(e.LinkedServers)
+
+	// new servers are linked first, so that the backend never refers to missing ones
+	servers, err := s.addServers(b)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.post(fmt.Sprintf("setBackend/%s", b.UUID), b.toAPI(servers))
+	if err != nil {
+		s.deleteServers(servers)
+		return err
+	}
+
+	err = s.deleteServers(previous)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// DeleteBackend destroy an existing HAProxy backend, along with its servers
+func (s *HAProxySession) DeleteBackend(b *HAProxyBackend) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	e, err := s.readBackend(b.UUID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.post(fmt.Sprintf("delBackend/%s", b.UUID), nil)
+	if err != nil {
+		return err
+	}
+
+	err = s.deleteServers(SelectedOptions(e.LinkedServers))
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// CreateFrontend creates a new HAProxy frontend
+func (s *HAProxySession) CreateFrontend(f *HAProxyFrontend) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	f.UUID, err = s.post("addFrontend", f.toAPI())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// ReadFrontend retrieves HAProxy frontend information for a specified UUID
+func (s *HAProxySession) ReadFrontend(f *HAProxyFrontend) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]apiHAProxyFrontendRead{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/getFrontend/%s", HAProxyAPI, f.UUID), &res)
+	if err != nil {
+		return err
+	}
+	e, ok := res["frontend"]
+	if !ok {
+		return fmt.Errorf("HAProxy frontend %s doesn't exists", f.UUID)
+	}
+
+	// assign values accordingly
+	f.Enabled = e.Enabled == "1"
+	f.Name = e.Name
+	f.Bind = SelectedOptions(e.Bind)
+	f.Mode = SelectedOption(e.Mode)
+	f.DefaultBackend = SelectedOption(e.DefaultBackend)
+	f.Description = e.Description
+
+	return nil
+}
+
+// UpdateFrontend modifies an already existing HAProxy frontend
+func (s *HAProxySession) UpdateFrontend(f *HAProxyFrontend) error {
+
+	_, err := s.post(fmt.Sprintf("setFrontend/%s", f.UUID), f.toAPI())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// DeleteFrontend destroy an existing HAProxy frontend
+func (s *HAProxySession) DeleteFrontend(f *HAProxyFrontend) error {
+
+	_, err := s.post(fmt.Sprintf("delFrontend/%s", f.UUID), nil)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
@@ -0,0 +1,68 @@
+package opnsense
+
+import (
+	"sort"
+)
+
+// DefaultUILanguage is the OPNSense WebUI language scrapers are written against
+const DefaultUILanguage = "en_US"
+
+// UILanguages maps supported OPNSense WebUI languages to the translation of
+// the English table headers and labels the provider relies on
+var UILanguages = map[string]map[string]string{
+	DefaultUILanguage: {},
+	"fr_FR": {
+		DHCPStaticARP:   "ARP statique",
+		DHCPMAC:         "Adresse MAC",
+		DHCPIP:          "Adresse IP",
+		DHCPHostname:    "Nom d'hôte",
+		DHCPDescription: "Description",
+		DNSHost:         "Hôte",
+		DNSDomain:       "Domaine",
+		DNSType:         "Type",
+		DNSValue:        "Valeur",
+		"Subnet":        "Sous-réseau",
+		"Subnet mask":   "Masque de sous-réseau",
+	},
+	"de_DE": {
+		DHCPStaticARP:   "Statisches ARP",
+		DHCPMAC:         "MAC-Adresse",
+		DHCPIP:          "IP-Adresse",
+		DHCPHostname:    "Hostname",
+		DHCPDescription: "Beschreibung",
+		DNSHost:         "Host",
+		DNSDomain:       "Domain",
+		DNSType:         "Typ",
+		DNSValue:        "Wert",
+		"Subnet":        "Subnetz",
+		"Subnet mask":   "Subnetzmaske",
+	},
+}
+
+// SupportedUILanguages returns the sorted list of supported OPNSense WebUI languages
+func SupportedUILanguages() []string {
+	langs := []string{}
+	for l := range UILanguages {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Localize translates an English WebUI label into the session language
+func (s *OPNSession) Localize(label string) string {
+	if t, ok := UILanguages[s.Language][label]; ok {
+		return t
+	}
+	return label
+}
+
+// Canonical translates a WebUI label of the session language back into English
+func (s *OPNSession) Canonical(label string) string {
+	for en, t := range UILanguages[s.Language] {
+		if t == label {
+			return en
+		}
+	}
+	return label
+}
@@ -0,0 +1,269 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"regexp"
+	"strings"
+)
+
+const (
+	// InterfaceGroupServiceURI is the WebUI interface groups URI
+	InterfaceGroupServiceURI = "/interfaces_groups.php"
+	// InterfaceGroupServiceEditURI is the WebUI interface group edit URI
+	InterfaceGroupServiceEditURI = "/interfaces_groups_edit.php"
+)
+
+const (
+	// ErrInterfaceGroupExists is thrown when an interface group with the same name is already configured
+	ErrInterfaceGroupExists = "interface group with this name already exists"
+	// ErrNoSuchInterfaceGroup is thrown if no interface group can be found for the specific name
+	ErrNoSuchInterfaceGroup = "interface group doesn't exists"
+	// ErrNoSuchInterfaceGroupMember is thrown if an interface group member isn't an assigned interface
+	ErrNoSuchInterfaceGroupMember = "interface can't be a group member, it must be assigned first"
+)
+
+// rxInterfaceGroupName matches interface group names, which can't end with a digit
+// so that they're never mistaken for an interface
+var rxInterfaceGroupName = regexp.MustCompile(`^[a-zA-Z0-9_]{0,14}[a-zA-Z_]$`)
+
+// InterfaceGroup abstracts a group of interfaces, firewall rules can target at once
+type InterfaceGroup struct {
+	ID          int
+	Name        string
+	Members     []string
+	Description string
+}
+
+// ApplyGroups reloads firewall filter so that interface groups changes take effect
+func (s *InterfaceSession) ApplyGroups(page string) error {
+	data := requests.Datas{
+		"apply": "Apply changes",
+	}
+
+	_, err := s.OPN.ApplyChanges(s.OPN.URL(InterfaceGroupServiceURI), page, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAllInterfaceGroups retrieves the list of all configured interface groups (without details)
+func (s *InterfaceSession) GetAllInterfaceGroups() ([]InterfaceGroup, error) {
+
+	groups := []InterfaceGroup{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return groups, err
+	}
+
+	// read out the service page
+	doc, err := s.OPN.GetPage(s.OPN.URL(InterfaceGroupServiceURI))
+	if err != nil {
+		return groups, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table`) == nil {
+		return groups, s.OPN.UnexpectedPage(doc, "interface groups table")
+	}
+
+	// XPath query to find all table rows with an edit link
+	q := fmt.Sprintf(`//table//tr[.//a[contains(@href, "%s")]]`, strings.TrimPrefix(InterfaceGroupServiceEditURI, "/"))
+	rows, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return groups, err
+	}
+
+	// retrieve all configured groups
+	for _, r := range rows {
+		td := htmlquery.FindOne(r, `//td[1]`)
+		if td == nil {
+			continue
+		}
+		g := InterfaceGroup{
+			ID:   RowID(r, InterfaceGroupServiceEditURI),
+			Name: strings.TrimSpace(htmlquery.InnerText(td)),
+		}
+		if g.ID == -1 {
+			continue
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, nil
+}
+
+// FindInterfaceGroup retrieves all interface groups and select the one that matches the name
+func (s *InterfaceSession) FindInterfaceGroup(name string) (*InterfaceGroup, error) {
+
+	// retrieves existing groups
+	groups, err := s.GetAllInterfaceGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if a group exists
+	for _, g := range groups {
+		// we found it
+		if g.Name == name {
+			return &g, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchInterfaceGroup)
+}
+
+// ReadGroupDetails retrieves an interface group members and description from its edit page
+func (s *InterfaceSession) ReadGroupDetails(g *InterfaceGroup) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?id=%d", InterfaceGroupServiceEditURI, g.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	g.Members = SelectedValues(doc, "members[]")
+	g.Description = InputValue(doc, "descr")
+
+	return nil
+}
+
+// saveInterfaceGroup creates or edit an interface group
+func (s *InterfaceSession) saveInterfaceGroup(g *InterfaceGroup) error {
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(InterfaceGroupServiceEditURI)
+	if g.ID != -1 {
+		editURI = fmt.Sprintf("%s?id=%d", editURI, g.ID)
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+	doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
+	if err != nil {
+		return err
+	}
+
+	// members must be assigned interfaces, as offered by the edit page
+	for _, m := range g.Members {
+		if htmlquery.FindOne(doc, fmt.Sprintf(`//select[@name="members[]"]/option[@value="%s"]`, m)) == nil {
+			return fmt.Errorf("%s: %s", ErrNoSuchInterfaceGroupMember, m)
+		}
+	}
+
+	// create a new group entry
+	data := requests.Datas{
+		"ifname": g.Name,
+		"descr":  g.Description,
+		"Submit": "Save",
+	}
+	if g.ID != -1 {
+		data["id"] = fmt.Sprintf("%d", g.ID)
+	}
+	for i, m := range g.Members {
+		data[fmt.Sprintf("members[%d]", i)] = m
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplyGroups(resp.Text())
+}
+
+// CreateInterfaceGroup creates a new interface group
+func (s *InterfaceSession) CreateInterfaceGroup(g *InterfaceGroup) error {
+
+	e, err := s.FindInterfaceGroup(g.Name)
+
+	// check if the group is not already configured
+	if e != nil {
+		return s.OPN.Error(ErrInterfaceGroupExists)
+	}
+	if err != nil && err.Error() != ErrNoSuchInterfaceGroup {
+		return err
+	}
+
+	// create the group entry
+	g.ID = -1
+	return s.saveInterfaceGroup(g)
+}
+
+// ReadInterfaceGroup retrieves interface group information for a specified name
+func (s *InterfaceSession) ReadInterfaceGroup(g *InterfaceGroup) error {
+
+	// check if a group exists
+	e, err := s.FindInterfaceGroup(g.Name)
+	if e == nil {
+		return err
+	}
+
+	// assign values accordingly
+	g.ID = e.ID
+
+	return s.ReadGroupDetails(g)
+}
+
+// UpdateInterfaceGroup modifies an already existing interface group
+func (s *InterfaceSession) UpdateInterfaceGroup(g *InterfaceGroup) error {
+
+	// check if a group exists
+	e, err := s.FindInterfaceGroup(g.Name)
+	if e == nil {
+		return err
+	}
+
+	// update the group entry
+	g.ID = e.ID
+	return s.saveInterfaceGroup(g)
+}
+
+// DeleteInterfaceGroup destroy an existing interface group
+func (s *InterfaceSession) DeleteInterfaceGroup(g *InterfaceGroup) error {
+
+	// check if a group exists
+	e, err := s.FindInterfaceGroup(g.Name)
+	if e == nil {
+		return err
+	}
+
+	// get the service page to retrieve form secret values
+	groupURI := s.OPN.URL(InterfaceGroupServiceURI)
+	resp, err := s.OPN.Get(groupURI)
+	if err != nil {
+		return err
+	}
+
+	// destroy group entry
+	data := requests.Datas{
+		"act": "del",
+		"id":  fmt.Sprintf("%d", e.ID),
+	}
+
+	resp, err = s.OPN.PostForm(groupURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input (e.g. group still referenced by a rule)
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplyGroups(resp.Text())
+}
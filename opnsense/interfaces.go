@@ -0,0 +1,137 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"strconv"
+	"strings"
+)
+
+const (
+	// InterfaceServiceURI is the WebUI interface configuration URI
+	InterfaceServiceURI = "/interfaces.php"
+)
+
+const (
+	// ErrNoSuchInterface is thrown if the logical interface hasn't been assigned
+	ErrNoSuchInterface = "interface doesn't exists, it must be assigned first"
+)
+
+// InterfaceIPv4Types are the supported IPv4 configuration types of an interface
+var InterfaceIPv4Types = []string{"none", "staticv4", "dhcp"}
+
+// InterfaceSession abstracts OPNSense interfaces configuration
+type InterfaceSession struct {
+	OPN *OPNSession
+}
+
+// Interface abstracts an assigned (logical) interface configuration
+type Interface struct {
+	Name        string
+	Enabled     bool
+	Description string
+	IPv4Type    string
+	IPAddress   string
+	Bits        int
+}
+
+// interfaceURI returns the configuration page URI of a logical interface
+func (s *InterfaceSession) interfaceURI(name string) string {
+	return s.OPN.URL(fmt.Sprintf("%s?if=%s", InterfaceServiceURI, name))
+}
+
+// Apply reconfigures an interface with its pending changes
+func (s *InterfaceSession) Apply(name, page string) error {
+	data := requests.Datas{
+		"if":    name,
+		"apply": "Apply changes",
+	}
+
+	_, err := s.OPN.ApplyChanges(s.interfaceURI(name), page, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadInterface retrieves the configuration of a logical interface
+func (s *InterfaceSession) ReadInterface(i *Interface) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	doc, err := s.OPN.GetPage(s.interfaceURI(i.Name))
+	if err != nil {
+		return err
+	}
+
+	// unassigned interfaces don't have any configuration form
+	if htmlquery.FindOne(doc, `//div[@class="content-box"]//form//*[@name="descr"]`) == nil {
+		return s.OPN.Error(ErrNoSuchInterface)
+	}
+
+	i.Enabled = IsChecked(doc, "enable")
+	i.Description = InputValue(doc, "descr")
+	i.IPv4Type = SelectedValue(doc, "type")
+	i.IPAddress = InputValue(doc, "ipaddr")
+	i.Bits, _ = strconv.Atoi(SelectedValue(doc, "subnet"))
+
+	return nil
+}
+
+// UpdateInterface configures an already assigned logical interface and reconfigures it
+func (s *InterfaceSession) UpdateInterface(i *Interface) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	// get the configuration page to retrieve form secret values and other settings
+	uri := s.interfaceURI(i.Name)
+	resp, err := s.OPN.Get(uri)
+	if err != nil {
+		return err
+	}
+	doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
+	if err != nil {
+		return err
+	}
+	if htmlquery.FindOne(doc, `//div[@class="content-box"]//form//*[@name="descr"]`) == nil {
+		return s.OPN.Error(ErrNoSuchInterface)
+	}
+
+	// keep settings we don't manage as they are
+	data := FormValues(doc)
+	delete(data, "enable")
+	data["if"] = i.Name
+	data["descr"] = i.Description
+	data["type"] = i.IPv4Type
+	data["Submit"] = "Save"
+	if i.Enabled {
+		data["enable"] = "yes"
+	}
+	if i.IPv4Type == "staticv4" {
+		data["ipaddr"] = i.IPAddress
+		data["subnet"] = fmt.Sprintf("%d", i.Bits)
+	}
+
+	resp, err = s.OPN.EditForm(uri, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply(i.Name, resp.Text())
+}
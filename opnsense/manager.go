@@ -0,0 +1,27 @@
+package opnsense
+
+// DHCPManager captures DHCP static mappings operations, so that code orchestrating
+// them can be exercised without a live OPNSense instance (see opnsense/fake package)
+type DHCPManager interface {
+	GetAllInterfaceStaticMappings(iface string) ([]StaticMapping, error)
+	CreateStaticMapping(m *StaticMapping) error
+	ReadStaticMapping(m *StaticMapping) error
+	UpdateStaticMapping(m *StaticMapping) error
+	DeleteStaticMapping(m *StaticMapping) error
+}
+
+// DNSManager captures DNS host overrides operations, so that code orchestrating
+// them can be exercised without a live OPNSense instance (see opnsense/fake package)
+type DNSManager interface {
+	GetAllHostEntries() ([]DNSHostEntry, error)
+	CreateHostOverride(h *DNSHostEntry) error
+	ReadHostOverride(h *DNSHostEntry) error
+	UpdateHostOverride(h *DNSHostEntry) error
+	DeleteHostOverride(h *DNSHostEntry) error
+}
+
+// real sessions must keep satisfying the interfaces
+var (
+	_ DHCPManager = (*DHCPSession)(nil)
+	_ DNSManager  = (*DNSSession)(nil)
+)
@@ -0,0 +1,169 @@
+package opnsense
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// MonitAPI is the Monit plugin MVC API root
+	MonitAPI = "/api/monit"
+)
+
+// MonitServiceTypes are the kinds of services Monit can check
+var MonitServiceTypes = []string{"process", "file", "fifo", "filesystem", "directory", "host", "system", "custom", "network"}
+
+// MonitSession abstracts OPNSense Monit plugin
+type MonitSession struct {
+	OPN *OPNSession
+}
+
+// MonitService abstracts a Monit service check
+type MonitService struct {
+	UUID        string
+	Enabled     bool
+	Name        string
+	Type        string
+	Address     string
+	Tests       []string
+	Description string
+}
+
+type apiMonitService struct {
+	Enabled     string `json:"enabled"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Address     string `json:"address"`
+	Tests       string `json:"tests"`
+	Description string `json:"description"`
+}
+
+type apiMonitServiceRead struct {
+	Enabled     string               `json:"enabled"`
+	Name        string               `json:"name"`
+	Type        map[string]APIOption `json:"type"`
+	Address     string               `json:"address"`
+	Tests       map[string]APIOption `json:"tests"`
+	Description string               `json:"description"`
+}
+
+func (m *MonitService) toAPI() map[string]apiMonitService {
+	enabled := "0"
+	if m.Enabled {
+		enabled = "1"
+	}
+
+	// tests are a set, write them in a stable order
+	tests := append([]string{}, m.Tests...)
+	sort.Strings(tests)
+
+	return map[string]apiMonitService{
+		"service": {
+			Enabled:     enabled,
+			Name:        m.Name,
+			Type:        m.Type,
+			Address:     m.Address,
+			Tests:       strings.Join(tests, ","),
+			Description: m.Description,
+		},
+	}
+}
+
+// Apply reconfigures and reloads Monit
+func (s *MonitSession) Apply() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/service/reconfigure", MonitAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// CreateService creates a new Monit service check
+func (s *MonitSession) CreateService(m *MonitService) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/settings/addService", MonitAPI), m.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+	m.UUID = res.UUID
+
+	// apply changes
+	return s.Apply()
+}
+
+// ReadService retrieves Monit service check information for a specified UUID
+func (s *MonitSession) ReadService(m *MonitService) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]apiMonitServiceRead{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/getService/%s", MonitAPI, m.UUID), &res)
+	if err != nil {
+		return err
+	}
+	e, ok := res["service"]
+	if !ok {
+		return fmt.Errorf("Monit service %s doesn't exists", m.UUID)
+	}
+
+	// assign values accordingly
+	m.Enabled = e.Enabled == "1"
+	m.Name = e.Name
+	m.Type = SelectedOption(e.Type)
+	m.Address = e.Address
+	m.Tests = SelectedOptions(e.Tests)
+	m.Description = e.Description
+
+	return nil
+}
+
+// UpdateService modifies an already existing Monit service check
+func (s *MonitSession) UpdateService(m *MonitService) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/setService/%s", MonitAPI, m.UUID), m.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// DeleteService destroy an existing Monit service check
+func (s *MonitSession) DeleteService(m *MonitService) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/delService/%s", MonitAPI, m.UUID), nil, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
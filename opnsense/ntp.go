@@ -0,0 +1,163 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"strings"
+)
+
+const (
+	// NTPServiceURI is the WebUI network time (ntpd) settings URI
+	NTPServiceURI = "/services_ntpd.php"
+)
+
+const (
+	// ErrNTPServerNotConfigured is thrown if a preferred time server isn't part of the time servers list
+	ErrNTPServerNotConfigured = "preferred time server must be one of the configured time servers"
+	// ErrNTPRestrictionUnsupported is thrown if an access restriction isn't offered by the NTP settings page
+	ErrNTPRestrictionUnsupported = "this OPNSense version doesn't support this NTP access restriction"
+)
+
+// NTPRestrictions lists the ntpd default access restrictions, applied to every client
+var NTPRestrictions = []string{"kod", "nomodify", "noquery", "noserve", "nopeer", "notrap"}
+
+// NTPSession abstracts OPNSense network time settings
+type NTPSession struct {
+	OPN *OPNSession
+}
+
+// NTPSettings abstracts OPNSense network time (ntpd) settings
+type NTPSettings struct {
+	Servers      []string
+	Prefer       []string
+	Restrictions []string
+}
+
+// ntpHasServer tells whether a time server is part of a servers list
+func ntpHasServer(servers []string, server string) bool {
+	for _, s := range servers {
+		if s == server {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadNTPSettings retrieves network time settings
+func (s *NTPSession) ReadNTPSettings() (*NTPSettings, error) {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return nil, err
+	}
+
+	// read out the settings page
+	doc, err := s.OPN.GetPage(s.OPN.URL(NTPServiceURI))
+	if err != nil {
+		return nil, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//select[@name="timeservers_host[]"]`) == nil {
+		return nil, s.OPN.UnexpectedPage(doc, "NTP time servers selection")
+	}
+
+	n := NTPSettings{
+		Servers:      SelectedValues(doc, "timeservers_host[]"),
+		Prefer:       SelectedValues(doc, "timeservers_prefer[]"),
+		Restrictions: []string{},
+	}
+	for _, r := range NTPRestrictions {
+		if IsChecked(doc, r) {
+			n.Restrictions = append(n.Restrictions, r)
+		}
+	}
+
+	return &n, nil
+}
+
+// UpdateNTPSettings modifies network time settings, ntpd being restarted on save
+func (s *NTPSession) UpdateNTPSettings(n *NTPSettings) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	// preferred servers must be part of the time servers list
+	for _, p := range n.Prefer {
+		if !ntpHasServer(n.Servers, p) {
+			return fmt.Errorf("%s: %s", ErrNTPServerNotConfigured, p)
+		}
+	}
+
+	// get the settings page to retrieve form secret values
+	uri := s.OPN.URL(NTPServiceURI)
+	resp, err := s.OPN.Get(uri)
+	if err != nil {
+		return err
+	}
+	doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
+	if err != nil {
+		return err
+	}
+	if htmlquery.FindOne(doc, `//select[@name="timeservers_host[]"]`) == nil {
+		return s.OPN.UnexpectedPage(doc, "NTP time servers selection")
+	}
+
+	// access restrictions are only posted if the settings page exposes them
+	for _, r := range n.Restrictions {
+		if htmlquery.FindOne(doc, fmt.Sprintf(`//input[@name="%s"]`, r)) == nil {
+			return fmt.Errorf("%s: %s", ErrNTPRestrictionUnsupported, r)
+		}
+	}
+
+	// keep all settings we don't manage (e.g. listening interfaces) as they currently are,
+	// multiple selections being posted with indexed keys
+	data := FormValues(doc)
+	for name := range data {
+		if strings.HasSuffix(name, "[]") {
+			delete(data, name)
+		}
+	}
+	for _, sel := range htmlquery.Find(doc, `//div[@class="content-box"]//form//select[@name]`) {
+		name := htmlquery.SelectAttr(sel, "name")
+		if !strings.HasSuffix(name, "[]") || name == "timeservers_host[]" || name == "timeservers_prefer[]" {
+			continue
+		}
+		i := 0
+		for _, v := range SelectedValues(doc, name) {
+			// servers flagged as not selectable are only kept if still configured
+			if name == "timeservers_noselect[]" && !ntpHasServer(n.Servers, v) {
+				continue
+			}
+			data[fmt.Sprintf("%s[%d]", strings.TrimSuffix(name, "[]"), i)] = v
+			i++
+		}
+	}
+	for _, r := range NTPRestrictions {
+		delete(data, r)
+	}
+
+	// update time servers and access restrictions
+	for i, h := range n.Servers {
+		data[fmt.Sprintf("timeservers_host[%d]", i)] = h
+	}
+	for i, p := range n.Prefer {
+		data[fmt.Sprintf("timeservers_prefer[%d]", i)] = p
+	}
+	for _, r := range n.Restrictions {
+		data[r] = "yes"
+	}
+	data["Submit"] = "Save"
+
+	resp, err = s.OPN.EditForm(uri, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	return s.OPN.FormErrors(resp.Text())
+}
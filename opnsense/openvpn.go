@@ -0,0 +1,270 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// OpenVPNCSCServiceURI is the WebUI service URI
+	OpenVPNCSCServiceURI = "/vpn_openvpn_csc.php"
+	// OpenVPNMaxDNSServers is the number of DNS servers a client specific override can push
+	OpenVPNMaxDNSServers = 4
+)
+
+const (
+	// ErrCSCExists is thrown when a client specific override already exists for this common name
+	ErrCSCExists = "client specific override for this common name already exists"
+	// ErrNoSuchCSC is thrown if no client specific override can be found for the specific common name
+	ErrNoSuchCSC = "client specific override doesn't exists"
+)
+
+var rxCSCID = regexp.MustCompile(`id=([0-9]+)`)
+
+// OpenVPNSession abstracts OPNSense OpenVPN
+type OpenVPNSession struct {
+	OPN *OPNSession
+}
+
+// ClientOverride abstracts an OpenVPN client specific override
+type ClientOverride struct {
+	ID            int
+	CommonName    string
+	TunnelNetwork string
+	DNSDomain     string
+	DNSServers    []string
+	Description   string
+}
+
+// GetAllClientOverrides retrieves the list of all configured client specific overrides (without details)
+func (s *OpenVPNSession) GetAllClientOverrides() ([]ClientOverride, error) {
+
+	cscs := []ClientOverride{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return cscs, err
+	}
+
+	// read out the service page
+	cscURI := s.OPN.URL(OpenVPNCSCServiceURI)
+	doc, err := s.OPN.GetPage(cscURI)
+	if err != nil {
+		return cscs, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table`) == nil {
+		return cscs, s.OPN.UnexpectedPage(doc, "client specific overrides table")
+	}
+
+	// XPath query to find all table rows with an edit link
+	q := `//table//tr[.//a[contains(@href, "act=edit")]]`
+	rows, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return cscs, err
+	}
+
+	// retrieve all configured client specific overrides
+	for _, r := range rows {
+		a := htmlquery.FindOne(r, `//a[contains(@href, "act=edit")]`)
+		id := rxCSCID.FindStringSubmatch(htmlquery.SelectAttr(a, "href"))
+		cells := htmlquery.Find(r, `//td`)
+		if len(id) < 2 || len(cells) < 2 {
+			continue
+		}
+		c := ClientOverride{}
+		c.ID, _ = strconv.Atoi(id[1])
+
+		// common name is the first non-empty cell, the first one may hold status icons
+		for _, cell := range cells {
+			c.CommonName = NormalizeText(htmlquery.InnerText(cell))
+			if c.CommonName != "" {
+				break
+			}
+		}
+		cscs = append(cscs, c)
+	}
+
+	return cscs, nil
+}
+
+// FindClientOverride retrieves all client specific overrides and select the one that matches the common name
+func (s *OpenVPNSession) FindClientOverride(cn string) (*ClientOverride, error) {
+
+	// retrieves existing client specific overrides
+	cscs, err := s.GetAllClientOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if a client specific override exists
+	for _, c := range cscs {
+		// we found it
+		if c.CommonName == cn {
+			return &c, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchCSC)
+}
+
+// ReadDetails retrieves a client specific override settings from its edit page
+func (s *OpenVPNSession) ReadDetails(c *ClientOverride) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=edit&id=%d", OpenVPNCSCServiceURI, c.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	c.CommonName = InputValue(doc, "common_name")
+	c.TunnelNetwork = InputValue(doc, "tunnel_network")
+	c.DNSDomain = InputValue(doc, "dns_domain")
+	c.Description = InputValue(doc, "description")
+	c.DNSServers = []string{}
+	for i := 1; i <= OpenVPNMaxDNSServers; i++ {
+		server := InputValue(doc, fmt.Sprintf("dns_server%d", i))
+		if server != "" {
+			c.DNSServers = append(c.DNSServers, server)
+		}
+	}
+
+	return nil
+}
+
+// CreateOrEdit creates or edit a client specific override.
+// OPNSense regenerates client specific configuration files on save, there's nothing else to apply.
+func (s *OpenVPNSession) CreateOrEdit(c *ClientOverride) error {
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=new", OpenVPNCSCServiceURI))
+	if c.ID != -1 {
+		editURI = s.OPN.URL(fmt.Sprintf("%s?act=edit&id=%d", OpenVPNCSCServiceURI, c.ID))
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+
+	// create a new client specific override entry
+	data := requests.Datas{
+		"common_name":    c.CommonName,
+		"tunnel_network": c.TunnelNetwork,
+		"dns_domain":     c.DNSDomain,
+		"description":    c.Description,
+		"save":           "Save",
+	}
+	for i, server := range c.DNSServers {
+		data[fmt.Sprintf("dns_server%d", i+1)] = server
+	}
+	if len(c.DNSServers) > 0 {
+		data["dns_server_enable"] = "yes"
+	}
+	if c.DNSDomain != "" {
+		data["dns_domain_enable"] = "yes"
+	}
+	if c.ID != -1 {
+		data["id"] = fmt.Sprintf("%d", c.ID)
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateClientOverride creates a new client specific override
+func (s *OpenVPNSession) CreateClientOverride(c *ClientOverride) error {
+
+	e, err := s.FindClientOverride(c.CommonName)
+
+	// check if the client specific override is not already registered
+	if e != nil {
+		return s.OPN.Error(ErrCSCExists)
+	}
+
+	// create the client specific override entry
+	c.ID = -1
+	err = s.CreateOrEdit(c)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadClientOverride retrieves client specific override information for a specified common name
+func (s *OpenVPNSession) ReadClientOverride(c *ClientOverride) error {
+
+	// check if a client specific override exists
+	e, err := s.FindClientOverride(c.CommonName)
+	if e == nil {
+		return err
+	}
+
+	c.ID = e.ID
+	return s.ReadDetails(c)
+}
+
+// UpdateClientOverride modifies an already existing client specific override
+func (s *OpenVPNSession) UpdateClientOverride(c *ClientOverride) error {
+
+	// check if a client specific override exists
+	e, err := s.FindClientOverride(c.CommonName)
+	if e == nil {
+		return err
+	}
+
+	// update the client specific override entry
+	c.ID = e.ID
+	err = s.CreateOrEdit(c)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteClientOverride destroy an existing client specific override
+func (s *OpenVPNSession) DeleteClientOverride(c *ClientOverride) error {
+
+	// check if a client specific override exists
+	e, err := s.FindClientOverride(c.CommonName)
+	if e == nil {
+		return err
+	}
+
+	cscURI := s.OPN.URL(OpenVPNCSCServiceURI)
+
+	// destroy client specific override entry
+	data := requests.Datas{
+		"id":  fmt.Sprintf("%d", e.ID),
+		"act": "del",
+	}
+
+	resp, err := s.OPN.PostForm(cscURI, "", data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected removal
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,18 +1,129 @@
 package opnsense
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
+	"github.com/antchfx/htmlquery"
 	"github.com/asmcos/requests"
+	"golang.org/x/net/html"
+	"log"
 	"net/http"
+	"net/url"
+	"path"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+const (
+	// LogoutURI is the WebUI URI closing the current session
+	LogoutURI = "/index.php?logout"
+)
+
+const (
+	// CSRFFailureMarker is the WebUI message returned when a form token has been rejected
+	CSRFFailureMarker = "CSRF check failed"
+)
+
+const (
+	// ConfigLockMaxAttempts is the number of times a form submission is tried while OPNSense configuration is locked
+	ConfigLockMaxAttempts = 5
+	// ConfigLockBackoff is the initial delay between two attempts, doubled after each one
+	ConfigLockBackoff = 500 * time.Millisecond
+)
+
+// ConfigLockMarkers are the WebUI messages returned when configuration is being written by another process
+var ConfigLockMarkers = []string{
+	"Settings have been applied by another process",
+	"configuration is locked",
+	"Could not obtain config lock",
+}
+
+// MaintenanceMarkers are the messages served on every URL while OPNSense is being upgraded or rebooted
+var MaintenanceMarkers = []string{
+	"currently being upgraded",
+	"upgrade is in progress",
+	"firmware upgrade in progress",
+	"maintenance mode",
+	"the system is rebooting",
+}
+
+const (
+	// ErrNotAuthenticated is thrown when OPNSense serves its login page instead of the requested one
+	ErrNotAuthenticated = "OPNSense session expired or not authenticated (login page returned)"
+	// ErrConfigLocked is thrown when OPNSense configuration remains locked by another process
+	ErrConfigLocked = "OPNSense configuration is locked by another process"
+	// ErrCSRFRejected is thrown when a form keeps being rejected despite a fresh CSRF token
+	ErrCSRFRejected = "form submission rejected by OPNSense CSRF protection"
+	// ErrFormInvalid is thrown when OPNSense redisplays a submitted form with input errors
+	ErrFormInvalid = "OPNSense rejected the submitted form"
+	// ErrConcurrentEdit is thrown when a form has been modified by someone else while being edited
+	ErrConcurrentEdit = "OPNSense form has been modified concurrently, refusing to overwrite it"
+	// ErrMaintenance is thrown when OPNSense serves its maintenance/upgrade page instead of the requested one
+	ErrMaintenance = "OPNSense appliance is in maintenance mode (firmware upgrade or reboot in progress), retry later"
+	// ErrUnexpectedPage is thrown when a WebUI page doesn't hold the expected content (e.g. PHP error page)
+	ErrUnexpectedPage = "unexpected OPNSense page content"
+)
+
+// UnexpectedPageSnippetLength is the maximum length of the received page excerpt reported on parsing failures
+const UnexpectedPageSnippetLength = 256
+
+var rxCSRF = regexp.MustCompile(`"X-CSRFToken", "(.*)" \);`)
+
+// FormToken abstracts the CSRF protection values of a WebUI page
+type FormToken struct {
+	Name   string
+	Value  string
+	Header string
+}
+
 // OPNSession abstracts OPNSense connection
 type OPNSession struct {
-	RootURI string
-	Session *requests.Request
-	Cookies []*http.Cookie
-	CSRF    string
+	RootURI   string
+	Session   *requests.Request
+	Cookies   []*http.Cookie
+	CSRF      string
+	TLSConfig *tls.Config
+	Language  string
+	// OptimisticLocking aborts edits of forms modified by someone else in the meantime
+	OptimisticLocking bool
+	// DeferApply leaves DHCP and DNS changes pending until explicitly committed
+	DeferApply bool
+	// LoginPath is the WebUI page, relative to RootURI, authentication goes through
+	LoginPath string
+	user      string
+	password  string
+	// mu serializes exchanges over the shared HTTP session, whose headers
+	// (i.e. CSRF token) are mutated per request
+	mu sync.Mutex
+}
+
+// URL resolves a WebUI page or API endpoint path against OPNSense root URI,
+// preserving its scheme, custom port and path prefix, if any
+func (s *OPNSession) URL(path string) string {
+	root, err := url.Parse(s.RootURI)
+	if err != nil {
+		return strings.TrimRight(s.RootURI, "/") + "/" + strings.TrimLeft(path, "/")
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return strings.TrimRight(s.RootURI, "/") + "/" + strings.TrimLeft(path, "/")
+	}
+	ref.Path = strings.TrimRight(root.Path, "/") + "/" + strings.TrimLeft(ref.Path, "/")
+	return root.ResolveReference(ref).String()
+}
+
+// loginURI returns the WebUI page authentication goes through, OPNSense root page by default
+func (s *OPNSession) loginURI() string {
+	if s.LoginPath == "" || s.LoginPath == "/" {
+		return s.RootURI
+	}
+	return s.URL(s.LoginPath)
 }
 
 // Error throws custom errors
@@ -23,22 +134,39 @@ func (s *OPNSession) Error(err string) error {
 // Authenticate allows authentication to OPNsense main web page
 func (s *OPNSession) Authenticate(rootURI, user, password string) error {
 
-	s.RootURI = rootURI
-	s.Session = requests.Requests()
+	// "https://fw/" and "https://fw" are the same instance
+	s.RootURI = strings.TrimRight(rootURI, "/")
+	s.user = user
+	s.password = password
+	session := requests.Requests()
+	if s.TLSConfig != nil {
+		session.Client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: s.TLSConfig,
+		}
+	}
+	s.mu.Lock()
+	s.Session = session
+	s.mu.Unlock()
 
 	// do a basic query
-	resp, err := s.Session.Get(s.RootURI)
+	loginURI := s.loginURI()
+	resp, err := s.Get(loginURI)
 	if err != nil {
 		return err
 	}
+	if resp.R.StatusCode >= 400 {
+		return fmt.Errorf("login page returned HTTP status %d", resp.R.StatusCode)
+	}
 
 	// fetch up cookies
 	s.Cookies = resp.Cookies()
 
 	// read CSRF token
-	re := regexp.MustCompile(`"X-CSRFToken", "(.*)" \);`)
-	csrf := re.FindSubmatch([]byte(resp.Text()))
-	s.CSRF = string(csrf[1])
+	csrf := rxCSRF.FindSubmatch([]byte(resp.Text()))
+	if len(csrf) < 2 {
+		return fmt.Errorf("unable to find CSRF token on login page (HTTP status %d), is it an OPNSense WebUI?", resp.R.StatusCode)
+	}
 
 	// re-try with authentication
 	data := requests.Datas{
@@ -46,12 +174,37 @@ func (s *OPNSession) Authenticate(rootURI, user, password string) error {
 		"usernamefld": user,
 		"passwordfld": password,
 	}
-	s.Session.Header.Set("X-CSRFToken", s.CSRF)
-	resp, err = s.Session.Post(s.RootURI, data)
+	resp, err = s.Post(loginURI, string(csrf[1]), data)
 	if err != nil {
+		s.CSRF = ""
 		return err
 	}
 
+	// redirects are followed, anything but a success means login failed on OPNSense side
+	if resp.R.StatusCode < 200 || resp.R.StatusCode >= 300 {
+		s.CSRF = ""
+		return fmt.Errorf("login returned HTTP status %d", resp.R.StatusCode)
+	}
+
+	// a successful login lands on the same OPNSense instance, with a session cookie
+	root, err := url.Parse(s.RootURI)
+	if err == nil && resp.R.Request != nil && resp.R.Request.URL.Host != root.Host {
+		s.CSRF = ""
+		return fmt.Errorf("login redirected to unexpected location %s", resp.R.Request.URL)
+	}
+	s.Cookies = resp.Cookies()
+	if len(s.Cookies) == 0 {
+		s.CSRF = ""
+		return fmt.Errorf("login didn't set any session cookie (HTTP status %d)", resp.R.StatusCode)
+	}
+
+	// we're still being served the login page, credentials have been refused
+	doc, err := htmlquery.Parse(strings.NewReader(resp.Text()))
+	if err == nil && s.IsLoginPage(doc) {
+		s.CSRF = ""
+		return fmt.Errorf("login refused (HTTP status %d), check user and password", resp.R.StatusCode)
+	}
+
 	return nil
 }
 
@@ -62,3 +215,655 @@ func (s *OPNSession) IsAuthenticated() error {
 	}
 	return nil
 }
+
+// Reauthenticate establishes a new session with the credentials of the previous one
+func (s *OPNSession) Reauthenticate() error {
+	if s.user == "" {
+		return s.Error(ErrNotAuthenticated)
+	}
+	log.Printf("[DEBUG] OPNSense session rejected, re-authenticating")
+	return s.Authenticate(s.RootURI, s.user, s.password)
+}
+
+// Logout closes the session on OPNSense side, so that it doesn't linger in its
+// bounded session table, and forgets about its CSRF token and cookies
+func (s *OPNSession) Logout() error {
+	if s.Session == nil || s.CSRF == "" {
+		return nil
+	}
+
+	_, err := s.Get(s.URL(LogoutURI))
+
+	// the session is unusable from now on, whatever the outcome
+	s.mu.Lock()
+	s.Session = requests.Requests()
+	s.Cookies = nil
+	s.CSRF = ""
+	s.mu.Unlock()
+
+	return err
+}
+
+// Get retrieves a WebUI page or API endpoint, one exchange at a time over the shared HTTP session
+func (s *OPNSession) Get(uri string) (*requests.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Session.Get(uri)
+}
+
+// Post submits form data along with a CSRF header token, if any, one exchange at a
+// time over the shared HTTP session so that concurrent callers never mix up tokens
+func (s *OPNSession) Post(uri, csrf string, data requests.Datas) (*requests.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if csrf != "" {
+		s.CSRF = csrf
+		s.Session.Header.Set("X-CSRFToken", csrf)
+	}
+	return s.Session.Post(uri, data)
+}
+
+// statusError turns HTTP error statuses of a WebUI page into errors
+func (s *OPNSession) statusError(uri string, resp *requests.Response) error {
+	if resp.R.StatusCode >= 400 {
+		return fmt.Errorf("OPNSense returned HTTP status %d for %s", resp.R.StatusCode, uri)
+	}
+	return nil
+}
+
+// ReadFormToken extracts the CSRF token values from a WebUI page
+func (s *OPNSession) ReadFormToken(page string) *FormToken {
+	t := FormToken{}
+
+	// header token, used by AJAX calls
+	csrf := rxCSRF.FindStringSubmatch(page)
+	if len(csrf) > 1 {
+		t.Header = csrf[1]
+	}
+
+	// form runtime values
+	doc, err := htmlquery.Parse(strings.NewReader(page))
+	if err != nil {
+		return &t
+	}
+	q := fmt.Sprintf(`//div[@class="content-box"]//form//input`)
+	n := htmlquery.FindOne(doc, q)
+	if n != nil {
+		t.Name = htmlquery.SelectAttr(n, "name")
+		t.Value = htmlquery.SelectAttr(n, "value")
+	}
+
+	return &t
+}
+
+// post submits form data using a given CSRF token
+func (s *OPNSession) post(uri string, t *FormToken, data requests.Datas) (*requests.Response, error) {
+	if t.Name != "" {
+		data[t.Name] = t.Value
+	}
+
+	return s.Post(uri, t.Header, data)
+}
+
+// submitForm submits form data with the CSRF token read from the most recent page
+// (or freshly fetched from uri if none is provided) and retries once on CSRF rejection
+func (s *OPNSession) submitForm(uri, page string, data requests.Datas) (*requests.Response, error) {
+
+	// fetch up the page to retrieve form secret values
+	if page == "" {
+		resp, err := s.Get(uri)
+		if err != nil {
+			return nil, err
+		}
+		page = resp.Text()
+	}
+
+	resp, err := s.post(uri, s.ReadFormToken(page), data)
+	if err != nil {
+		return nil, err
+	}
+
+	// some OPNSense versions reject writes of expired sessions with a 403 instead of the login page
+	if resp.R.StatusCode == http.StatusForbidden {
+		err = s.Reauthenticate()
+		if err != nil {
+			return nil, err
+		}
+		resp, err = s.Get(uri)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = s.post(uri, s.ReadFormToken(resp.Text()), data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = s.statusError(uri, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.Contains(resp.Text(), CSRFFailureMarker) {
+		return resp, nil
+	}
+
+	// token was stale, re-fetch it and try again
+	resp, err = s.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	resp, err = s.post(uri, s.ReadFormToken(resp.Text()), data)
+	if err != nil {
+		return nil, err
+	}
+	err = s.statusError(uri, resp)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(resp.Text(), CSRFFailureMarker) {
+		return nil, s.Error(ErrCSRFRejected)
+	}
+
+	return resp, nil
+}
+
+// FormFingerprint computes a hash of the current values of a WebUI page main form,
+// runtime values (i.e. CSRF token) aside
+func (s *OPNSession) FormFingerprint(page string) string {
+	doc, err := htmlquery.Parse(strings.NewReader(page))
+	if err != nil {
+		return ""
+	}
+	data := FormValues(doc)
+	t := s.ReadFormToken(page)
+	if t.Name != "" {
+		delete(data, t.Name)
+	}
+
+	keys := []string{}
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, data[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// EditForm submits an edit form whose current values have been retrieved from page. With optimistic
+// locking enabled, the form is fetched again beforehand and submission is aborted if it changed.
+func (s *OPNSession) EditForm(uri, page string, data requests.Datas) (*requests.Response, error) {
+	if s.OptimisticLocking {
+		resp, err := s.Get(uri)
+		if err != nil {
+			return nil, err
+		}
+		if s.FormFingerprint(resp.Text()) != s.FormFingerprint(page) {
+			return nil, fmt.Errorf("%s: %s", ErrConcurrentEdit, uri)
+		}
+		page = resp.Text()
+	}
+
+	return s.PostForm(uri, page, data)
+}
+
+// IsMaintenancePage checks whether OPNSense served its maintenance/upgrade page.
+// Regular WebUI pages (with a content box) and JSON answers may legitimately hold
+// the markers (e.g. in descriptions) and are never considered as such.
+func (s *OPNSession) IsMaintenancePage(page string) bool {
+	if strings.Contains(page, "content-box") || !strings.HasPrefix(strings.TrimSpace(page), "<") {
+		return false
+	}
+	page = strings.ToLower(page)
+	for _, m := range MaintenanceMarkers {
+		if strings.Contains(page, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsConfigLocked checks whether a WebUI page reports a configuration write collision
+func (s *OPNSession) IsConfigLocked(page string) bool {
+	for _, m := range ConfigLockMarkers {
+		if strings.Contains(page, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// PostForm submits form data with the CSRF token read from the most recent page
+// (or freshly fetched from uri if none is provided). Submission is retried with
+// an exponential backoff, up to ConfigLockMaxAttempts times, as long as OPNSense
+// reports its configuration being written by another process.
+func (s *OPNSession) PostForm(uri, page string, data requests.Datas) (*requests.Response, error) {
+	backoff := ConfigLockBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := s.submitForm(uri, page, data)
+		if err != nil {
+			return nil, err
+		}
+		if !s.IsConfigLocked(resp.Text()) {
+			return resp, nil
+		}
+		if attempt >= ConfigLockMaxAttempts {
+			return nil, fmt.Errorf("%s (gave up after %d attempts)", ErrConfigLocked, attempt)
+		}
+
+		// wait for the other writer to be done, then start over with a fresh token
+		time.Sleep(backoff)
+		backoff *= 2
+		page = ""
+	}
+}
+
+// FormErrors returns the input errors OPNSense reported on a submitted form page, if any
+func (s *OPNSession) FormErrors(page string) error {
+	doc, err := htmlquery.Parse(strings.NewReader(page))
+	if err != nil {
+		return nil
+	}
+
+	msgs := []string{}
+	q := `//div[contains(@class, "alert-danger")]//li`
+	for _, n := range htmlquery.Find(doc, q) {
+		m := strings.TrimSpace(htmlquery.InnerText(n))
+		if m != "" {
+			msgs = append(msgs, m)
+		}
+	}
+
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s", ErrFormInvalid, strings.Join(msgs, "; "))
+}
+
+// NormalizeText cleans up a value extracted from a WebUI page: HTML entities left over are
+// decoded, non-breaking spaces are turned into regular ones and whitespaces are collapsed
+func NormalizeText(text string) string {
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// RowID returns the OPNSense internal ID of a WebUI table row, as carried by its edit
+// link (or action buttons data-id), -1 if none can be found. Table positions don't
+// always match those IDs and can't be relied upon to edit or delete entries.
+// Edit links may be relative ones, only their page name is compared to editURI.
+func RowID(row *html.Node, editURI string) int {
+	for _, a := range htmlquery.Find(row, `.//a[@href]`) {
+		ref, err := url.Parse(htmlquery.SelectAttr(a, "href"))
+		if err != nil || path.Base(ref.Path) != path.Base(editURI) {
+			continue
+		}
+		id, err := strconv.Atoi(ref.Query().Get("id"))
+		if err == nil && id >= 0 {
+			return id
+		}
+	}
+
+	for _, n := range htmlquery.Find(row, `.//*[@data-id]`) {
+		id, err := strconv.Atoi(htmlquery.SelectAttr(n, "data-id"))
+		if err == nil && id >= 0 {
+			return id
+		}
+	}
+
+	return -1
+}
+
+// InputValue returns the value of a named form input of a WebUI page
+func InputValue(doc *html.Node, name string) string {
+	n := htmlquery.FindOne(doc, fmt.Sprintf(`//input[@name="%s"]`, name))
+	if n == nil {
+		n = htmlquery.FindOne(doc, fmt.Sprintf(`//textarea[@name="%s"]`, name))
+		if n == nil {
+			return ""
+		}
+		return htmlquery.InnerText(n)
+	}
+	return htmlquery.SelectAttr(n, "value")
+}
+
+// SelectedValue returns the selected value of a named form select (or radio input) of a WebUI page
+func SelectedValue(doc *html.Node, name string) string {
+	n := htmlquery.FindOne(doc, fmt.Sprintf(`//select[@name="%s"]/option[@selected]`, name))
+	if n == nil {
+		n = htmlquery.FindOne(doc, fmt.Sprintf(`//input[@name="%s"][@type="radio"][@checked]`, name))
+		if n == nil {
+			return ""
+		}
+	}
+	return htmlquery.SelectAttr(n, "value")
+}
+
+// SelectedValues returns all selected values of a named form multi-select of a WebUI page
+func SelectedValues(doc *html.Node, name string) []string {
+	values := []string{}
+	for _, n := range htmlquery.Find(doc, fmt.Sprintf(`//select[@name="%s"]/option[@selected]`, name)) {
+		values = append(values, htmlquery.SelectAttr(n, "value"))
+	}
+	return values
+}
+
+// IsChecked tells whether a named form checkbox of a WebUI page is checked
+func IsChecked(doc *html.Node, name string) bool {
+	return htmlquery.FindOne(doc, fmt.Sprintf(`//input[@name="%s"][@checked]`, name)) != nil
+}
+
+// UnexpectedPage reports that an expected WebUI page element is missing, along with an excerpt of
+// what was actually received. Only visible text is kept, so that form values (passwords) never leak.
+func (s *OPNSession) UnexpectedPage(doc *html.Node, what string) error {
+	snippet := ""
+	if doc != nil {
+		body := htmlquery.FindOne(doc, "//body")
+		if body == nil {
+			body = doc
+		}
+		for _, n := range htmlquery.Find(body, `//script|//style`) {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+		snippet = strings.Join(strings.Fields(htmlquery.InnerText(body)), " ")
+	}
+	if len(snippet) > UnexpectedPageSnippetLength {
+		snippet = snippet[:UnexpectedPageSnippetLength] + "..."
+	}
+	return fmt.Errorf("%s: %s not found, received %q", ErrUnexpectedPage, what, snippet)
+}
+
+// FormValues returns the current values of all inputs of a WebUI page main form, as they would be
+// submitted by a browser (i.e. unchecked checkboxes and buttons are left out)
+func FormValues(doc *html.Node) requests.Datas {
+	data := requests.Datas{}
+
+	form := htmlquery.FindOne(doc, `//div[@class="content-box"]//form`)
+	if form == nil {
+		return data
+	}
+
+	for _, n := range htmlquery.Find(form, `//input[@name]`) {
+		name := htmlquery.SelectAttr(n, "name")
+		switch strings.ToLower(htmlquery.SelectAttr(n, "type")) {
+		case "submit", "button", "reset", "image", "file":
+			continue
+		case "checkbox", "radio":
+			if !htmlquery.ExistsAttr(n, "checked") {
+				continue
+			}
+			value := htmlquery.SelectAttr(n, "value")
+			if value == "" {
+				value = "on"
+			}
+			data[name] = value
+		default:
+			data[name] = htmlquery.SelectAttr(n, "value")
+		}
+	}
+
+	for _, n := range htmlquery.Find(form, `//textarea[@name]`) {
+		data[htmlquery.SelectAttr(n, "name")] = htmlquery.InnerText(n)
+	}
+
+	for _, n := range htmlquery.Find(form, `//select[@name]`) {
+		o := htmlquery.FindOne(n, `./option[@selected]`)
+		if o == nil {
+			continue
+		}
+		data[htmlquery.SelectAttr(n, "name")] = htmlquery.SelectAttr(o, "value")
+	}
+
+	return data
+}
+
+// ApplyResult tells whether a WebUI page had pending changes, and whether they've been applied
+type ApplyResult struct {
+	Pending bool
+	Applied bool
+}
+
+// HasPendingChanges checks whether a WebUI page invites to apply pending changes
+func HasPendingChanges(page string) bool {
+	doc, err := htmlquery.Parse(strings.NewReader(page))
+	if err != nil {
+		return false
+	}
+	return htmlquery.FindOne(doc, `//form//*[@name="apply"]`) != nil
+}
+
+// ApplyChanges submits a WebUI page apply form, reporting whether changes were pending and got applied
+func (s *OPNSession) ApplyChanges(uri, page string, data requests.Datas) (*ApplyResult, error) {
+
+	// find out the most recent state of the page
+	if page == "" {
+		resp, err := s.Get(uri)
+		if err != nil {
+			return nil, err
+		}
+		page = resp.Text()
+	}
+	res := ApplyResult{
+		Pending: HasPendingChanges(page),
+	}
+
+	resp, err := s.PostForm(uri, page, data)
+	if err != nil {
+		return &res, err
+	}
+	res.Applied = res.Pending && !HasPendingChanges(resp.Text())
+	if !res.Pending {
+		log.Printf("[DEBUG] OPNSense had no pending changes to apply on %s", uri)
+	}
+
+	return &res, nil
+}
+
+// IsLoginPage checks whether a WebUI page is the login one
+func (s *OPNSession) IsLoginPage(doc *html.Node) bool {
+	return htmlquery.FindOne(doc, `//form//input[@name="usernamefld"]`) != nil &&
+		htmlquery.FindOne(doc, `//form//input[@name="passwordfld"]`) != nil
+}
+
+// GetPage retrieves and parses a WebUI page, ensuring we've not been redirected to login page
+func (s *OPNSession) GetPage(uri string) (*html.Node, error) {
+
+	// read out the page
+	resp, err := s.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	// expired sessions may get a 403 instead of the login page
+	if resp.R.StatusCode == http.StatusForbidden {
+		err = s.Reauthenticate()
+		if err != nil {
+			return nil, err
+		}
+		resp, err = s.Get(uri)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// upgrades serve a maintenance page on every URL, never parse it as empty content
+	if s.IsMaintenancePage(resp.Text()) {
+		return nil, s.Error(ErrMaintenance)
+	}
+
+	err = s.statusError(uri, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// get HTML
+	page := strings.NewReader(resp.Text())
+	doc, err := htmlquery.Parse(page)
+	if err != nil {
+		return nil, err
+	}
+
+	// unauthenticated sessions get redirected to login page
+	if s.IsLoginPage(doc) {
+		return nil, s.Error(ErrNotAuthenticated)
+	}
+
+	return doc, nil
+}
+
+// APIResult abstracts OPNSense MVC API write operations outcome
+type APIResult struct {
+	Result      string            `json:"result"`
+	Status      string            `json:"status"`
+	UUID        string            `json:"uuid"`
+	Validations map[string]string `json:"validations"`
+}
+
+// APIOption abstracts an OPNSense MVC API option field value
+type APIOption struct {
+	Value    string `json:"value"`
+	Selected int    `json:"selected"`
+}
+
+// SelectedOption returns the key of the selected option of an OPNSense MVC API option field
+func SelectedOption(opts map[string]APIOption) string {
+	for k, o := range opts {
+		if o.Selected == 1 {
+			return k
+		}
+	}
+	return ""
+}
+
+// SelectedOptions returns the sorted keys of all selected options of an OPNSense MVC API multiple option field
+func SelectedOptions(opts map[string]APIOption) []string {
+	keys := []string{}
+	for k, o := range opts {
+		if o.Selected == 1 && k != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// APIError returns an error if an OPNSense MVC API write operation failed
+func (s *OPNSession) APIError(r *APIResult) error {
+	if len(r.Validations) > 0 {
+		msgs := []string{}
+		for k, v := range r.Validations {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", k, v))
+		}
+		sort.Strings(msgs)
+		return fmt.Errorf("%s: %s", ErrFormInvalid, strings.Join(msgs, "; "))
+	}
+	if r.Result == "failed" || (r.Status != "" && strings.ToLower(r.Status) != "ok") {
+		return fmt.Errorf("OPNSense API call failed: %s%s", r.Result, r.Status)
+	}
+	return nil
+}
+
+// decodeJSON decodes an OPNSense MVC API answer
+func (s *OPNSession) decodeJSON(resp *requests.Response, v interface{}) error {
+	if resp.R.StatusCode == http.StatusUnauthorized || resp.R.StatusCode == http.StatusForbidden {
+		return s.Error(ErrNotAuthenticated)
+	}
+	if s.IsMaintenancePage(resp.Text()) {
+		return s.Error(ErrMaintenance)
+	}
+	if resp.R.StatusCode >= 400 {
+		return fmt.Errorf("OPNSense API call failed with HTTP status %d", resp.R.StatusCode)
+	}
+	if v == nil {
+		return nil
+	}
+	err := resp.Json(v)
+	if err != nil {
+		return fmt.Errorf("unable to decode OPNSense API answer: %v", err)
+	}
+	return nil
+}
+
+// GetJSON queries an OPNSense MVC API endpoint and decodes its JSON answer
+func (s *OPNSession) GetJSON(path string, v interface{}) error {
+	resp, err := s.Get(s.URL(path))
+	if err != nil {
+		return err
+	}
+	return s.decodeJSON(resp, v)
+}
+
+// PostJSON submits a JSON payload to an OPNSense MVC API endpoint and decodes its JSON answer
+func (s *OPNSession) PostJSON(path string, body, v interface{}) error {
+	if body == nil {
+		body = map[string]string{}
+	}
+	s.mu.Lock()
+	resp, err := s.Session.PostJson(s.URL(path), body)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.decodeJSON(resp, v)
+}
+
+// GetAllPages retrieves a WebUI page and all the subsequent ones its pagination links to
+func (s *OPNSession) GetAllPages(uri string) ([]*html.Node, error) {
+
+	docs := []*html.Node{}
+	visited := map[string]bool{}
+	tables := map[string]bool{}
+	pending := []string{uri}
+
+	for len(pending) > 0 {
+		u := pending[0]
+		pending = pending[1:]
+		if visited[u] {
+			continue
+		}
+		visited[u] = true
+
+		// read out the page
+		doc, err := s.GetPage(u)
+		if err != nil {
+			return docs, err
+		}
+
+		// first/previous links may lead to an already read page under another URI
+		table := ""
+		n := htmlquery.FindOne(doc, `//table[@class="table table-striped"]`)
+		if n != nil {
+			table = htmlquery.OutputHTML(n, true)
+		}
+		if tables[table] {
+			continue
+		}
+		tables[table] = true
+		docs = append(docs, doc)
+
+		// follow up pagination links, if any
+		base, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+		links := htmlquery.Find(doc, `//ul[contains(@class, "pagination")]//a[@href]`)
+		for _, l := range links {
+			ref, err := url.Parse(htmlquery.SelectAttr(l, "href"))
+			if err != nil || ref.String() == "" || strings.HasPrefix(ref.String(), "#") {
+				continue
+			}
+			next := base.ResolveReference(ref).String()
+			if !visited[next] {
+				pending = append(pending, next)
+			}
+		}
+	}
+
+	return docs, nil
+}
@@ -0,0 +1,574 @@
+package opnsense
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+)
+
+// newTestSession returns a session to an OPNSense fake served by h, as if already authenticated
+func newTestSession(t testing.TB, h http.Handler) *OPNSession {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return &OPNSession{
+		RootURI: srv.URL,
+		Session: requests.Requests(),
+		CSRF:    "test",
+	}
+}
+
+// fixtures serves WebUI pages out of testdata files, keyed by request URI (path and query)
+func fixtures(t testing.TB, pages map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+	})
+}
+
+// writeJSON answers an OPNSense MVC API call
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// formPage renders a WebUI form page protected by a given CSRF token
+func formPage(token string) string {
+	return fmt.Sprintf(`<html><head><script>$.ajaxSetup({ beforeSend: function(xhr) { xhr.setRequestHeader("X-CSRFToken", "%s" ); } });</script></head>
+<body><div class="content-box"><form method="post"><input type="hidden" name="csrf_%s" value="%s"/><input name="descr" value=""/></form></div></body></html>`, token, token, token)
+}
+
+func TestPostFormRetriesRotatedCSRF(t *testing.T) {
+	token := "t1"
+	posted := []string{}
+	opn := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, formPage(token))
+			return
+		}
+		r.ParseForm()
+		posted = append(posted, r.Header.Get("X-CSRFToken"))
+		if r.Form.Get("csrf_"+token) != token {
+			fmt.Fprint(w, CSRFFailureMarker)
+			return
+		}
+		fmt.Fprint(w, "saved")
+	}))
+
+	// the token rotates between the page being read and the form being posted
+	page := formPage(token)
+	token = "t2"
+
+	resp, err := opn.PostForm(opn.URL("/form.php"), page, requests.Datas{"descr": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "saved" {
+		t.Errorf("unexpected answer %q", resp.Text())
+	}
+	if !reflect.DeepEqual(posted, []string{"t1", "t2"}) {
+		t.Errorf("posted tokens %v, expected a retry with the rotated one", posted)
+	}
+}
+
+func TestPostFormGivesUpOnCSRFRejection(t *testing.T) {
+	opn := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, formPage("t1"))
+			return
+		}
+		fmt.Fprint(w, CSRFFailureMarker)
+	}))
+
+	_, err := opn.PostForm(opn.URL("/form.php"), "", requests.Datas{"descr": "test"})
+	if err == nil || err.Error() != ErrCSRFRejected {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+// loginPage renders the WebUI login page, protected by a given CSRF token
+func loginPage(token string) string {
+	return fmt.Sprintf(`<html><head><script>$.ajaxSetup({ beforeSend: function(xhr) { xhr.setRequestHeader("X-CSRFToken", "%s" ); } });</script></head>
+<body><main class="login-modal-container"><form class="clearfix" id="iform" name="iform" method="post" autocomplete="off">
+<input type="hidden" name="csrf" value="%s"/>
+<input id="usernamefld" type="text" name="usernamefld" class="form-control user" tabindex="1" autofocus="autofocus" autocapitalize="off" autocorrect="off" />
+<input id="passwordfld" type="password" name="passwordfld" class="form-control pwd" tabindex="2" />
+<button type="submit" name="login" value="1" class="btn btn-primary pull-right">Login</button>
+</form></main></body></html>`, token, token)
+}
+
+func TestGetPageDetectsLoginRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, loginPage("t1"))
+	})
+	mux.HandleFunc(DHCPServiceURI, func(w http.ResponseWriter, r *http.Request) {
+		// expired sessions are redirected to the login page
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	dhcp := DHCPSession{
+		OPN: newTestSession(t, mux),
+	}
+
+	// the login page must not be mistaken for an interface without mappings
+	_, err := dhcp.GetAllInterfaceStaticMappings("lan")
+	if err == nil || err.Error() != ErrNotAuthenticated {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := map[string]string{
+		"printer":                  "printer",
+		"  nas  \n":                "nas",
+		"Office&nbsp;printer":      "Office printer",
+		"Storage &amp; backups":    "Storage & backups",
+		"web\t\tserver &#32; farm": "web server farm",
+		"":                         "",
+	}
+	for text, expected := range tests {
+		if res := NormalizeText(text); res != expected {
+			t.Errorf("%q normalized as %q, expected %q", text, res, expected)
+		}
+	}
+}
+
+func TestURLPreservesCustomPort(t *testing.T) {
+	tests := []struct {
+		root     string
+		path     string
+		expected string
+	}{
+		{"https://fw.acme.local:8443", DHCPServiceURI + "?if=lan", "https://fw.acme.local:8443/services_dhcp.php?if=lan"},
+		{"https://fw.acme.local:8443/", "api/core/firmware/status", "https://fw.acme.local:8443/api/core/firmware/status"},
+		{"http://10.0.0.1:8080/opnsense", DNSServiceURI, "http://10.0.0.1:8080/opnsense/services_unbound_overrides.php"},
+		{"https://[2001:db8::1]:4443", LogoutURI, "https://[2001:db8::1]:4443" + LogoutURI},
+		{"https://fw.acme.local", DNSServiceEditURI + "?id=3", "https://fw.acme.local/services_unbound_host_edit.php?id=3"},
+	}
+	for _, tt := range tests {
+		s := OPNSession{
+			RootURI: tt.root,
+		}
+		if u := s.URL(tt.path); u != tt.expected {
+			t.Errorf("%s resolved against %s as %s, expected %s", tt.path, tt.root, u, tt.expected)
+		}
+	}
+}
+
+// loginWebUI fakes OPNSense WebUI authentication in front of other pages, answering
+// requests of unauthenticated sessions with a 403 as some OPNSense versions do
+type loginWebUI struct {
+	mu       sync.Mutex
+	user     string
+	password string
+	// path is the login page path, OPNSense root page if unset
+	path string
+	// session is the cookie value of the authenticated session, if any
+	session string
+	logins  int
+	logouts int
+	next    http.Handler
+}
+
+func newLoginWebUI(user, password string, next http.Handler) *loginWebUI {
+	return &loginWebUI{
+		user:     user,
+		password: password,
+		path:     "/",
+		next:     next,
+	}
+}
+
+// expire drops the authenticated session, as OPNSense does once it timed out
+func (f *loginWebUI) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session = ""
+}
+
+func (f *loginWebUI) authenticated(r *http.Request) bool {
+	c, err := r.Cookie("PHPSESSID")
+	return err == nil && f.session != "" && c.Value == f.session
+}
+
+func (f *loginWebUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	switch {
+	case r.URL.Path == f.path && r.Method == http.MethodPost:
+		r.ParseForm()
+		if r.Form.Get("usernamefld") == f.user && r.Form.Get("passwordfld") == f.password {
+			f.logins++
+			f.session = fmt.Sprintf("session%d", f.logins)
+			http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: f.session})
+			f.mu.Unlock()
+			fmt.Fprint(w, formPage("dashboard"))
+			return
+		}
+		f.mu.Unlock()
+		fmt.Fprint(w, loginPage("login"))
+		return
+	case r.URL.Path == f.path && !f.authenticated(r):
+		http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "anonymous"})
+		f.mu.Unlock()
+		fmt.Fprint(w, loginPage("login"))
+		return
+	case strings.HasPrefix(r.URL.RequestURI(), LogoutURI):
+		f.logouts++
+		f.session = ""
+		f.mu.Unlock()
+		fmt.Fprint(w, loginPage("login"))
+		return
+	case !f.authenticated(r):
+		f.mu.Unlock()
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	f.mu.Unlock()
+	f.next.ServeHTTP(w, r)
+}
+
+func TestForbiddenPagesReauthenticate(t *testing.T) {
+	dhcp := newDHCPWebUI("lan", StaticMapping{MAC: "00:11:22:33:44:01", IP: "192.168.1.10", Hostname: "printer"})
+	f := newLoginWebUI("root", "secret", dhcp)
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	opn := &OPNSession{}
+	err := opn.Authenticate(srv.URL, "root", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := DHCPSession{
+		OPN: opn,
+	}
+
+	// reads are retried once logged in again
+	f.expire()
+	entries, err := s.GetAllInterfaceStaticMappings("lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || f.logins != 2 {
+		t.Errorf("read %d mappings after %d logins, expected 1 after 2", len(entries), f.logins)
+	}
+
+	// and so are writes
+	f.expire()
+	_, err = opn.PostForm(opn.URL(DHCPServiceURI+"?if=lan"), "", requests.Datas{"act": "del", "id": "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dhcp.mappings) != 0 || f.logins != 3 {
+		t.Errorf("%d mappings left after %d logins, expected none after 3", len(dhcp.mappings), f.logins)
+	}
+}
+
+func TestForbiddenPagesWithoutCredentials(t *testing.T) {
+	opn := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}))
+
+	// sessions which never authenticated can't do so again
+	_, err := opn.GetPage(opn.URL(DHCPServiceURI + "?if=lan"))
+	if err == nil || err.Error() != ErrNotAuthenticated {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestHTTPErrorStatusesFail(t *testing.T) {
+	opn := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html><body><div class=\"content-box\">Internal Server Error</div></body></html>", http.StatusInternalServerError)
+	}))
+	dns := DNSSession{
+		OPN: opn,
+	}
+
+	// error pages must not be mistaken for empty tables
+	_, err := dns.GetAllHostEntries()
+	if err == nil || !strings.Contains(err.Error(), "HTTP status 500") {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, err = opn.PostForm(opn.URL(DNSServiceURI), "", requests.Datas{"act": "del", "id": "0"})
+	if err == nil || !strings.Contains(err.Error(), "HTTP status 500") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestMaintenancePage(t *testing.T) {
+	opn := newTestSession(t, fixtures(t, map[string]string{
+		DHCPServiceURI + "?if=lan": "maintenance.html",
+		DNSServiceURI:              "maintenance.html",
+	}))
+
+	// the upgrade page is served on every URL, never as an empty table
+	_, err := (&DHCPSession{OPN: opn}).GetAllInterfaceStaticMappings("lan")
+	if err == nil || err.Error() != ErrMaintenance {
+		t.Errorf("unexpected error reading static mappings: %v", err)
+	}
+	_, err = (&DNSSession{OPN: opn}).GetAllHostEntries()
+	if err == nil || err.Error() != ErrMaintenance {
+		t.Errorf("unexpected error reading host overrides: %v", err)
+	}
+
+	// regular pages merely mentioning it aren't
+	data, err := os.ReadFile(filepath.Join("testdata", "unbound_overrides_page1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := strings.Replace(string(data), "web server", "maintenance mode web server", 1)
+	if opn.IsMaintenancePage(page) {
+		t.Error("host overrides page has been taken for the maintenance one")
+	}
+	if opn.IsMaintenancePage(`{"status":"maintenance mode"}`) {
+		t.Error("API answer has been taken for the maintenance page")
+	}
+}
+
+func TestRootURITrailingSlash(t *testing.T) {
+	paths := []string{}
+	f := newLoginWebUI("root", "secret", newDHCPWebUI("lan"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		f.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	// "https://fw/" and "https://fw" are the same instance
+	opn := &OPNSession{}
+	err := opn.Authenticate(srv.URL+"/", "root", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opn.RootURI != srv.URL {
+		t.Errorf("root URI %s kept its trailing slash", opn.RootURI)
+	}
+	_, err = (&DHCPSession{OPN: opn}).GetAllInterfaceStaticMappings("lan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		if strings.Contains(p, "//") {
+			t.Errorf("requested %s", p)
+		}
+	}
+
+	// root URIs which can't be parsed are joined alike
+	opn.RootURI = "https://fw.acme.local/%zz/"
+	if u := opn.URL(DHCPServiceURI); u != "https://fw.acme.local/%zz/services_dhcp.php" {
+		t.Errorf("got URL %s", u)
+	}
+}
+
+func TestRowID(t *testing.T) {
+	tests := map[string]int{
+		`<a href="services_unbound_host_edit.php?id=4">edit</a>`:                                 4,
+		`<a href="/services_unbound_host_edit.php?id=5">edit</a>`:                                5,
+		`<a href="https://fw.acme.local/ui/services_unbound_host_edit.php?id=6">edit</a>`:        6,
+		`<a href="services_unbound_host_edit.php">add</a><a data-id="7" class="act_delete"></a>`: 7,
+		`<a href="services_unbound_overrides.php?id=8">other</a>`:                                -1,
+		`<a href="services_unbound_host_edit.php?id=-1">edit</a>`:                                -1,
+		`<span>no action</span>`: -1,
+	}
+	for cell, expected := range tests {
+		doc, err := htmlquery.Parse(strings.NewReader("<table><tr><td>www</td><td>" + cell + "</td></tr></table>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		row := htmlquery.FindOne(doc, "//tr")
+		if id := RowID(row, DNSServiceEditURI); id != expected {
+			t.Errorf("%s: got ID %d, expected %d", cell, id, expected)
+		}
+	}
+}
+
+func TestConcurrentFormsKeepTheirCSRFTokens(t *testing.T) {
+	var mu sync.Mutex
+	mismatches := 0
+	opn := newTestSession(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// every page has its own token
+		token := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".php")
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, formPage(token))
+			return
+		}
+		if r.Header.Get("X-CSRFToken") != token {
+			mu.Lock()
+			mismatches++
+			mu.Unlock()
+		}
+		fmt.Fprint(w, "saved")
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			uri := opn.URL(fmt.Sprintf("/form%d.php", i%4))
+			_, err := opn.PostForm(uri, "", requests.Datas{"descr": "test"})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if mismatches != 0 {
+		t.Errorf("%d forms have been posted with another form token", mismatches)
+	}
+}
+
+func TestAuthenticateFailures(t *testing.T) {
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "SSO", Value: "portal"})
+		fmt.Fprint(w, formPage("portal"))
+	}))
+	t.Cleanup(elsewhere.Close)
+
+	// loginFails serves the login page, then answers credentials with the given handler
+	loginFails := func(h http.HandlerFunc) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == "/" {
+				http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "anonymous"})
+				fmt.Fprint(w, loginPage("login"))
+				return
+			}
+			h(w, r)
+		})
+	}
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		err     string
+	}{
+		{
+			name:    "wrong password",
+			handler: newLoginWebUI("root", "other", nil),
+			err:     "login refused",
+		},
+		{
+			name: "server error",
+			handler: loginFails(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}),
+			err: "login returned HTTP status 500",
+		},
+		{
+			name: "redirected elsewhere",
+			handler: loginFails(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, elsewhere.URL+"/sso", http.StatusFound)
+			}),
+			err: "login redirected to unexpected location",
+		},
+		{
+			name:    "not an OPNSense WebUI",
+			handler: http.NotFoundHandler(),
+			err:     "login page returned HTTP status 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			t.Cleanup(srv.Close)
+
+			opn := &OPNSession{}
+			err := opn.Authenticate(srv.URL, "root", "secret")
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, expected %q", err, tt.err)
+			}
+			if opn.IsAuthenticated() == nil {
+				t.Error("failed login left the session authenticated")
+			}
+		})
+	}
+}
+
+func TestTeardownLogsOut(t *testing.T) {
+	f := newLoginWebUI("root", "secret", newDHCPWebUI("lan"))
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	opn := &OPNSession{}
+	err := opn.Authenticate(srv.URL, "root", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions.Lock()
+	sessions.opened = append(sessions.opened, opn)
+	sessions.Unlock()
+
+	// sessions are closed on OPNSense side, and forgotten about
+	Teardown()
+	if f.logouts != 1 || f.session != "" {
+		t.Errorf("%d logouts, OPNSense session %q left open", f.logouts, f.session)
+	}
+	if opn.IsAuthenticated() == nil || len(opn.Cookies) != 0 {
+		t.Error("session is still usable after logout")
+	}
+
+	// closed sessions aren't logged out twice
+	err = opn.Logout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Teardown()
+	if f.logouts != 1 {
+		t.Errorf("%d logouts, expected a single one", f.logouts)
+	}
+}
+
+func TestAuthenticateThroughLoginPath(t *testing.T) {
+	elsewhere := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body>Welcome to acme.com</body></html>")
+	}))
+	t.Cleanup(elsewhere.Close)
+
+	// the root page is served by a reverse proxy, redirecting elsewhere
+	f := newLoginWebUI("root", "secret", newDHCPWebUI("lan"))
+	f.path = "/index.php"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, elsewhere.URL, http.StatusFound)
+			return
+		}
+		f.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	opn := &OPNSession{}
+	err := opn.Authenticate(srv.URL, "root", "secret")
+	if err == nil {
+		t.Error("login succeeded through the root page")
+	}
+
+	opn = &OPNSession{
+		LoginPath: "/index.php",
+	}
+	err = opn.Authenticate(srv.URL, "root", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (&DHCPSession{OPN: opn}).GetAllInterfaceStaticMappings("lan")
+	if err != nil {
+		t.Error(err)
+	}
+}
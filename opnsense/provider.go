@@ -1,8 +1,18 @@
 package opnsense
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
@@ -11,11 +21,52 @@ import (
 
 // ProviderConfiguration struct for opnsense-provider
 type ProviderConfiguration struct {
-	OPN   *OPNSession
-	DHCP  *DHCPSession
-	DNS   *DNSSession
-	Mutex *sync.Mutex
-	Cond  *sync.Cond
+	OPN           *OPNSession
+	DHCP          *DHCPSession
+	DNS           *DNSSession
+	User          *UserSession
+	UnboundACL    *UnboundACLSession
+	TrafficShaper *TrafficShaperSession
+	Firewall      *FirewallSession
+	VIP           *VIPSession
+	OpenVPN       *OpenVPNSession
+	Syslog        *SyslogSession
+	Monit         *MonitSession
+	Interface     *InterfaceSession
+	Tunable       *TunableSession
+	HAProxy       *HAProxySession
+	Trust         *TrustSession
+	Gateway       *GatewaySession
+	NTP           *NTPSession
+	Semaphore     *Semaphore
+	Cond          *sync.Cond
+	// SkipPostCreateRead populates created resources state from their known values,
+	// rather than reading them out again
+	SkipPostCreateRead bool
+}
+
+// rxLoginPath matches absolute WebUI paths, with an optional query string
+var rxLoginPath = regexp.MustCompile(`^/[^\s?#]*(\?[^\s#]*)?$`)
+
+// sessions tracks the OPNSense sessions established by configured providers,
+// so that they can be closed on teardown
+var sessions struct {
+	sync.Mutex
+	opened []*OPNSession
+}
+
+// Teardown logs out of every OPNSense session established by configured providers
+func Teardown() {
+	sessions.Lock()
+	defer sessions.Unlock()
+
+	for _, s := range sessions.opened {
+		err := s.Logout()
+		if err != nil {
+			log.Printf("[WARN] Unable to log out of OPNSense at %s: %v", s.RootURI, err)
+		}
+	}
+	sessions.opened = nil
 }
 
 // Provider libvirt
@@ -38,52 +89,347 @@ func Provider() terraform.ResourceProvider {
 			},
 			"password": {
 				Type:         schema.TypeString,
-				Required:     true,
+				Optional:     true,
+				Sensitive:    true,
 				DefaultFunc:  schema.EnvDefaultFunc("OPNSENSE_USER_PASSWORD", nil),
 				ValidateFunc: validation.All(validation.StringIsNotEmpty),
 				Description:  "OPNsense platform user password",
 			},
+			"password_file": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("OPNSENSE_USER_PASSWORD_FILE", nil),
+				ValidateFunc: validation.All(validation.StringIsNotEmpty),
+				Description:  "Path to a file containing OPNsense platform user password",
+			},
+			"password_command": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("OPNSENSE_USER_PASSWORD_COMMAND", nil),
+				ValidateFunc: validation.All(validation.StringIsNotEmpty),
+				Description:  "Shell command whose standard output is OPNsense platform user password",
+			},
+			"login_path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "/",
+				ValidateFunc: validation.StringMatch(rxLoginPath, "must be an absolute path, e.g. /index.php"),
+				Description:  "OPNsense platform WebUI page, relative to uri, to log in through",
+			},
+			"ca_cert_pem": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("OPNSENSE_CA_CERT_PEM", nil),
+				Description: "PEM-encoded CA bundle used to verify OPNsense platform TLS certificate",
+			},
+			"client_cert_pem": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("OPNSENSE_CLIENT_CERT_PEM", nil),
+				Description: "PEM-encoded client certificate presented to OPNsense platform, for mutual TLS",
+			},
+			"client_key_pem": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Sensitive:   true,
+				DefaultFunc: schema.EnvDefaultFunc("OPNSENSE_CLIENT_KEY_PEM", nil),
+				Description: "PEM-encoded private key of the client certificate",
+			},
+			"dns_apply_timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      int(DNSApplyTimeout.Seconds()),
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Time, in seconds, given to Unbound to serve applied DNS host overrides",
+			},
+			"dns_backend": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      DNSBackendUnbound,
+				ValidateFunc: validation.StringInSlice(DNSBackends, false),
+				Description:  "OPNsense DNS service host overrides are managed with (unbound or dnsmasq)",
+			},
+			"optimistic_locking": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Refuse to overwrite OPNsense forms modified by someone else while being edited",
+			},
+			"debug_diagnostics": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Log detailed diagnostics (headers, rows, HTML) whenever OPNsense pages can't be parsed",
+			},
+			"defer_apply": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Leave DHCP and DNS changes pending until an opnsense_apply resource applies them",
+			},
+			"skip_post_create_read": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Trust created DHCP static mappings and DNS host overrides, rather than reading them out again",
+			},
+			"max_concurrent_ops": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      1,
+				ValidateFunc: validation.IntAtLeast(1),
+				Description:  "Maximum number of resource operations run at once against OPNsense",
+			},
+			"ui_language": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("OPNSENSE_UI_LANGUAGE", DefaultUILanguage),
+				ValidateFunc: validation.StringInSlice(SupportedUILanguages(), false),
+				Description:  "OPNsense platform WebUI language, as set in System: Settings: General",
+			},
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
-			"opnsense_dhcp_static_map":   resourceOpnDHCPStaticMap(),
-			"opnsense_dns_host_override": resourceOpnDNSHostOverride(),
+			"opnsense_dhcp_static_map":         resourceOpnDHCPStaticMap(),
+			"opnsense_dns_host_override":       resourceOpnDNSHostOverride(),
+			"opnsense_dns_host_overrides":      resourceOpnDNSHostOverrides(),
+			"opnsense_user":                    resourceOpnUser(),
+			"opnsense_unbound_access_list":     resourceOpnUnboundAccessList(),
+			"opnsense_unbound_blocklist":       resourceOpnUnboundBlocklist(),
+			"opnsense_unbound_forward":         resourceOpnUnboundForward(),
+			"opnsense_unbound_general":         resourceOpnUnboundGeneral(),
+			"opnsense_traffic_shaper_pipe":     resourceOpnTrafficShaperPipe(),
+			"opnsense_firewall_alias":          resourceOpnFirewallAlias(),
+			"opnsense_firewall_category":       resourceOpnFirewallCategory(),
+			"opnsense_firewall_rule":           resourceOpnFirewallRule(),
+			"opnsense_firewall_schedule":       resourceOpnFirewallSchedule(),
+			"opnsense_openvpn_client_override": resourceOpnOpenVPNClientOverride(),
+			"opnsense_gateway_group":           resourceOpnGatewayGroup(),
+			"opnsense_haproxy_backend":         resourceOpnHAProxyBackend(),
+			"opnsense_haproxy_frontend":        resourceOpnHAProxyFrontend(),
+			"opnsense_apply":                   resourceOpnApply(),
+			"opnsense_interface":               resourceOpnInterface(),
+			"opnsense_interface_group":         resourceOpnInterfaceGroup(),
+			"opnsense_interface_vip":           resourceOpnInterfaceVIP(),
+			"opnsense_syslog_target":           resourceOpnSyslogTarget(),
+			"opnsense_monit_service":           resourceOpnMonitService(),
+			"opnsense_ntp":                     resourceOpnNTP(),
+			"opnsense_system_tunable":          resourceOpnSystemTunable(),
+		},
+
+		DataSourcesMap: map[string]*schema.Resource{
+			"opnsense_dhcp_status":     dataSourceOpnDHCPStatus(),
+			"opnsense_dhcp_static_map": dataSourceOpnDHCPStaticMap(),
+			"opnsense_dhcpv6_prefix":   dataSourceOpnDHCPv6Prefix(),
+			"opnsense_firewall_rule":   dataSourceOpnFirewallRule(),
+			"opnsense_unbound_stats":   dataSourceOpnUnboundStats(),
+			"opnsense_certificate":     dataSourceOpnCertificate(),
 		},
 
 		ConfigureFunc: providerConfigure,
 	}
 }
 
+// providerPassword retrieves user password from configuration, file or external command
+func providerPassword(d *schema.ResourceData) (string, error) {
+	password := d.Get("password").(string)
+	file := d.Get("password_file").(string)
+	command := d.Get("password_command").(string)
+
+	set := 0
+	for _, v := range []string{password, file, command} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", fmt.Errorf("Only one of password, password_file or password_command can be set")
+	}
+
+	if file != "" {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("Unable to read password_file: %v", err)
+		}
+		password = strings.TrimRight(string(content), "\r\n")
+	}
+
+	if command != "" {
+		// don't forward command output in errors, it may contain the secret
+		out, err := exec.Command("sh", "-c", command).Output()
+		if err != nil {
+			return "", fmt.Errorf("Unable to run password_command: %v", err)
+		}
+		password = strings.TrimRight(string(out), "\r\n")
+	}
+
+	return password, nil
+}
+
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
 
 	// check for mandatory requirements
 	uri := d.Get("uri").(string)
 	user := d.Get("user").(string)
-	password := d.Get("password").(string)
+	password, err := providerPassword(d)
+	if err != nil {
+		return nil, err
+	}
 
 	if uri == "" || user == "" || password == "" {
 		return nil, fmt.Errorf("The opnsense provider needs proper initialization parameters")
 	}
 
-	var mut sync.Mutex
-	var opn OPNSession
+	var sem = NewSemaphore(d.Get("max_concurrent_ops").(int))
+	var opn = OPNSession{
+		Language:          d.Get("ui_language").(string),
+		OptimisticLocking: d.Get("optimistic_locking").(bool),
+		DeferApply:        d.Get("defer_apply").(bool),
+		LoginPath:         d.Get("login_path").(string),
+	}
 	var dhcp = DHCPSession{
-		OPN: &opn,
+		OPN:   &opn,
+		Debug: d.Get("debug_diagnostics").(bool),
 	}
 	var dns = DNSSession{
+		OPN:          &opn,
+		ApplyTimeout: time.Duration(d.Get("dns_apply_timeout").(int)) * time.Second,
+		Backend:      d.Get("dns_backend").(string),
+	}
+	var users = UserSession{
+		OPN: &opn,
+	}
+	var acl = UnboundACLSession{
+		OPN: &opn,
+	}
+	var shaper = TrafficShaperSession{
+		OPN: &opn,
+	}
+	var fw = FirewallSession{
+		OPN: &opn,
+	}
+	var vip = VIPSession{
+		OPN: &opn,
+	}
+	var ovpn = OpenVPNSession{
+		OPN: &opn,
+	}
+	var syslog = SyslogSession{
+		OPN: &opn,
+	}
+	var monit = MonitSession{
+		OPN: &opn,
+	}
+	var iface = InterfaceSession{
+		OPN: &opn,
+	}
+	var tunable = TunableSession{
+		OPN: &opn,
+	}
+	var haproxy = HAProxySession{
+		OPN: &opn,
+	}
+	var trust = TrustSession{
+		OPN: &opn,
+	}
+	var gateway = GatewaySession{
+		OPN: &opn,
+	}
+	var ntp = NTPSession{
 		OPN: &opn,
 	}
 	var provider = ProviderConfiguration{
-		OPN:   &opn,
-		DHCP:  &dhcp,
-		DNS:   &dns,
-		Mutex: &mut,
-		Cond:  sync.NewCond(&mut),
+		OPN:           &opn,
+		DHCP:          &dhcp,
+		DNS:           &dns,
+		User:          &users,
+		UnboundACL:    &acl,
+		TrafficShaper: &shaper,
+		Firewall:      &fw,
+		VIP:           &vip,
+		OpenVPN:       &ovpn,
+		Syslog:        &syslog,
+		Monit:         &monit,
+		Interface:     &iface,
+		Tunable:       &tunable,
+		HAProxy:       &haproxy,
+		Trust:         &trust,
+		Gateway:       &gateway,
+		NTP:           &ntp,
+		Semaphore:     sem,
+		Cond:          sync.NewCond(sem),
+	}
+	provider.SkipPostCreateRead = d.Get("skip_post_create_read").(bool)
+
+	opn.TLSConfig, err = NewTLSConfig(d.Get("ca_cert_pem").(string), d.Get("client_cert_pem").(string), d.Get("client_key_pem").(string))
+	if err != nil {
+		return nil, err
 	}
-	err := provider.OPN.Authenticate(uri, user, password)
+
+	err = provider.OPN.Authenticate(uri, user, password)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to connect to OPNSense")
+		return nil, connectionError(uri, err)
 	}
 
+	// the session is to be closed on teardown
+	sessions.Lock()
+	sessions.opened = append(sessions.opened, provider.OPN)
+	sessions.Unlock()
+
 	return &provider, nil
 }
+
+// NewTLSConfig builds the TLS configuration of OPNSense sessions out of a PEM-encoded CA bundle
+// and client certificate/key pair, nil if none of them is set (i.e. default verification)
+func NewTLSConfig(ca, cert, key string) (*tls.Config, error) {
+	var config *tls.Config
+
+	// verify TLS against a custom CA bundle, if any
+	if ca != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return nil, fmt.Errorf("Unable to parse ca_cert_pem: no valid PEM certificate found")
+		}
+		config = &tls.Config{
+			RootCAs: pool,
+		}
+	}
+
+	// present a client certificate, if any, to deployments requiring mutual TLS
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, fmt.Errorf("client_cert_pem and client_key_pem must be set together")
+		}
+		pair, err := tls.X509KeyPair([]byte(cert), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client_cert_pem/client_key_pem: %v", err)
+		}
+		if config == nil {
+			config = &tls.Config{}
+		}
+		config.Certificates = []tls.Certificate{pair}
+	}
+
+	return config, nil
+}
+
+// connectionError gives context about why OPNSense session couldn't be established
+func connectionError(uri string, err error) error {
+	hint := ""
+	var dnsErr *net.DNSError
+	var unknownCA x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &dnsErr):
+		hint = " (hint: host name could not be resolved, check uri)"
+	case errors.As(err, &unknownCA):
+		hint = " (hint: certificate is signed by an unknown authority, set ca_cert_pem with the CA bundle that issued it)"
+	case errors.As(err, &invalidCert), errors.As(err, &hostname), strings.Contains(err.Error(), "x509:"):
+		hint = " (hint: TLS certificate verification failed, check uri host name and ca_cert_pem)"
+	case strings.Contains(err.Error(), "tls: bad certificate"), strings.Contains(err.Error(), "tls: certificate required"):
+		hint = " (hint: OPNsense platform requires a client certificate, set client_cert_pem and client_key_pem)"
+	}
+
+	return fmt.Errorf("Failed to connect to OPNSense at %s: %v%s", uri, err, hint)
+}
@@ -0,0 +1,177 @@
+package opnsense
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+func TestProviderLoginPathValidation(t *testing.T) {
+	validate := Provider().(*schema.Provider).Schema["login_path"].ValidateFunc
+	for path, valid := range map[string]bool{
+		"/":                     true,
+		"/index.php":            true,
+		"/ui/index.php?lang=en": true,
+		"index.php":             false,
+		"/index php":            false,
+		"https://fw/index.php":  false,
+		"":                      false,
+	} {
+		_, errs := validate(path, "login_path")
+		if (len(errs) == 0) != valid {
+			t.Errorf("%q: got errors %v, expected valid to be %v", path, errs, valid)
+		}
+	}
+}
+
+// testPEM is a PEM-encoded certificate and its private key
+type testPEM struct {
+	cert []byte
+	key  []byte
+}
+
+// newTestCert issues a certificate signed by the given parent, or a self-signed CA
+// certificate whenever there's no parent
+func newTestCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, testPEM) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent = &tmpl
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, testPEM{
+		cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// newMutualTLSServer starts a fake WebUI requiring client certificates issued by its own CA,
+// returning the CA bundle and a client certificate it accepts
+func newMutualTLSServer(t *testing.T) (*httptest.Server, testPEM, testPEM) {
+	ca, caKey, caPEM := newTestCert(t, "ca", nil, nil)
+	_, _, serverPEM := newTestCert(t, "server", ca, caKey)
+	_, _, clientPEM := newTestCert(t, "client", ca, caKey)
+
+	pair, err := tls.X509KeyPair(serverPEM.cert, serverPEM.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	srv := httptest.NewUnstartedServer(newLoginWebUI("root", "secret", http.NotFoundHandler()))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	t.Cleanup(Teardown)
+
+	return srv, caPEM, clientPEM
+}
+
+func configureProvider(t *testing.T, raw map[string]interface{}) (interface{}, error) {
+	d := schema.TestResourceDataRaw(t, Provider().(*schema.Provider).Schema, raw)
+	return providerConfigure(d)
+}
+
+func TestProviderClientCertificate(t *testing.T) {
+	srv, ca, client := newMutualTLSServer(t)
+	_, _, other := newTestCert(t, "other", nil, nil)
+
+	for name, tc := range map[string]struct {
+		cert string
+		key  string
+		err  string
+	}{
+		"valid pair":       {cert: string(client.cert), key: string(client.key)},
+		"no certificate":   {err: "set client_cert_pem and client_key_pem"},
+		"certificate only": {cert: string(client.cert), err: "must be set together"},
+		"key only":         {key: string(client.key), err: "must be set together"},
+		"mismatched pair":  {cert: string(client.cert), key: string(other.key), err: "Unable to load client_cert_pem/client_key_pem"},
+		"malformed pair":   {cert: "not a certificate", key: "not a key", err: "Unable to load client_cert_pem/client_key_pem"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			meta, err := configureProvider(t, map[string]interface{}{
+				"uri":             srv.URL,
+				"user":            "root",
+				"password":        "secret",
+				"ca_cert_pem":     string(ca.cert),
+				"client_cert_pem": tc.cert,
+				"client_key_pem":  tc.key,
+			})
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if meta.(*ProviderConfiguration).OPN.IsAuthenticated() != nil {
+					t.Error("session isn't authenticated")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("got %v, expected %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestProviderClientCertificateKeepsVerification(t *testing.T) {
+	// a client certificate alone doesn't disable server certificate verification
+	_, _, client := newTestCert(t, "client", nil, nil)
+	srv := httptest.NewTLSServer(newLoginWebUI("root", "secret", http.NotFoundHandler()))
+	defer srv.Close()
+	t.Cleanup(Teardown)
+
+	_, err := configureProvider(t, map[string]interface{}{
+		"uri":             srv.URL,
+		"user":            "root",
+		"password":        "secret",
+		"client_cert_pem": string(client.cert),
+		"client_key_pem":  string(client.key),
+	})
+	if err == nil || !strings.Contains(err.Error(), "set ca_cert_pem") {
+		t.Errorf("got %v, expected the server certificate to be verified", err)
+	}
+}
@@ -0,0 +1,90 @@
+package opnsense
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyApplyDHCPInterfaces corresponds to the associated resource schema key
+	KeyApplyDHCPInterfaces = "dhcp_interfaces"
+	// KeyApplyDNS corresponds to the associated resource schema key
+	KeyApplyDNS = "dns"
+	// KeyApplyTriggers corresponds to the associated resource schema key
+	KeyApplyTriggers = "triggers"
+)
+
+func resourceOpnApply() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceApplyCreate,
+		Read:   resourceApplyRead,
+		Delete: resourceApplyDelete,
+
+		Schema: map[string]*schema.Schema{
+			KeyApplyDHCPInterfaces: {
+				Type:     schema.TypeList,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+			},
+			KeyApplyDNS: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				ForceNew: true,
+			},
+			KeyApplyTriggers: {
+				Type:        schema.TypeMap,
+				Optional:    true,
+				ForceNew:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "Arbitrary values whose change makes changes being applied again",
+			},
+		},
+	}
+}
+
+func resourceApplyCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// reload DHCP server of each requested interface
+	for _, iface := range d.Get(KeyApplyDHCPInterfaces).([]interface{}) {
+		_, err := pconf.DHCP.Commit(iface.(string), "")
+		if err != nil {
+			return fmt.Errorf("unable to apply DHCP changes on %s: %v", iface, err)
+		}
+	}
+
+	// reload DNS server
+	if d.Get(KeyApplyDNS).(bool) {
+		_, err := pconf.DNS.Commit("")
+		if err != nil {
+			return fmt.Errorf("unable to apply DNS changes: %v", err)
+		}
+	}
+
+	d.SetId(fmt.Sprintf("%d", time.Now().UnixNano()))
+
+	return nil
+}
+
+func resourceApplyRead(d *schema.ResourceData, meta interface{}) error {
+	// applying changes leaves nothing to read out
+	return nil
+}
+
+func resourceApplyDelete(d *schema.ResourceData, meta interface{}) error {
+	// applied changes can't be undone
+	d.SetId("")
+	return nil
+}
@@ -2,7 +2,9 @@ package opnsense
 
 import (
 	"fmt"
+	"log"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
@@ -14,10 +16,30 @@ const (
 	KeyInterface = "interface"
 	// KeyMAC corresponds to the associated resource schema key
 	KeyMAC = "mac"
+	// KeyMACs corresponds to the associated resource schema key
+	KeyMACs = "macs"
 	// KeyIP corresponds to the associated resource schema key
 	KeyIP = "ipaddr"
 	// KeyName corresponds to the associated resource schema key
 	KeyName = "hostname"
+	// KeyEnabled corresponds to the associated resource schema key
+	KeyEnabled = "enabled"
+	// KeyNextServer corresponds to the associated resource schema key
+	KeyNextServer = "next_server"
+	// KeyBootFilename corresponds to the associated resource schema key
+	KeyBootFilename = "boot_filename"
+	// KeyRootPath corresponds to the associated resource schema key
+	KeyRootPath = "root_path"
+	// KeyPool corresponds to the associated resource schema key
+	KeyPool = "pool"
+	// KeyDHCPOptions corresponds to the associated resource schema key
+	KeyDHCPOptions = "dhcp_options"
+	// KeyDHCPOptionNumber corresponds to the associated resource schema key
+	KeyDHCPOptionNumber = "number"
+	// KeyDHCPOptionType corresponds to the associated resource schema key
+	KeyDHCPOptionType = "type"
+	// KeyDHCPOptionValue corresponds to the associated resource schema key
+	KeyDHCPOptionValue = "value"
 )
 
 func resourceOpnDHCPStaticMap() *schema.Resource {
@@ -41,6 +63,16 @@ func resourceOpnDHCPStaticMap() *schema.Resource {
 				Required:     true,
 				ValidateFunc: validation.IsMACAddress,
 			},
+			KeyMACs: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsMACAddress,
+				},
+				Set:         schema.HashString,
+				Description: "Additional MAC addresses of the same device, on OPNsense versions supporting it",
+			},
 			KeyIP: {
 				Type:         schema.TypeString,
 				Required:     true,
@@ -51,10 +83,99 @@ func resourceOpnDHCPStaticMap() *schema.Resource {
 				Optional:     true,
 				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
 			},
+			KeyEnabled: {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Disabling a mapping requires an OPNsense version supporting it, it's rejected otherwise",
+			},
+			KeyNextServer: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv4Address,
+			},
+			KeyBootFilename: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			KeyRootPath: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			KeyPool: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "DHCP pool the mapping belongs to, interface primary one if unset",
+			},
+			KeyDHCPOptions: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						KeyDHCPOptionNumber: {
+							Type:         schema.TypeInt,
+							Required:     true,
+							ValidateFunc: validation.IntBetween(0, DHCPOptionMaxNumber),
+						},
+						KeyDHCPOptionType: {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      DHCPOptionTypes[0],
+							ValidateFunc: validation.StringInSlice(DHCPOptionTypes, false),
+						},
+						KeyDHCPOptionValue: {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+					},
+				},
+				Description: "Additional numbered DHCP options, on OPNsense versions supporting it",
+			},
+			KeyLeaseActive: {
+				Type:        schema.TypeBool,
+				Computed:    true,
+				Description: "Whether the device currently holds a non-expired lease, as of last refresh",
+			},
 		},
 	}
 }
 
+// dhcpMACs returns the additional MAC addresses of a static mapping
+func dhcpMACs(d *schema.ResourceData) []string {
+	macs := []string{}
+	for _, mac := range d.Get(KeyMACs).(*schema.Set).List() {
+		macs = append(macs, mac.(string))
+	}
+	return macs
+}
+
+// dhcpOptionsFromSchema returns the additional DHCP options of a static mapping
+func dhcpOptionsFromSchema(d *schema.ResourceData) []DHCPOption {
+	options := []DHCPOption{}
+	for _, o := range d.Get(KeyDHCPOptions).(*schema.Set).List() {
+		opt := o.(map[string]interface{})
+		options = append(options, DHCPOption{
+			Number: opt[KeyDHCPOptionNumber].(int),
+			Type:   opt[KeyDHCPOptionType].(string),
+			Value:  opt[KeyDHCPOptionValue].(string),
+		})
+	}
+	return options
+}
+
+// dhcpOptionsToSchema converts additional DHCP options into their resource schema form
+func dhcpOptionsToSchema(options []DHCPOption) []map[string]interface{} {
+	res := []map[string]interface{}{}
+	for _, o := range options {
+		res = append(res, map[string]interface{}{
+			KeyDHCPOptionNumber: o.Number,
+			KeyDHCPOptionType:   o.Type,
+			KeyDHCPOptionValue:  o.Value,
+		})
+	}
+	return res
+}
+
 var rxRsID = regexp.MustCompile("([^/]+)/([^/]+)")
 
 func parseDhcpResourceID(resID string) (string, string, error) {
@@ -72,7 +193,7 @@ func dhcpResourceID(itf, mac string) string {
 func resourceDhcpStaticMappingCreate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
 	dhcp := pconf.DHCP
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 
 	lock.Lock()
 
@@ -80,10 +201,28 @@ func resourceDhcpStaticMappingCreate(d *schema.ResourceData, meta interface{}) e
 	iface := d.Get(KeyInterface).(string)
 	mac := d.Get(KeyMAC).(string)
 	m := StaticMapping{
-		Interface: iface,
-		IP:        d.Get(KeyIP).(string),
-		MAC:       mac,
-		Hostname:  d.Get(KeyName).(string),
+		Interface:  iface,
+		IP:         d.Get(KeyIP).(string),
+		MAC:        mac,
+		MACs:       dhcpMACs(d),
+		Hostname:   d.Get(KeyName).(string),
+		Disabled:   !d.Get(KeyEnabled).(bool),
+		NextServer: d.Get(KeyNextServer).(string),
+		Filename:   d.Get(KeyBootFilename).(string),
+		RootPath:   d.Get(KeyRootPath).(string),
+		Pool:       d.Get(KeyPool).(string),
+		Options:    dhcpOptionsFromSchema(d),
+	}
+
+	// report all conflicts at once, rather than failing on the first one
+	errs := dhcp.ValidateMapping(&m)
+	if len(errs) > 0 {
+		lock.Unlock()
+		msgs := []string{}
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		return fmt.Errorf("invalid static mapping %s: %s", dhcpResourceID(iface, mac), strings.Join(msgs, "; "))
 	}
 
 	err := dhcp.CreateStaticMapping(&m)
@@ -97,6 +236,12 @@ func resourceDhcpStaticMappingCreate(d *schema.ResourceData, meta interface{}) e
 	// set resource ID accordingly
 	d.SetId(dhcpResourceID(iface, mac))
 
+	// trust the created mapping, whose state is all known values
+	if pconf.SkipPostCreateRead {
+		lock.Unlock()
+		return nil
+	}
+
 	// read out resource again
 	lock.Unlock()
 	err = resourceDhcpStaticMappingRead(d, meta)
@@ -106,7 +251,7 @@ func resourceDhcpStaticMappingCreate(d *schema.ResourceData, meta interface{}) e
 
 func resourceDhcpStaticMappingRead(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 	dhcp := pconf.DHCP
 
 	lock.Lock()
@@ -123,10 +268,12 @@ func resourceDhcpStaticMappingRead(d *schema.ResourceData, meta interface{}) err
 		MAC:       mac,
 	}
 
-	// read out DHCP information
+	// read out DHCP information, only a mapping known to be gone is dropped from state
 	err = dhcp.ReadStaticMapping(&m)
 	if err != nil {
-		d.SetId("")
+		if err.Error() == ErrNoSuchMAC {
+			d.SetId("")
+		}
 		return err
 	}
 
@@ -138,13 +285,37 @@ func resourceDhcpStaticMappingRead(d *schema.ResourceData, meta interface{}) err
 	d.Set(KeyIP, m.IP)
 	d.Set(KeyName, m.Hostname)
 	d.Set(KeyMAC, m.MAC)
+	d.Set(KeyMACs, m.MACs)
+	d.Set(KeyEnabled, !m.Disabled)
+	d.Set(KeyNextServer, m.NextServer)
+	d.Set(KeyBootFilename, m.Filename)
+	d.Set(KeyRootPath, m.RootPath)
+	d.Set(KeyPool, m.Pool)
+	d.Set(KeyDHCPOptions, dhcpOptionsToSchema(m.Options))
+
+	// correlate with leases status by MAC, any of the mapping ones holding a lease,
+	// the status page being shared by all mappings of a refresh
+	leases, err := dhcp.CachedLeases()
+	if err != nil {
+		// the status page being unavailable isn't the mapping fault: leave lease status as it was
+		log.Printf("[WARN] OPNSense DHCP leases status unavailable for %s on %s: %v", m.MAC, m.Interface, err)
+		return nil
+	}
+	active := false
+	for _, mac := range append([]string{m.MAC}, m.MACs...) {
+		l := dhcp.FindLease(leases, mac)
+		if l != nil && l.Active {
+			active = true
+		}
+	}
+	d.Set(KeyLeaseActive, active)
 
 	return nil
 }
 
 func resourceDhcpStaticMappingDelete(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 	dhcp := pconf.DHCP
 
 	lock.Lock()
@@ -156,10 +327,11 @@ func resourceDhcpStaticMappingDelete(d *schema.ResourceData, meta interface{}) e
 		return err
 	}
 
-	// delete an existing mapping
+	// delete an existing mapping, the IP address being used as a fallback on MAC drift
 	m := StaticMapping{
 		Interface: iface,
 		MAC:       mac,
+		IP:        d.Get(KeyIP).(string),
 	}
 
 	err = dhcp.DeleteStaticMapping(&m)
@@ -173,7 +345,7 @@ func resourceDhcpStaticMappingDelete(d *schema.ResourceData, meta interface{}) e
 func resourceDhcpStaticMappingUpdate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
 	dhcp := pconf.DHCP
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 
 	lock.Lock()
 	defer lock.Unlock()
@@ -186,10 +358,17 @@ func resourceDhcpStaticMappingUpdate(d *schema.ResourceData, meta interface{}) e
 
 	// updated mapping
 	m := StaticMapping{
-		Interface: iface,
-		IP:        d.Get(KeyIP).(string),
-		MAC:       mac,
-		Hostname:  d.Get(KeyName).(string),
+		Interface:  iface,
+		IP:         d.Get(KeyIP).(string),
+		MAC:        mac,
+		MACs:       dhcpMACs(d),
+		Hostname:   d.Get(KeyName).(string),
+		Disabled:   !d.Get(KeyEnabled).(bool),
+		NextServer: d.Get(KeyNextServer).(string),
+		Filename:   d.Get(KeyBootFilename).(string),
+		RootPath:   d.Get(KeyRootPath).(string),
+		Pool:       d.Get(KeyPool).(string),
+		Options:    dhcpOptionsFromSchema(d),
 	}
 
 	err = dhcp.UpdateStaticMapping(&m)
@@ -2,9 +2,12 @@ package opnsense
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
@@ -13,12 +16,32 @@ import (
 const (
 	// KeyDNSType corresponds to the associated resource schema key
 	KeyDNSType = "type"
+	// KeyDNSRecordType corresponds to the associated resource schema key
+	KeyDNSRecordType = "record_type"
 	// KeyDNSHost corresponds to the associated resource schema key
 	KeyDNSHost = "host"
 	// KeyDNSDomain corresponds to the associated resource schema key
 	KeyDNSDomain = "domain"
 	// KeyDNSIP corresponds to the associated resource schema key
 	KeyDNSIP = "ip"
+	// KeyDNSIPs corresponds to the associated resource schema key
+	KeyDNSIPs = "ips"
+	// KeyDNSName corresponds to the associated resource schema key
+	KeyDNSName = "name"
+	// KeyDNSView corresponds to the associated resource schema key
+	KeyDNSView = "view"
+	// KeyDNSMXPriority corresponds to the associated resource schema key
+	KeyDNSMXPriority = "mx_priority"
+	// KeyDNSTTL corresponds to the associated resource schema key
+	KeyDNSTTL = "ttl"
+	// KeyDNSReverseName corresponds to the associated resource schema key
+	KeyDNSReverseName = "reverse_name"
+	// KeyDNSFQDN corresponds to the associated resource schema key
+	KeyDNSFQDN = "fqdn"
+	// KeyDNSApplyImmediately corresponds to the associated resource schema key
+	KeyDNSApplyImmediately = "apply_immediately"
+	// KeyDNSOverwriteExisting corresponds to the associated resource schema key
+	KeyDNSOverwriteExisting = "overwrite_existing"
 )
 
 func resourceOpnDNSHostOverride() *schema.Resource {
@@ -28,41 +51,320 @@ func resourceOpnDNSHostOverride() *schema.Resource {
 		Update: resourceDNSHostOverrideUpdate,
 		Delete: resourceDNSHostOverrideDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceDNSHostOverrideImport,
 		},
+		CustomizeDiff: resourceDNSHostOverrideCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			KeyDNSType: {
 				Type:         schema.TypeString,
-				Required:     true,
+				Optional:     true,
 				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				Description:  "Record type, inferred as A or AAAA from the IP address family when omitted",
+			},
+			KeyDNSRecordType: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Effective record type: type when set, otherwise the one inferred from the IP address family",
 			},
 			KeyDNSHost: {
 				Type:         schema.TypeString,
 				Required:     true,
-				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				ValidateFunc: validateDNSHost,
 			},
 			KeyDNSDomain: {
 				Type:         schema.TypeString,
 				Required:     true,
-				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				ValidateFunc: validateDNSDomain,
+				StateFunc:    dnsDomainStateFunc,
 			},
 			KeyDNSIP: {
 				Type:         schema.TypeString,
-				Required:     true,
-				ValidateFunc: validation.IsIPAddress,
+				Optional:     true,
+				ExactlyOneOf: []string{KeyDNSIP, KeyDNSIPs},
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyDNSIPs: {
+				Type:         schema.TypeSet,
+				Optional:     true,
+				MinItems:     1,
+				ExactlyOneOf: []string{KeyDNSIP, KeyDNSIPs},
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPAddress,
+				},
+				Set: schema.HashString,
+			},
+			KeyDNSName: {
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{KeyDNSIPs},
+				ValidateFunc:  validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace, validation.StringDoesNotContainAny("/")),
+				Description:   "Unique name, stored as entry description behind a terraform: prefix, used to track the entry across changes",
+			},
+			KeyDNSView: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				Description:  "Unbound view the override applies to, rejected if OPNsense doesn't expose views",
+			},
+			KeyDNSMXPriority: {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(0, 65535),
+				Description:  "MX record priority, only with type MX",
+			},
+			KeyDNSTTL: {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntAtLeast(0),
+				Description:  "Record TTL in seconds, rejected if OPNsense doesn't expose it",
+			},
+			KeyDNSReverseName: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "PTR record name (in-addr.arpa or ip6.arpa) of an A/AAAA override address",
+			},
+			KeyDNSFQDN: {
+				Type:        schema.TypeString,
+				Computed:    true,
+				Description: "Fully qualified name of the override (e.g. *.example.com for a wildcard)",
+			},
+			KeyDNSApplyImmediately: {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     true,
+				Description: "Reload Unbound on create/update, otherwise the record isn't served until something else does",
+			},
+			KeyDNSOverwriteExisting: {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{KeyDNSIPs},
+				Description:   "Adopt, on create, an existing entry of the same type, host and domain instead of failing",
 			},
 		},
 	}
 }
 
+var rxDNSName = regexp.MustCompile(`^([a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?\.)*[a-zA-Z0-9_]([a-zA-Z0-9_-]{0,61}[a-zA-Z0-9_])?\.?$`)
+
+// validateDNSDomain rejects values which can't be a domain name, whatever their case or trailing dot
+func validateDNSDomain(v interface{}, k string) ([]string, []error) {
+	domain := NormalizeDomain(v.(string))
+	if domain == "" || !rxDNSName.MatchString(domain) {
+		return nil, []error{fmt.Errorf("%s: %q is not a valid domain name", k, v.(string))}
+	}
+	return nil, nil
+}
+
+// validateDNSHost rejects values which can't be a host name, but the "*" wildcard
+func validateDNSHost(v interface{}, k string) ([]string, []error) {
+	host := v.(string)
+	if host == DNSWildcardHost {
+		return nil, nil
+	}
+	if host == "" || !rxDNSName.MatchString(host) {
+		return nil, []error{fmt.Errorf("%s: %q is not a valid host name (nor the %q wildcard)", k, host, DNSWildcardHost)}
+	}
+	return nil, nil
+}
+
+// dnsDomainStateFunc stores domains in their canonical form, as OPNSense does
+func dnsDomainStateFunc(v interface{}) string {
+	return NormalizeDomain(v.(string))
+}
+
+// validateDNSValue checks that a record value is consistent with its type
+func validateDNSValue(rr, value string) error {
+	ip := net.ParseIP(value)
+	switch rr {
+	case "A":
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("type A override expects an IPv4 address, got %q", value)
+		}
+	case "AAAA":
+		if ip == nil || ip.To4() != nil {
+			return fmt.Errorf("type AAAA override expects an IPv6 address, got %q", value)
+		}
+	case "CNAME", "MX":
+		if ip != nil || !rxDNSName.MatchString(value) {
+			return fmt.Errorf("type %s override expects a host name, got %q", rr, value)
+		}
+	case "TXT":
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("type TXT override expects a single line value, got %q", value)
+		}
+	}
+	return nil
+}
+
+// dnsInferType returns the A/AAAA record type matching an IP address family, if any
+func dnsInferType(value string) string {
+	ip := net.ParseIP(value)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return "A"
+	default:
+		return "AAAA"
+	}
+}
+
+// resourceDNSHostOverrideCustomizeDiff infers omitted record type from the IP address
+// family, cross-validates record type against its value(s), and replaces round-robin
+// overrides whose IPs can't just be added or removed
+func resourceDNSHostOverrideCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	err := dnsCustomizeValues(d)
+	if err != nil {
+		return err
+	}
+	return dnsCustomizeRoundRobin(d)
+}
+
+// dnsCustomizeRoundRobin forces the replacement of an override switching between ip and ips,
+// and of a round-robin one whose other settings change, as round-robin entries are only ever
+// added or removed in place
+func dnsCustomizeRoundRobin(d *schema.ResourceDiff) error {
+	if d.Id() == "" {
+		return nil
+	}
+
+	o, _ := d.GetChange(KeyDNSIPs)
+	wasRoundRobin := o.(*schema.Set).Len() > 0
+	isRoundRobin := wasRoundRobin
+	if d.NewValueKnown(KeyDNSIPs) {
+		isRoundRobin = d.Get(KeyDNSIPs).(*schema.Set).Len() > 0
+	}
+
+	keys := []string{}
+	if wasRoundRobin != isRoundRobin {
+		keys = append(keys, KeyDNSIP, KeyDNSIPs)
+	}
+	if wasRoundRobin && isRoundRobin {
+		keys = append(keys, KeyDNSRecordType, KeyDNSHost, KeyDNSDomain, KeyDNSTTL, KeyDNSView, KeyDNSMXPriority)
+	}
+	for _, k := range keys {
+		if !d.HasChange(k) {
+			continue
+		}
+		err := d.ForceNew(k)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dnsCustomizeValues infers omitted record type from the IP address family and
+// cross-validates record type against its value(s)
+func dnsCustomizeValues(d *schema.ResourceDiff) error {
+
+	// FQDN follows host and domain
+	if d.HasChange(KeyDNSHost) || d.HasChange(KeyDNSDomain) {
+		var err error
+		if d.NewValueKnown(KeyDNSHost) && d.NewValueKnown(KeyDNSDomain) {
+			err = d.SetNew(KeyDNSFQDN, dnsFQDN(d.Get(KeyDNSHost).(string), d.Get(KeyDNSDomain).(string)))
+		} else {
+			err = d.SetNewComputed(KeyDNSFQDN)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// type isn't computed, so that it's only ever empty when omitted from the configuration:
+	// a configured type is never inferred over, and mismatching addresses are rejected below
+	if !d.NewValueKnown(KeyDNSType) {
+		return d.SetNewComputed(KeyDNSRecordType)
+	}
+	rr := d.Get(KeyDNSType).(string)
+	if !d.NewValueKnown(KeyDNSIP) || !d.NewValueKnown(KeyDNSIPs) {
+		if rr == "" {
+			return d.SetNewComputed(KeyDNSRecordType)
+		}
+		return dnsSetNewRecordType(d, rr)
+	}
+
+	// first IP address drives type inference, round-robin ones sharing the same family
+	value := d.Get(KeyDNSIP).(string)
+	if ips := dnsResourceIPs(d.Get(KeyDNSIPs).(*schema.Set)); len(ips) > 0 {
+		value = ips[0]
+	}
+
+	if rr == "" {
+		rr = dnsInferType(value)
+		if rr == "" {
+			return fmt.Errorf("%s can't be inferred from %q, it must be set", KeyDNSType, value)
+		}
+	}
+	err := dnsSetNewRecordType(d, rr)
+	if err != nil {
+		return err
+	}
+
+	if rr != "MX" && d.NewValueKnown(KeyDNSMXPriority) && d.Get(KeyDNSMXPriority).(int) != 0 {
+		return fmt.Errorf("%s can only be set on type MX overrides", KeyDNSMXPriority)
+	}
+
+	if d.NewValueKnown(KeyDNSIP) {
+		ip := d.Get(KeyDNSIP).(string)
+		if ip != "" {
+			err := validateDNSValue(rr, ip)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if d.NewValueKnown(KeyDNSIPs) {
+		for _, ip := range d.Get(KeyDNSIPs).(*schema.Set).List() {
+			err := validateDNSValue(rr, ip.(string))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dnsSetNewRecordType plans the effective record type, only when it changes
+func dnsSetNewRecordType(d *schema.ResourceDiff, rr string) error {
+	if d.Get(KeyDNSRecordType).(string) == rr {
+		return nil
+	}
+	return d.SetNew(KeyDNSRecordType, rr)
+}
+
+// dnsSetType reads out the effective record type, reflected in type only when it's
+// configured or can't be inferred from the value (e.g. on import)
+func dnsSetType(d *schema.ResourceData, rr, value string) {
+	d.Set(KeyDNSRecordType, rr)
+	if d.Get(KeyDNSType).(string) != "" || dnsInferType(value) != rr {
+		d.Set(KeyDNSType, rr)
+	}
+}
+
 var dnsRsID = regexp.MustCompile("([^/]+)/([^/]+)/([^/]+)/([^/]+)/([^/]+)")
 
+// named entries are identified by their name only
+const dnsNamedRsIDPrefix = "name:"
+
 func parseDNSResourceID(resID string) (*DNSHostEntry, error) {
 	e := DNSHostEntry{}
 
+	if strings.HasPrefix(resID, dnsNamedRsIDPrefix) {
+		e.Description = DNSNamedDescription(strings.TrimPrefix(resID, dnsNamedRsIDPrefix))
+		return &e, nil
+	}
+
 	if !dnsRsID.MatchString(resID) {
-		return &e, fmt.Errorf("invalid resource format: %s. must be type/host/domain/ip/id", resID)
+		return &e, fmt.Errorf("invalid resource format: %s. must be type/host/domain/ip/id, type/host/domain or name:<name>", resID)
 	}
 	idMatch := dnsRsID.FindStringSubmatch(resID)
 	e.Type = idMatch[1]
@@ -71,41 +373,186 @@ func parseDNSResourceID(resID string) (*DNSHostEntry, error) {
 	e.IP = idMatch[4]
 	e.ID, _ = strconv.Atoi(idMatch[5])
 
+	// TXT values may hold slashes, they're escaped in resource IDs
+	if e.Type == "TXT" {
+		ip, err := url.PathUnescape(e.IP)
+		if err != nil {
+			return &e, fmt.Errorf("invalid resource TXT value: %s", e.IP)
+		}
+		e.IP = ip
+	}
+
 	return &e, nil
 }
 
 func dnsResourceID(e *DNSHostEntry) string {
-	return fmt.Sprintf("%s/%s/%s/%s/%d", e.Type, e.Host, e.Domain, e.IP, e.ID)
+	if name := DNSEntryName(e); name != "" {
+		return dnsNamedRsIDPrefix + name
+	}
+	ip := e.IP
+	if e.Type == "TXT" {
+		ip = url.PathEscape(ip)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%d", e.Type, e.Host, e.Domain, ip, e.ID)
+}
+
+// dnsAdoptRsID matches type/host/domain import IDs, adopting the live entries of a record
+var dnsAdoptRsID = regexp.MustCompile("^([^/]+)/([^/]+)/([^/]+)$")
+
+// resourceDNSHostOverrideImport accepts resource IDs as they are, and resolves type/host/domain
+// IDs into the live entry of that record, whatever its value (all of them if round-robin)
+func resourceDNSHostOverrideImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	// settings which aren't read out of OPNsense get their default values
+	d.Set(KeyDNSApplyImmediately, true)
+	d.Set(KeyDNSOverwriteExisting, false)
+
+	m := dnsAdoptRsID.FindStringSubmatch(d.Id())
+	if m == nil {
+		return []*schema.ResourceData{d}, nil
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	entries, err := dns.FindHostEntries(&DNSHostEntry{
+		Type:   m[1],
+		Host:   m[2],
+		Domain: m[3],
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to adopt %s: %v", d.Id(), err)
+	}
+
+	// several entries are a round-robin record, identified by all of their IPs
+	e := entries[0]
+	if len(entries) > 1 {
+		ips := []string{}
+		for _, rr := range entries {
+			ips = append(ips, rr.IP)
+		}
+		sort.Strings(ips)
+		e.IP = strings.Join(ips, ",")
+		e.Description = ""
+		d.Set(KeyDNSIPs, ips)
+	}
+	d.SetId(dnsResourceID(&e))
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// DNSHostOverrideImportID returns the opnsense_dns_host_override resource ID of an existing entry,
+// as expected by terraform import
+func DNSHostOverrideImportID(e *DNSHostEntry) string {
+	return dnsResourceID(e)
+}
+
+// DNSHostOverrideInfersType tells whether the type of an existing entry can be left out of
+// opnsense_dns_host_override configuration, being inferred from its IP address
+func DNSHostOverrideInfersType(e *DNSHostEntry) bool {
+	return dnsInferType(e.IP) == e.Type
+}
+
+// round-robin entries are identified by all of their IPs, comma-separated
+func dnsResourceIPs(ips *schema.Set) []string {
+	res := []string{}
+	for _, ip := range ips.List() {
+		res = append(res, ip.(string))
+	}
+	sort.Strings(res)
+	return res
+}
+
+func dnsIsRoundRobin(d *schema.ResourceData, e *DNSHostEntry) bool {
+	return d.Get(KeyDNSIPs).(*schema.Set).Len() > 0 || strings.Contains(e.IP, ",")
 }
 
 func resourceDNSHostOverrideCreate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
 	dns := pconf.DNS
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 
 	lock.Lock()
 
 	// create a new host override
 	e := DNSHostEntry{
-		Type:   d.Get(KeyDNSType).(string),
-		Host:   d.Get(KeyDNSHost).(string),
-		Domain: d.Get(KeyDNSDomain).(string),
-		IP:     d.Get(KeyDNSIP).(string),
+		Type:       d.Get(KeyDNSType).(string),
+		Host:       d.Get(KeyDNSHost).(string),
+		Domain:     NormalizeDomain(d.Get(KeyDNSDomain).(string)),
+		IP:         d.Get(KeyDNSIP).(string),
+		View:       d.Get(KeyDNSView).(string),
+		MXPriority: d.Get(KeyDNSMXPriority).(int),
+		TTL:        d.Get(KeyDNSTTL).(int),
+		Deferred:   !d.Get(KeyDNSApplyImmediately).(bool),
 	}
 
-	err := dns.CreateHostOverride(&e)
-	if err != nil {
-		lock.Unlock()
-		return err
+	if name := d.Get(KeyDNSName).(string); name != "" {
+		e.Description = DNSNamedDescription(name)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	ips := dnsResourceIPs(d.Get(KeyDNSIPs).(*schema.Set))
+
+	// IP address may have been unknown at plan time
+	if e.Type == "" {
+		if len(ips) > 0 {
+			e.Type = dnsInferType(ips[0])
+		} else {
+			e.Type = dnsInferType(e.IP)
+		}
+	}
+	if len(ips) > 0 {
+		// create one entry per round-robin IP
+		e.ID = -1
+		err := dns.UpdateRoundRobin(&e, ips, nil)
+		if err != nil {
+			lock.Unlock()
+			return err
+		}
+		e.IP = strings.Join(ips, ",")
+	} else if d.Get(KeyDNSOverwriteExisting).(bool) {
+		err := dns.CreateOrAdoptHostOverride(&e)
+		if err != nil {
+			lock.Unlock()
+			return err
+		}
+	} else {
+		err := dns.CreateHostOverride(&e)
+		if err != nil {
+			lock.Unlock()
+			return err
+		}
+	}
 
 	// set resource ID accordingly
 	d.SetId(dnsResourceID(&e))
+	lock.Unlock()
+
+	// Unbound takes a while to reload, wait for the override to be served
+	// while other resources go on
+	last := e
+	if len(ips) > 0 {
+		last.IP = ips[len(ips)-1]
+	}
+	err := dns.WaitApplied(&last)
+	if err != nil {
+		return err
+	}
+
+	// trust the created override, only computing values which may still be unknown
+	if pconf.SkipPostCreateRead {
+		d.Set(KeyDNSRecordType, e.Type)
+		d.Set(KeyDNSTTL, e.TTL)
+		d.Set(KeyDNSFQDN, dnsFQDN(e.Host, e.Domain))
+		if len(ips) == 0 {
+			d.Set(KeyDNSReverseName, dnsReverseName(&e))
+		}
+		return nil
+	}
 
 	// read out resource again
-	lock.Unlock()
 	err = resourceDNSHostOverrideRead(d, meta)
 
 	return err
@@ -113,7 +560,7 @@ func resourceDNSHostOverrideCreate(d *schema.ResourceData, meta interface{}) err
 
 func resourceDNSHostOverrideRead(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 	dns := pconf.DNS
 
 	lock.Lock()
@@ -125,28 +572,100 @@ func resourceDNSHostOverrideRead(d *schema.ResourceData, meta interface{}) error
 		return err
 	}
 
+	// read out all round-robin entries together
+	if dnsIsRoundRobin(d, e) {
+		entries, err := dns.FindHostEntries(e)
+		if err != nil {
+			d.SetId("")
+			return err
+		}
+
+		ips := []string{}
+		for _, rr := range entries {
+			ips = append(ips, rr.IP)
+		}
+		sort.Strings(ips)
+		e.IP = strings.Join(ips, ",")
+		e.ID = entries[0].ID
+
+		// all round-robin entries share the same settings
+		rr := entries[0]
+		err = dns.ReadDetails(&rr)
+		if err != nil {
+			d.SetId("")
+			return err
+		}
+
+		// set Terraform resource ID
+		d.SetId(dnsResourceID(e))
+
+		// set object params
+		dnsSetType(d, e.Type, ips[0])
+		d.Set(KeyDNSHost, e.Host)
+		d.Set(KeyDNSDomain, e.Domain)
+		d.Set(KeyDNSIPs, ips)
+		d.Set(KeyDNSView, rr.View)
+		d.Set(KeyDNSTTL, rr.TTL)
+		d.Set(KeyDNSFQDN, dnsFQDN(e.Host, e.Domain))
+
+		return nil
+	}
+
 	// read out DNS Host information
 	err = dns.ReadHostOverride(e)
 	if err != nil {
 		d.SetId("")
 		return err
 	}
+	err = dns.ReadDetails(e)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
 
 	// set Terraform resource ID
 	d.SetId(dnsResourceID(e))
 
 	// set object params
-	d.Set(KeyDNSType, e.Type)
+	dnsSetType(d, e.Type, e.IP)
 	d.Set(KeyDNSHost, e.Host)
 	d.Set(KeyDNSDomain, e.Domain)
 	d.Set(KeyDNSIP, e.IP)
+	d.Set(KeyDNSName, DNSEntryName(e))
+	d.Set(KeyDNSView, e.View)
+	d.Set(KeyDNSMXPriority, e.MXPriority)
+	d.Set(KeyDNSTTL, e.TTL)
+	d.Set(KeyDNSReverseName, dnsReverseName(e))
+	d.Set(KeyDNSFQDN, dnsFQDN(e.Host, e.Domain))
 
 	return nil
 }
 
+// dnsReverseName computes the PTR record name of A/AAAA overrides, if any
+func dnsReverseName(e *DNSHostEntry) string {
+	if e.Type != "A" && e.Type != "AAAA" {
+		return ""
+	}
+	ptr, err := ReverseName(e.IP)
+	if err != nil {
+		return ""
+	}
+	return ptr
+}
+
+// dnsFQDN computes the fully qualified name of an override, which is its
+// domain itself when the host is empty
+func dnsFQDN(host, domain string) string {
+	domain = NormalizeDomain(domain)
+	if host == "" {
+		return domain
+	}
+	return fmt.Sprintf("%s.%s", host, domain)
+}
+
 func resourceDNSHostOverrideUpdate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 	dns := pconf.DNS
 
 	lock.Lock()
@@ -157,28 +676,66 @@ func resourceDNSHostOverrideUpdate(d *schema.ResourceData, meta interface{}) err
 		lock.Unlock()
 		return err
 	}
+	e.Deferred = !d.Get(KeyDNSApplyImmediately).(bool)
+
+	if dnsIsRoundRobin(d, e) {
+		// add/remove individual round-robin entries, anything else forcing a replacement
+		o, n := d.GetChange(KeyDNSIPs)
+		e.View = d.Get(KeyDNSView).(string)
+		e.MXPriority = d.Get(KeyDNSMXPriority).(int)
+		e.TTL = d.Get(KeyDNSTTL).(int)
+		added := dnsResourceIPs(n.(*schema.Set).Difference(o.(*schema.Set)))
+		removed := dnsResourceIPs(o.(*schema.Set).Difference(n.(*schema.Set)))
+		err = dns.UpdateRoundRobin(e, added, removed)
+		if err != nil {
+			lock.Unlock()
+			return err
+		}
+		e.IP = strings.Join(dnsResourceIPs(n.(*schema.Set)), ",")
+		d.SetId(dnsResourceID(e))
+	} else {
+		// updated entry
+		e.Type = d.Get(KeyDNSType).(string)
+		e.Host = d.Get(KeyDNSHost).(string)
+		e.Domain = NormalizeDomain(d.Get(KeyDNSDomain).(string))
+		e.IP = d.Get(KeyDNSIP).(string)
+		e.View = d.Get(KeyDNSView).(string)
+		e.MXPriority = d.Get(KeyDNSMXPriority).(int)
+		e.TTL = d.Get(KeyDNSTTL).(int)
+		if e.Type == "" {
+			e.Type = dnsInferType(e.IP)
+		}
+
+		err = dns.UpdateHostOverride(e)
+		if err != nil {
+			lock.Unlock()
+			return err
+		}
+		d.SetId(dnsResourceID(e))
+	}
+	lock.Unlock()
 
-	// updated entry
-	e.IP = d.Get(KeyDNSIP).(string)
-
-	err = dns.UpdateHostOverride(e)
+	// Unbound takes a while to reload, wait for the override to be served
+	// while other resources go on
+	last := *e
+	if strings.Contains(last.IP, ",") {
+		ips := strings.Split(last.IP, ",")
+		last.IP = ips[len(ips)-1]
+	}
+	err = dns.WaitApplied(&last)
 	if err != nil {
-		lock.Unlock()
 		return err
 	}
 
-	time.Sleep(100 * time.Millisecond)
-
 	// read out resource again
-	lock.Unlock()
 	err = resourceDNSHostOverrideRead(d, meta)
 
-	return nil
+	return err
 }
 
 func resourceDNSHostOverrideDelete(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*ProviderConfiguration)
-	lock := pconf.Mutex
+	lock := pconf.Semaphore
 	dns := pconf.DNS
 
 	lock.Lock()
@@ -190,10 +747,10 @@ func resourceDNSHostOverrideDelete(d *schema.ResourceData, meta interface{}) err
 		return err
 	}
 
-	err = dns.DeleteHostOverride(e)
-	if err != nil {
-		return err
+	// delete every round-robin entry at once
+	if strings.Contains(e.IP, ",") {
+		return dns.UpdateRoundRobin(e, nil, strings.Split(e.IP, ","))
 	}
 
-	return nil
+	return dns.DeleteHostOverride(e)
 }
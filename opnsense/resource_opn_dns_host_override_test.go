@@ -0,0 +1,413 @@
+package opnsense
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+// dnsRoundRobinState returns the state of a round-robin override of www.acme.local
+func dnsRoundRobinState(ips ...string) *terraform.InstanceState {
+	attrs := map[string]string{
+		KeyDNSRecordType:        "A",
+		KeyDNSHost:              "www",
+		KeyDNSDomain:            "acme.local",
+		KeyDNSTTL:               "0",
+		KeyDNSMXPriority:        "0",
+		KeyDNSApplyImmediately:  "true",
+		KeyDNSOverwriteExisting: "false",
+		KeyDNSFQDN:              "www.acme.local",
+		KeyDNSIPs + ".#":        fmt.Sprintf("%d", len(ips)),
+	}
+	for _, ip := range ips {
+		attrs[fmt.Sprintf("%s.%d", KeyDNSIPs, schema.HashString(ip))] = ip
+	}
+	return &terraform.InstanceState{
+		ID:         "A/www/acme.local/192.168.0.1,192.168.0.2/-1",
+		Attributes: attrs,
+	}
+}
+
+func TestDNSRoundRobinDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      map[string]interface{}
+		requiresNew bool
+	}{
+		{
+			name: "IP added in place",
+			config: map[string]interface{}{
+				KeyDNSHost:   "www",
+				KeyDNSDomain: "acme.local",
+				KeyDNSIPs:    []interface{}{"192.168.0.1", "192.168.0.2", "192.168.0.3"},
+			},
+		},
+		{
+			name: "host changed",
+			config: map[string]interface{}{
+				KeyDNSHost:   "web",
+				KeyDNSDomain: "acme.local",
+				KeyDNSIPs:    []interface{}{"192.168.0.1", "192.168.0.2"},
+			},
+			requiresNew: true,
+		},
+		{
+			name: "domain changed",
+			config: map[string]interface{}{
+				KeyDNSHost:   "www",
+				KeyDNSDomain: "acme.lan",
+				KeyDNSIPs:    []interface{}{"192.168.0.1", "192.168.0.2"},
+			},
+			requiresNew: true,
+		},
+		{
+			name: "TTL changed",
+			config: map[string]interface{}{
+				KeyDNSHost:   "www",
+				KeyDNSDomain: "acme.local",
+				KeyDNSIPs:    []interface{}{"192.168.0.1", "192.168.0.2"},
+				KeyDNSTTL:    300,
+			},
+			requiresNew: true,
+		},
+		{
+			name: "switched to a single IP",
+			config: map[string]interface{}{
+				KeyDNSHost:   "www",
+				KeyDNSDomain: "acme.local",
+				KeyDNSIP:     "192.168.0.1",
+			},
+			requiresNew: true,
+		},
+	}
+
+	r := resourceOpnDNSHostOverride()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := r.Diff(dnsRoundRobinState("192.168.0.1", "192.168.0.2"), terraform.NewResourceConfigRaw(tt.config), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff == nil {
+				t.Fatal("no diff")
+			}
+			if diff.RequiresNew() != tt.requiresNew {
+				t.Errorf("replacement is %v, expected %v", diff.RequiresNew(), tt.requiresNew)
+			}
+		})
+	}
+}
+
+func TestValidateDNSValue(t *testing.T) {
+	tests := []struct {
+		rr    string
+		value string
+		valid bool
+	}{
+		{"A", "192.168.0.1", true},
+		{"A", "2001:db8::1", false},
+		{"A", "www.acme.local", false},
+		{"AAAA", "2001:db8::1", true},
+		{"AAAA", "192.168.0.1", false},
+		{"AAAA", "::ffff:192.168.0.1", false},
+		{"CNAME", "www.acme.local", true},
+		{"CNAME", "www.acme.local.", true},
+		{"CNAME", "192.168.0.1", false},
+		{"CNAME", "not a host", false},
+		{"MX", "mx.acme.local", true},
+		{"MX", "2001:db8::1", false},
+		{"MX", "10 mx.acme.local", false},
+	}
+
+	for _, tt := range tests {
+		err := validateDNSValue(tt.rr, tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s %q: got error %v, expected valid to be %v", tt.rr, tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestDNSTypeValueDiff(t *testing.T) {
+	r := resourceOpnDNSHostOverride()
+
+	// inconsistent type and value are rejected at plan time
+	_, err := r.Diff(nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSType:   "A",
+		KeyDNSHost:   "www",
+		KeyDNSDomain: "acme.local",
+		KeyDNSIP:     "2001:db8::1",
+	}), nil)
+	if err == nil {
+		t.Error("AAAA address has been accepted for type A")
+	}
+
+	// MX priority is reserved to MX records
+	_, err = r.Diff(nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSType:       "CNAME",
+		KeyDNSHost:       "www",
+		KeyDNSDomain:     "acme.local",
+		KeyDNSIP:         "web.acme.local",
+		KeyDNSMXPriority: 10,
+	}), nil)
+	if err == nil {
+		t.Error("MX priority has been accepted on a CNAME override")
+	}
+
+	_, err = r.Diff(nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSType:       "MX",
+		KeyDNSHost:       "",
+		KeyDNSDomain:     "acme.local",
+		KeyDNSIP:         "mx.acme.local",
+		KeyDNSMXPriority: 10,
+	}), nil)
+	if err != nil {
+		t.Errorf("MX override has been rejected: %v", err)
+	}
+}
+
+func TestValidateDNSDomain(t *testing.T) {
+	for domain, valid := range map[string]bool{
+		"example.com":  true,
+		"Example.COM.": true,
+		"acme.local":   true,
+		"ex ample.com": false,
+		"example..com": false,
+		"-example.com": false,
+		"":             false,
+		" ":            false,
+	} {
+		_, errs := validateDNSDomain(domain, KeyDNSDomain)
+		if (len(errs) == 0) != valid {
+			t.Errorf("%q: got errors %v, expected valid to be %v", domain, errs, valid)
+		}
+	}
+}
+
+func TestDNSDomainDiff(t *testing.T) {
+	r := resourceOpnDNSHostOverride()
+
+	// domains differing only by case and trailing dot are the same
+	diff, err := r.Diff(dnsRoundRobinState("192.168.0.1", "192.168.0.2"), terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSHost:   "www",
+		KeyDNSDomain: "Acme.LOCAL.",
+		KeyDNSIPs:    []interface{}{"192.168.0.1", "192.168.0.2"},
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff != nil && !diff.Empty() {
+		t.Errorf("unexpected diff %v", diff)
+	}
+}
+
+func TestDNSInferType(t *testing.T) {
+	for value, expected := range map[string]string{
+		"192.168.0.1":     "A",
+		"2001:db8::1":     "AAAA",
+		"::ffff:10.0.0.1": "A",
+		"www.acme.local":  "",
+		"":                "",
+	} {
+		if rr := dnsInferType(value); rr != expected {
+			t.Errorf("%q inferred as %q, expected %q", value, rr, expected)
+		}
+	}
+}
+
+func TestDNSTypeInferenceDiff(t *testing.T) {
+	r := resourceOpnDNSHostOverride()
+
+	// omitted type follows the IP address family
+	diff, err := r.Diff(nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSHost:   "www",
+		KeyDNSDomain: "acme.local",
+		KeyDNSIP:     "2001:db8::1",
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := diff.Attributes[KeyDNSRecordType]; a == nil || a.New != "AAAA" {
+		t.Errorf("record type planned as %+v, expected AAAA", a)
+	}
+
+	// including when the address changes family afterwards
+	state := &terraform.InstanceState{
+		ID: "A/www/acme.local/192.168.0.1/0",
+		Attributes: map[string]string{
+			KeyDNSRecordType:        "A",
+			KeyDNSHost:              "www",
+			KeyDNSDomain:            "acme.local",
+			KeyDNSIP:                "192.168.0.1",
+			KeyDNSTTL:               "0",
+			KeyDNSMXPriority:        "0",
+			KeyDNSApplyImmediately:  "true",
+			KeyDNSOverwriteExisting: "false",
+			KeyDNSFQDN:              "www.acme.local",
+		},
+	}
+	diff, err = r.Diff(state, terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSHost:   "www",
+		KeyDNSDomain: "acme.local",
+		KeyDNSIP:     "2001:db8::1",
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := diff.Attributes[KeyDNSRecordType]; a == nil || a.New != "AAAA" {
+		t.Errorf("record type planned as %+v, expected AAAA", a)
+	}
+	if a := diff.Attributes[KeyDNSType]; a != nil {
+		t.Errorf("omitted type planned as %+v", a)
+	}
+
+	// but an explicit type is never inferred over
+	state.Attributes[KeyDNSType] = "A"
+	_, err = r.Diff(state, terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSType:   "A",
+		KeyDNSHost:   "www",
+		KeyDNSDomain: "acme.local",
+		KeyDNSIP:     "2001:db8::1",
+	}), nil)
+	if err == nil || !strings.Contains(err.Error(), "expects an IPv4 address") {
+		t.Errorf("type A accepted an IPv6 address, got error %v", err)
+	}
+
+	// other record types can't be inferred
+	_, err = r.Diff(nil, terraform.NewResourceConfigRaw(map[string]interface{}{
+		KeyDNSHost:   "www",
+		KeyDNSDomain: "acme.local",
+		KeyDNSIP:     "web.acme.local",
+	}), nil)
+	if err == nil {
+		t.Error("type has been inferred from a host name")
+	}
+}
+
+func TestDNSSetType(t *testing.T) {
+	r := resourceOpnDNSHostOverride()
+
+	// imported entries only get the type which can't be inferred
+	for rr, value := range map[string]string{
+		"A":     "192.168.0.1",
+		"AAAA":  "2001:db8::1",
+		"CNAME": "web.acme.local",
+	} {
+		d := r.TestResourceData()
+		dnsSetType(d, rr, value)
+		expected := ""
+		if rr == "CNAME" {
+			expected = rr
+		}
+		if d.Get(KeyDNSRecordType).(string) != rr || d.Get(KeyDNSType).(string) != expected {
+			t.Errorf("%s %s read as type %q and record type %q", rr, value, d.Get(KeyDNSType), d.Get(KeyDNSRecordType))
+		}
+	}
+
+	// while configured ones follow the live entry
+	d := r.TestResourceData()
+	d.Set(KeyDNSType, "A")
+	dnsSetType(d, "AAAA", "2001:db8::1")
+	if d.Get(KeyDNSType).(string) != "AAAA" {
+		t.Errorf("configured type read as %q, expected AAAA", d.Get(KeyDNSType))
+	}
+}
+
+func TestValidateDNSHost(t *testing.T) {
+	for host, valid := range map[string]bool{
+		"www":    true,
+		"*":      true,
+		"_dmarc": true,
+		"a.b":    true,
+		"*.www":  false,
+		"w*w":    false,
+		"**":     false,
+		"ww w":   false,
+		"":       false,
+	} {
+		_, errs := validateDNSHost(host, KeyDNSHost)
+		if (len(errs) == 0) != valid {
+			t.Errorf("%q: got errors %v, expected valid to be %v", host, errs, valid)
+		}
+	}
+}
+
+func TestDNSResourceIDEscapesTXT(t *testing.T) {
+	e := DNSHostEntry{ID: 3, Type: "TXT", Host: "_dmarc", Domain: "acme.local", IP: "v=DMARC1; p=reject; rua=mailto:a/b@acme.local"}
+	id := dnsResourceID(&e)
+	if strings.Count(id, "/") != 4 {
+		t.Errorf("resource ID %s holds unescaped slashes", id)
+	}
+	res, err := parseDNSResourceID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IP != e.IP || res.ID != e.ID {
+		t.Errorf("parsed back %+v, expected %+v", res, e)
+	}
+}
+
+func TestDNSHostOverrideImportAdopts(t *testing.T) {
+	f := newDNSWebUI(
+		DNSHostEntry{Type: "A", Host: "www", Domain: "acme.local", IP: "192.168.0.1"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.4"},
+		DNSHostEntry{Type: "A", Host: "ftp", Domain: "acme.local", IP: "192.168.0.3"},
+		DNSHostEntry{Type: "A", Host: "mx", Domain: "acme.local", IP: "192.168.0.5", Description: DNSNamedDescription("mail")},
+		DNSHostEntry{Type: "A", Host: "smtp", Domain: "acme.local", IP: "192.168.0.6", Description: "relay"},
+	)
+	pconf := &ProviderConfiguration{
+		DNS:       &DNSSession{OPN: newTestSession(t, f)},
+		Semaphore: NewSemaphore(1),
+	}
+	r := resourceOpnDNSHostOverride()
+
+	for id, tc := range map[string]struct {
+		expected string
+		ips      []string
+	}{
+		// type/host/domain IDs resolve to the live entry, whatever its value
+		"A/www/acme.local": {expected: "A/www/acme.local/192.168.0.1/0"},
+		// entries named by the provider keep their name, others are identified by their values
+		"A/mx/acme.local":   {expected: "name:mail"},
+		"A/smtp/acme.local": {expected: "A/smtp/acme.local/192.168.0.6/4"},
+		// round-robin records are adopted as a whole
+		"A/ftp/acme.local": {expected: "A/ftp/acme.local/192.168.0.3,192.168.0.4/1", ips: []string{"192.168.0.3", "192.168.0.4"}},
+		// full resource IDs are taken as they are
+		"A/www/acme.local/192.168.0.1/0": {expected: "A/www/acme.local/192.168.0.1/0"},
+		"name:www.acme.local":            {expected: "name:www.acme.local"},
+	} {
+		d := r.TestResourceData()
+		d.SetId(id)
+		res, err := r.Importer.State(d, pconf)
+		if err != nil {
+			t.Errorf("%s: %v", id, err)
+			continue
+		}
+		if len(res) != 1 || res[0].Id() != tc.expected {
+			t.Errorf("%s: imported as %s, expected %s", id, res[0].Id(), tc.expected)
+		}
+		ips := []string{}
+		for _, ip := range res[0].Get(KeyDNSIPs).(*schema.Set).List() {
+			ips = append(ips, ip.(string))
+		}
+		sort.Strings(ips)
+		if tc.ips != nil && !reflect.DeepEqual(ips, tc.ips) {
+			t.Errorf("%s: got ips %v, expected %v", id, ips, tc.ips)
+		}
+		if !res[0].Get(KeyDNSApplyImmediately).(bool) || res[0].Get(KeyDNSOverwriteExisting).(bool) {
+			t.Errorf("%s: settings not read out of OPNsense don't have their default values", id)
+		}
+	}
+
+	// records without any entry can't be adopted
+	d := r.TestResourceData()
+	d.SetId("A/mail/acme.local")
+	_, err := r.Importer.State(d, pconf)
+	if err == nil || !strings.Contains(err.Error(), ErrDNSNoSuchEntry) {
+		t.Errorf("unexpected import error %v", err)
+	}
+}
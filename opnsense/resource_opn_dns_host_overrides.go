@@ -0,0 +1,214 @@
+package opnsense
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/hashcode"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyDNSRecord corresponds to the associated resource schema key
+	KeyDNSRecord = "record"
+)
+
+func resourceOpnDNSHostOverrides() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDNSHostOverridesCreate,
+		Read:   resourceDNSHostOverridesRead,
+		Update: resourceDNSHostOverridesUpdate,
+		Delete: resourceDNSHostOverridesDelete,
+
+		Schema: map[string]*schema.Schema{
+			KeyDNSRecord: {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						KeyDNSType: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+						},
+						KeyDNSHost: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateDNSHost,
+						},
+						KeyDNSDomain: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validateDNSDomain,
+							StateFunc:    dnsDomainStateFunc,
+						},
+						KeyDNSIP: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dnsRecordsFromSet(set *schema.Set) []DNSHostEntry {
+	entries := []DNSHostEntry{}
+	for _, r := range set.List() {
+		rec := r.(map[string]interface{})
+		entries = append(entries, DNSHostEntry{
+			Type:   rec[KeyDNSType].(string),
+			Host:   rec[KeyDNSHost].(string),
+			Domain: NormalizeDomain(rec[KeyDNSDomain].(string)),
+			IP:     rec[KeyDNSIP].(string),
+		})
+	}
+	return entries
+}
+
+func dnsRecordsToList(entries []DNSHostEntry) []interface{} {
+	records := []interface{}{}
+	for _, e := range entries {
+		records = append(records, map[string]interface{}{
+			KeyDNSType:   e.Type,
+			KeyDNSHost:   e.Host,
+			KeyDNSDomain: e.Domain,
+			KeyDNSIP:     e.IP,
+		})
+	}
+	return records
+}
+
+// dnsSyncRecords applies the desired records and keeps in state only the ones that actually are
+func dnsSyncRecords(d *schema.ResourceData, dns *DNSSession, current, desired []DNSHostEntry) error {
+	failures, err := dns.SyncHostOverrides(current, desired)
+	if err != nil {
+		return err
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	// keep track of what has been applied, records that couldn't be removed remain managed
+	applied := []DNSHostEntry{}
+	for _, e := range desired {
+		if _, ok := failures[e.Key()]; !ok {
+			applied = append(applied, e)
+		}
+	}
+	for _, e := range current {
+		if _, ok := failures[e.Key()]; ok {
+			applied = append(applied, e)
+		}
+	}
+	d.Set(KeyDNSRecord, dnsRecordsToList(applied))
+
+	msgs := []string{}
+	for k, e := range failures {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", k, e))
+	}
+	sort.Strings(msgs)
+	return fmt.Errorf("%d host override(s) couldn't be applied:\n%s", len(failures), strings.Join(msgs, "\n"))
+}
+
+func resourceDNSHostOverridesCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	dns := pconf.DNS
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create all host overrides at once
+	desired := dnsRecordsFromSet(d.Get(KeyDNSRecord).(*schema.Set))
+	err := dnsSyncRecords(d, dns, []DNSHostEntry{}, desired)
+
+	// set resource ID accordingly, from the initial set of records
+	keys := []string{}
+	for _, e := range desired {
+		keys = append(keys, e.Key())
+	}
+	sort.Strings(keys)
+	d.SetId(fmt.Sprintf("dns-host-overrides-%d", hashcode.String(strings.Join(keys, ","))))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceDNSHostOverridesRead(d, meta)
+
+	return err
+}
+
+func resourceDNSHostOverridesRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	entries, err := dns.GetAllHostEntries()
+	if err != nil {
+		return err
+	}
+	live := map[string]bool{}
+	for _, e := range entries {
+		live[e.Key()] = true
+	}
+
+	// only keep managed records which still exist
+	records := []DNSHostEntry{}
+	for _, e := range dnsRecordsFromSet(d.Get(KeyDNSRecord).(*schema.Set)) {
+		if live[e.Key()] {
+			records = append(records, e)
+		}
+	}
+	d.Set(KeyDNSRecord, dnsRecordsToList(records))
+
+	return nil
+}
+
+func resourceDNSHostOverridesUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+
+	o, n := d.GetChange(KeyDNSRecord)
+	err := dnsSyncRecords(d, dns, dnsRecordsFromSet(o.(*schema.Set)), dnsRecordsFromSet(n.(*schema.Set)))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceDNSHostOverridesRead(d, meta)
+
+	return err
+}
+
+func resourceDNSHostOverridesDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	current := dnsRecordsFromSet(d.Get(KeyDNSRecord).(*schema.Set))
+	err := dnsSyncRecords(d, dns, current, []DNSHostEntry{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyAliasName corresponds to the associated resource schema key
+	KeyAliasName = "name"
+	// KeyAliasType corresponds to the associated resource schema key
+	KeyAliasType = "type"
+	// KeyAliasContent corresponds to the associated resource schema key
+	KeyAliasContent = "content"
+	// KeyAliasCategories corresponds to the associated resource schema key
+	KeyAliasCategories = "categories"
+	// KeyAliasDescription corresponds to the associated resource schema key
+	KeyAliasDescription = "description"
+)
+
+func resourceOpnFirewallAlias() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceFirewallAliasCreate,
+		Read:   resourceFirewallAliasRead,
+		Update: resourceFirewallAliasUpdate,
+		Delete: resourceFirewallAliasDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyAliasName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringMatch(rxAliasName, "must only contain letters, digits and underscores"),
+			},
+			KeyAliasType: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"host", "network", "port", "url", "urltable"}, false),
+			},
+			KeyAliasContent: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+				Set:         schema.HashString,
+				Description: "Alias entries, order doesn't matter",
+			},
+			KeyAliasCategories: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+				Set:         schema.HashString,
+				Description: "UUIDs of the firewall categories the alias is tagged with",
+			},
+			KeyAliasDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func aliasFromResource(d *schema.ResourceData) *Alias {
+	a := Alias{
+		UUID:        d.Id(),
+		Name:        d.Get(KeyAliasName).(string),
+		Type:        d.Get(KeyAliasType).(string),
+		Content:     []string{},
+		Categories:  []string{},
+		Description: d.Get(KeyAliasDescription).(string),
+	}
+	for _, c := range d.Get(KeyAliasContent).(*schema.Set).List() {
+		a.Content = append(a.Content, c.(string))
+	}
+	for _, c := range d.Get(KeyAliasCategories).(*schema.Set).List() {
+		a.Categories = append(a.Categories, c.(string))
+	}
+	return &a
+}
+
+// aliasEntries returns the alias entries of a set
+func aliasEntries(set *schema.Set) []string {
+	entries := []string{}
+	for _, e := range set.List() {
+		entries = append(entries, e.(string))
+	}
+	return entries
+}
+
+func resourceFirewallAliasCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	fw := pconf.Firewall
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new alias
+	a := aliasFromResource(d)
+	err := fw.CreateAlias(a)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(a.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallAliasRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallAliasRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	a := Alias{
+		UUID: d.Id(),
+	}
+
+	// read out alias information
+	err := fw.ReadAlias(&a)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyAliasName, a.Name)
+	d.Set(KeyAliasType, a.Type)
+	d.Set(KeyAliasContent, a.Content)
+	d.Set(KeyAliasCategories, a.Categories)
+	d.Set(KeyAliasDescription, a.Description)
+
+	return nil
+}
+
+func resourceFirewallAliasUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+
+	// updated alias, only changing entries when nothing but its content changed
+	a := aliasFromResource(d)
+	var err error
+	if d.HasChange(KeyAliasContent) && !d.HasChanges(KeyAliasName, KeyAliasCategories, KeyAliasDescription) {
+		o, n := d.GetChange(KeyAliasContent)
+		added := aliasEntries(n.(*schema.Set).Difference(o.(*schema.Set)))
+		removed := aliasEntries(o.(*schema.Set).Difference(n.(*schema.Set)))
+		err = fw.UpdateAliasContent(a, added, removed)
+	} else {
+		err = fw.UpdateAlias(a)
+	}
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallAliasRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallAliasDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	a := Alias{
+		UUID: d.Id(),
+	}
+
+	err := fw.DeleteAlias(&a)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package opnsense
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyCategoryName corresponds to the associated resource schema key
+	KeyCategoryName = "name"
+	// KeyCategoryColor corresponds to the associated resource schema key
+	KeyCategoryColor = "color"
+)
+
+func resourceOpnFirewallCategory() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceFirewallCategoryCreate,
+		Read:   resourceFirewallCategoryRead,
+		Update: resourceFirewallCategoryUpdate,
+		Delete: resourceFirewallCategoryDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyCategoryName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyCategoryColor: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringMatch(rxCategoryColor, "must be an hexadecimal RGB color, e.g. ff0000"),
+				StateFunc: func(v interface{}) string {
+					return strings.ToLower(v.(string))
+				},
+			},
+		},
+	}
+}
+
+func categoryFromResource(d *schema.ResourceData) *Category {
+	return &Category{
+		UUID:  d.Id(),
+		Name:  d.Get(KeyCategoryName).(string),
+		Color: strings.ToLower(d.Get(KeyCategoryColor).(string)),
+	}
+}
+
+func resourceFirewallCategoryCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	fw := pconf.Firewall
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new category
+	c := categoryFromResource(d)
+	err := fw.CreateCategory(c)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(c.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallCategoryRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallCategoryRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	c := Category{
+		UUID: d.Id(),
+	}
+
+	// read out category information
+	err := fw.ReadCategory(&c)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyCategoryName, c.Name)
+	d.Set(KeyCategoryColor, strings.ToLower(c.Color))
+
+	return nil
+}
+
+func resourceFirewallCategoryUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+
+	// updated category
+	c := categoryFromResource(d)
+	err := fw.UpdateCategory(c)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallCategoryRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallCategoryDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	c := Category{
+		UUID: d.Id(),
+	}
+
+	err := fw.DeleteCategory(&c)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyRuleEnabled corresponds to the associated resource schema key
+	KeyRuleEnabled = "enabled"
+)
+
+func resourceOpnFirewallRule() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceFirewallRuleCreate,
+		Read:   resourceFirewallRuleRead,
+		Update: resourceFirewallRuleUpdate,
+		Delete: resourceFirewallRuleDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyRuleEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeyRuleSequence: {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntBetween(1, FirewallRuleMaxSequence),
+				Description:  "Rule position, lowest first (first match wins), after all other rules when unset",
+			},
+			KeyRuleInterface: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyRuleAction: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "pass",
+				ValidateFunc: validation.StringInSlice(FirewallRuleActions, false),
+			},
+			KeyRuleProtocol: {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "any",
+			},
+			KeyRuleSource: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "any",
+				Description: "Address, network, interface network (e.g. lan) or alias name, which must exist",
+			},
+			KeyRuleDestination: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "any",
+				Description: "Address, network, interface network (e.g. lan) or alias name, which must exist",
+			},
+			KeyRuleDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func firewallRuleFromResource(d *schema.ResourceData) *FirewallRule {
+	return &FirewallRule{
+		UUID:        d.Id(),
+		Enabled:     d.Get(KeyRuleEnabled).(bool),
+		Sequence:    d.Get(KeyRuleSequence).(int),
+		Interface:   d.Get(KeyRuleInterface).(string),
+		Action:      d.Get(KeyRuleAction).(string),
+		Protocol:    d.Get(KeyRuleProtocol).(string),
+		Source:      d.Get(KeyRuleSource).(string),
+		Destination: d.Get(KeyRuleDestination).(string),
+		Description: d.Get(KeyRuleDescription).(string),
+	}
+}
+
+func resourceFirewallRuleCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	fw := pconf.Firewall
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new rule
+	r := firewallRuleFromResource(d)
+	err := fw.CreateRule(r)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(r.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallRuleRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallRuleRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	r := FirewallRule{
+		UUID: d.Id(),
+	}
+
+	// read out rule information
+	err := fw.ReadRule(&r)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params, the actual position reveals reordering
+	d.Set(KeyRuleEnabled, r.Enabled)
+	d.Set(KeyRuleSequence, r.Sequence)
+	d.Set(KeyRuleInterface, r.Interface)
+	d.Set(KeyRuleAction, r.Action)
+	d.Set(KeyRuleProtocol, r.Protocol)
+	d.Set(KeyRuleSource, r.Source)
+	d.Set(KeyRuleDestination, r.Destination)
+	d.Set(KeyRuleDescription, r.Description)
+
+	return nil
+}
+
+func resourceFirewallRuleUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+
+	// updated rule
+	r := firewallRuleFromResource(d)
+	err := fw.UpdateRule(r)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallRuleRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallRuleDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	r := FirewallRule{
+		UUID: d.Id(),
+	}
+
+	err := fw.DeleteRule(&r)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyScheduleName corresponds to the associated resource schema key
+	KeyScheduleName = "name"
+	// KeyScheduleDescription corresponds to the associated resource schema key
+	KeyScheduleDescription = "description"
+	// KeyScheduleTimeRange corresponds to the associated resource schema key
+	KeyScheduleTimeRange = "time_range"
+	// KeyScheduleDays corresponds to the associated resource schema key
+	KeyScheduleDays = "days"
+	// KeyScheduleStart corresponds to the associated resource schema key
+	KeyScheduleStart = "start"
+	// KeyScheduleStop corresponds to the associated resource schema key
+	KeyScheduleStop = "stop"
+)
+
+func resourceOpnFirewallSchedule() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceFirewallScheduleCreate,
+		Read:   resourceFirewallScheduleRead,
+		Update: resourceFirewallScheduleUpdate,
+		Delete: resourceFirewallScheduleDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyScheduleName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringMatch(rxAliasName, "must only contain letters, digits and underscores"),
+			},
+			KeyScheduleDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			KeyScheduleTimeRange: {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						KeyScheduleDays: {
+							Type:     schema.TypeSet,
+							Required: true,
+							MinItems: 1,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validation.StringInSlice(ScheduleDays, false),
+							},
+							Set: schema.HashString,
+						},
+						KeyScheduleStart: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringMatch(rxScheduleTime, "must be a HH:MM time"),
+						},
+						KeyScheduleStop: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringMatch(rxScheduleTime, "must be a HH:MM time"),
+						},
+						KeyScheduleDescription: {
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func scheduleFromResource(d *schema.ResourceData) *Schedule {
+	sc := Schedule{
+		ID:          -1,
+		Name:        d.Get(KeyScheduleName).(string),
+		Description: d.Get(KeyScheduleDescription).(string),
+		TimeRanges:  []ScheduleTimeRange{},
+	}
+
+	for _, v := range d.Get(KeyScheduleTimeRange).([]interface{}) {
+		tr := v.(map[string]interface{})
+		r := ScheduleTimeRange{
+			Days:        []string{},
+			Start:       tr[KeyScheduleStart].(string),
+			Stop:        tr[KeyScheduleStop].(string),
+			Description: tr[KeyScheduleDescription].(string),
+		}
+		// keep days in week order, as OPNsense does
+		days := tr[KeyScheduleDays].(*schema.Set)
+		for _, day := range ScheduleDays {
+			if days.Contains(day) {
+				r.Days = append(r.Days, day)
+			}
+		}
+		sc.TimeRanges = append(sc.TimeRanges, r)
+	}
+
+	return &sc
+}
+
+func resourceFirewallScheduleCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	fw := pconf.Firewall
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new schedule
+	sc := scheduleFromResource(d)
+	err := fw.CreateSchedule(sc)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(sc.Name)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallScheduleRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallScheduleRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	sc := Schedule{
+		Name: d.Id(),
+	}
+
+	// read out schedule information
+	err := fw.ReadSchedule(&sc)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	ranges := []map[string]interface{}{}
+	for _, r := range sc.TimeRanges {
+		ranges = append(ranges, map[string]interface{}{
+			KeyScheduleDays:        r.Days,
+			KeyScheduleStart:       r.Start,
+			KeyScheduleStop:        r.Stop,
+			KeyScheduleDescription: r.Description,
+		})
+	}
+
+	// set object params
+	d.Set(KeyScheduleName, sc.Name)
+	d.Set(KeyScheduleDescription, sc.Description)
+	d.Set(KeyScheduleTimeRange, ranges)
+
+	return nil
+}
+
+func resourceFirewallScheduleUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+
+	// updated schedule
+	sc := scheduleFromResource(d)
+	err := fw.UpdateSchedule(sc)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceFirewallScheduleRead(d, meta)
+
+	return err
+}
+
+func resourceFirewallScheduleDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	fw := pconf.Firewall
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	sc := Schedule{
+		Name: d.Id(),
+	}
+
+	err := fw.DeleteSchedule(&sc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
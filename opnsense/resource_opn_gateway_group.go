@@ -0,0 +1,207 @@
+package opnsense
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyGatewayGroupName corresponds to the associated resource schema key
+	KeyGatewayGroupName = "name"
+	// KeyGatewayGroupTrigger corresponds to the associated resource schema key
+	KeyGatewayGroupTrigger = "trigger"
+	// KeyGatewayGroupDescription corresponds to the associated resource schema key
+	KeyGatewayGroupDescription = "description"
+	// KeyGatewayGroupMember corresponds to the associated resource schema key
+	KeyGatewayGroupMember = "member"
+	// KeyGatewayGroupGateway corresponds to the associated resource schema key
+	KeyGatewayGroupGateway = "gateway"
+	// KeyGatewayGroupTier corresponds to the associated resource schema key
+	KeyGatewayGroupTier = "tier"
+)
+
+func resourceOpnGatewayGroup() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceGatewayGroupCreate,
+		Read:   resourceGatewayGroupRead,
+		Update: resourceGatewayGroupUpdate,
+		Delete: resourceGatewayGroupDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyGatewayGroupName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringMatch(rxAliasName, "must only contain letters, digits and underscores"),
+			},
+			KeyGatewayGroupTrigger: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      GatewayGroupTriggers[0],
+				ValidateFunc: validation.StringInSlice(GatewayGroupTriggers, false),
+				Description:  "Condition a member gateway is considered down on",
+			},
+			KeyGatewayGroupDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			KeyGatewayGroupMember: {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						KeyGatewayGroupGateway: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+						},
+						KeyGatewayGroupTier: {
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      1,
+							ValidateFunc: validation.IntBetween(1, GatewayMaxTier),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func gatewayGroupFromResource(d *schema.ResourceData) (*GatewayGroup, error) {
+	g := GatewayGroup{
+		ID:          -1,
+		Name:        d.Get(KeyGatewayGroupName).(string),
+		Trigger:     d.Get(KeyGatewayGroupTrigger).(string),
+		Description: d.Get(KeyGatewayGroupDescription).(string),
+		Tiers:       map[string]int{},
+	}
+
+	for _, v := range d.Get(KeyGatewayGroupMember).(*schema.Set).List() {
+		m := v.(map[string]interface{})
+		name := m[KeyGatewayGroupGateway].(string)
+		if _, ok := g.Tiers[name]; ok {
+			return nil, fmt.Errorf("gateway %s is set several times in group %s", name, g.Name)
+		}
+		g.Tiers[name] = m[KeyGatewayGroupTier].(int)
+	}
+
+	return &g, nil
+}
+
+func resourceGatewayGroupCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	gw := pconf.Gateway
+	lock := pconf.Semaphore
+
+	g, err := gatewayGroupFromResource(d)
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+
+	// create a new gateway group
+	err = gw.CreateGatewayGroup(g)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(g.Name)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceGatewayGroupRead(d, meta)
+
+	return err
+}
+
+func resourceGatewayGroupRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	gw := pconf.Gateway
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	g := GatewayGroup{
+		Name: d.Id(),
+	}
+
+	// read out gateway group information
+	err := gw.ReadGatewayGroup(&g)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	members := []map[string]interface{}{}
+	for _, name := range g.Gateways() {
+		members = append(members, map[string]interface{}{
+			KeyGatewayGroupGateway: name,
+			KeyGatewayGroupTier:    g.Tiers[name],
+		})
+	}
+
+	// set object params
+	d.Set(KeyGatewayGroupName, g.Name)
+	d.Set(KeyGatewayGroupTrigger, g.Trigger)
+	d.Set(KeyGatewayGroupDescription, g.Description)
+	d.Set(KeyGatewayGroupMember, members)
+
+	return nil
+}
+
+func resourceGatewayGroupUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	gw := pconf.Gateway
+
+	g, err := gatewayGroupFromResource(d)
+	if err != nil {
+		return err
+	}
+
+	lock.Lock()
+
+	// updated gateway group
+	err = gw.UpdateGatewayGroup(g)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceGatewayGroupRead(d, meta)
+
+	return err
+}
+
+func resourceGatewayGroupDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	gw := pconf.Gateway
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	g := GatewayGroup{
+		Name: d.Id(),
+	}
+
+	err := gw.DeleteGatewayGroup(&g)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyHAProxyEnabled corresponds to the associated resource schema key
+	KeyHAProxyEnabled = "enabled"
+	// KeyHAProxyName corresponds to the associated resource schema key
+	KeyHAProxyName = "name"
+	// KeyHAProxyMode corresponds to the associated resource schema key
+	KeyHAProxyMode = "mode"
+	// KeyHAProxyServer corresponds to the associated resource schema key
+	KeyHAProxyServer = "server"
+	// KeyHAProxyServerAddress corresponds to the associated resource schema key
+	KeyHAProxyServerAddress = "address"
+	// KeyHAProxyServerPort corresponds to the associated resource schema key
+	KeyHAProxyServerPort = "port"
+	// KeyHAProxyDescription corresponds to the associated resource schema key
+	KeyHAProxyDescription = "description"
+)
+
+func resourceOpnHAProxyBackend() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceHAProxyBackendCreate,
+		Read:   resourceHAProxyBackendRead,
+		Update: resourceHAProxyBackendUpdate,
+		Delete: resourceHAProxyBackendDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyHAProxyEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeyHAProxyName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyHAProxyMode: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "http",
+				ValidateFunc: validation.StringInSlice(HAProxyBackendModes, false),
+			},
+			KeyHAProxyServer: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						KeyHAProxyName: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+						},
+						KeyHAProxyServerAddress: {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+						},
+						KeyHAProxyServerPort: {
+							Type:         schema.TypeInt,
+							Required:     true,
+							ValidateFunc: validation.IsPortNumber,
+						},
+					},
+				},
+			},
+			KeyHAProxyDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func haproxyBackendFromResource(d *schema.ResourceData) *HAProxyBackend {
+	b := HAProxyBackend{
+		UUID:        d.Id(),
+		Enabled:     d.Get(KeyHAProxyEnabled).(bool),
+		Name:        d.Get(KeyHAProxyName).(string),
+		Mode:        d.Get(KeyHAProxyMode).(string),
+		Servers:     []HAProxyServer{},
+		Description: d.Get(KeyHAProxyDescription).(string),
+	}
+	for _, v := range d.Get(KeyHAProxyServer).(*schema.Set).List() {
+		srv := v.(map[string]interface{})
+		b.Servers = append(b.Servers, HAProxyServer{
+			Name:    srv[KeyHAProxyName].(string),
+			Address: srv[KeyHAProxyServerAddress].(string),
+			Port:    srv[KeyHAProxyServerPort].(int),
+		})
+	}
+	return &b
+}
+
+func resourceHAProxyBackendCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	haproxy := pconf.HAProxy
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new backend
+	b := haproxyBackendFromResource(d)
+	err := haproxy.CreateBackend(b)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(b.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceHAProxyBackendRead(d, meta)
+
+	return err
+}
+
+func resourceHAProxyBackendRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	haproxy := pconf.HAProxy
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	b := HAProxyBackend{
+		UUID: d.Id(),
+	}
+
+	// read out backend information
+	err := haproxy.ReadBackend(&b)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	servers := []map[string]interface{}{}
+	for _, srv := range b.Servers {
+		servers = append(servers, map[string]interface{}{
+			KeyHAProxyName:          srv.Name,
+			KeyHAProxyServerAddress: srv.Address,
+			KeyHAProxyServerPort:    srv.Port,
+		})
+	}
+	d.Set(KeyHAProxyEnabled, b.Enabled)
+	d.Set(KeyHAProxyName, b.Name)
+	d.Set(KeyHAProxyMode, b.Mode)
+	d.Set(KeyHAProxyServer, servers)
+	d.Set(KeyHAProxyDescription, b.Description)
+
+	return nil
+}
+
+func resourceHAProxyBackendUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	haproxy := pconf.HAProxy
+
+	lock.Lock()
+
+	// updated backend
+	b := haproxyBackendFromResource(d)
+	err := haproxy.UpdateBackend(b)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceHAProxyBackendRead(d, meta)
+
+	return err
+}
+
+func resourceHAProxyBackendDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	haproxy := pconf.HAProxy
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	b := HAProxyBackend{
+		UUID: d.Id(),
+	}
+
+	err := haproxy.DeleteBackend(&b)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyHAProxyBind corresponds to the associated resource schema key
+	KeyHAProxyBind = "bind"
+	// KeyHAProxyDefaultBackend corresponds to the associated resource schema key
+	KeyHAProxyDefaultBackend = "default_backend"
+)
+
+func resourceOpnHAProxyFrontend() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceHAProxyFrontendCreate,
+		Read:   resourceHAProxyFrontendRead,
+		Update: resourceHAProxyFrontendUpdate,
+		Delete: resourceHAProxyFrontendDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyHAProxyEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeyHAProxyName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyHAProxyBind: {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+				Set:         schema.HashString,
+				Description: "Listen addresses, as address:port (e.g. 0.0.0.0:443)",
+			},
+			KeyHAProxyMode: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "http",
+				ValidateFunc: validation.StringInSlice(HAProxyFrontendModes, false),
+			},
+			KeyHAProxyDefaultBackend: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "UUID of the backend traffic is forwarded to by default",
+			},
+			KeyHAProxyDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func haproxyFrontendFromResource(d *schema.ResourceData) *HAProxyFrontend {
+	f := HAProxyFrontend{
+		UUID:           d.Id(),
+		Enabled:        d.Get(KeyHAProxyEnabled).(bool),
+		Name:           d.Get(KeyHAProxyName).(string),
+		Bind:           []string{},
+		Mode:           d.Get(KeyHAProxyMode).(string),
+		DefaultBackend: d.Get(KeyHAProxyDefaultBackend).(string),
+		Description:    d.Get(KeyHAProxyDescription).(string),
+	}
+	for _, b := range d.Get(KeyHAProxyBind).(*schema.Set).List() {
+		f.Bind = append(f.Bind, b.(string))
+	}
+	return &f
+}
+
+func resourceHAProxyFrontendCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	haproxy := pconf.HAProxy
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new frontend
+	f := haproxyFrontendFromResource(d)
+	err := haproxy.CreateFrontend(f)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(f.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceHAProxyFrontendRead(d, meta)
+
+	return err
+}
+
+func resourceHAProxyFrontendRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	haproxy := pconf.HAProxy
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	f := HAProxyFrontend{
+		UUID: d.Id(),
+	}
+
+	// read out frontend information
+	err := haproxy.ReadFrontend(&f)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyHAProxyEnabled, f.Enabled)
+	d.Set(KeyHAProxyName, f.Name)
+	d.Set(KeyHAProxyBind, f.Bind)
+	d.Set(KeyHAProxyMode, f.Mode)
+	d.Set(KeyHAProxyDefaultBackend, f.DefaultBackend)
+	d.Set(KeyHAProxyDescription, f.Description)
+
+	return nil
+}
+
+func resourceHAProxyFrontendUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	haproxy := pconf.HAProxy
+
+	lock.Lock()
+
+	// updated frontend
+	f := haproxyFrontendFromResource(d)
+	err := haproxy.UpdateFrontend(f)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceHAProxyFrontendRead(d, meta)
+
+	return err
+}
+
+func resourceHAProxyFrontendDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	haproxy := pconf.HAProxy
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	f := HAProxyFrontend{
+		UUID: d.Id(),
+	}
+
+	err := haproxy.DeleteFrontend(&f)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
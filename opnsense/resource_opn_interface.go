@@ -0,0 +1,207 @@
+package opnsense
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyInterfaceName corresponds to the associated resource schema key
+	KeyInterfaceName = "name"
+	// KeyInterfaceEnabled corresponds to the associated resource schema key
+	KeyInterfaceEnabled = "enabled"
+	// KeyInterfaceDescription corresponds to the associated resource schema key
+	KeyInterfaceDescription = "description"
+	// KeyInterfaceIPv4Type corresponds to the associated resource schema key
+	KeyInterfaceIPv4Type = "ipv4_type"
+	// KeyInterfaceIPAddress corresponds to the associated resource schema key
+	KeyInterfaceIPAddress = "ipv4_address"
+	// KeyInterfaceBits corresponds to the associated resource schema key
+	KeyInterfaceBits = "ipv4_subnet_bits"
+)
+
+func resourceOpnInterface() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceInterfaceCreate,
+		Read:   resourceInterfaceRead,
+		Update: resourceInterfaceUpdate,
+		Delete: resourceInterfaceDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		CustomizeDiff: resourceInterfaceCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			KeyInterfaceName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				Description:  "Logical interface name (e.g. lan, opt3), assigned beforehand",
+			},
+			KeyInterfaceEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeyInterfaceDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			KeyInterfaceIPv4Type: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "none",
+				ValidateFunc: validation.StringInSlice(InterfaceIPv4Types, false),
+			},
+			KeyInterfaceIPAddress: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv4Address,
+			},
+			KeyInterfaceBits: {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(1, 32),
+			},
+		},
+	}
+}
+
+// resourceInterfaceCustomizeDiff checks static IPv4 configuration consistency
+func resourceInterfaceCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown(KeyInterfaceIPv4Type) {
+		return nil
+	}
+	static := d.Get(KeyInterfaceIPv4Type).(string) == "staticv4"
+	address := d.Get(KeyInterfaceIPAddress).(string)
+	bits := d.Get(KeyInterfaceBits).(int)
+	if static && d.NewValueKnown(KeyInterfaceIPAddress) && (address == "" || bits == 0) {
+		return fmt.Errorf("%s and %s are required with %s staticv4", KeyInterfaceIPAddress, KeyInterfaceBits, KeyInterfaceIPv4Type)
+	}
+	if !static && (address != "" || bits != 0) {
+		return fmt.Errorf("%s and %s can only be set with %s staticv4", KeyInterfaceIPAddress, KeyInterfaceBits, KeyInterfaceIPv4Type)
+	}
+	return nil
+}
+
+func interfaceFromResource(d *schema.ResourceData) *Interface {
+	return &Interface{
+		Name:        d.Get(KeyInterfaceName).(string),
+		Enabled:     d.Get(KeyInterfaceEnabled).(bool),
+		Description: d.Get(KeyInterfaceDescription).(string),
+		IPv4Type:    d.Get(KeyInterfaceIPv4Type).(string),
+		IPAddress:   d.Get(KeyInterfaceIPAddress).(string),
+		Bits:        d.Get(KeyInterfaceBits).(int),
+	}
+}
+
+func resourceInterfaceCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	ifaces := pconf.Interface
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// configure the assigned interface
+	i := interfaceFromResource(d)
+	err := ifaces.UpdateInterface(i)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(i.Name)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceInterfaceRead(d, meta)
+
+	return err
+}
+
+func resourceInterfaceRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ifaces := pconf.Interface
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	i := Interface{
+		Name: d.Id(),
+	}
+
+	// read out interface configuration
+	err := ifaces.ReadInterface(&i)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyInterfaceName, i.Name)
+	d.Set(KeyInterfaceEnabled, i.Enabled)
+	d.Set(KeyInterfaceDescription, i.Description)
+	d.Set(KeyInterfaceIPv4Type, i.IPv4Type)
+	if i.IPv4Type == "staticv4" {
+		d.Set(KeyInterfaceIPAddress, i.IPAddress)
+		d.Set(KeyInterfaceBits, i.Bits)
+	} else {
+		d.Set(KeyInterfaceIPAddress, "")
+		d.Set(KeyInterfaceBits, 0)
+	}
+
+	return nil
+}
+
+func resourceInterfaceUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ifaces := pconf.Interface
+
+	lock.Lock()
+
+	// updated interface
+	i := interfaceFromResource(d)
+	err := ifaces.UpdateInterface(i)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceInterfaceRead(d, meta)
+
+	return err
+}
+
+func resourceInterfaceDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ifaces := pconf.Interface
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// interface assignment is left as is, only disable it
+	i := Interface{
+		Name: d.Id(),
+	}
+	err := ifaces.ReadInterface(&i)
+	if err != nil {
+		return err
+	}
+	i.Enabled = false
+
+	err = ifaces.UpdateInterface(&i)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyInterfaceGroupName corresponds to the associated resource schema key
+	KeyInterfaceGroupName = "name"
+	// KeyInterfaceGroupMembers corresponds to the associated resource schema key
+	KeyInterfaceGroupMembers = "members"
+	// KeyInterfaceGroupDescription corresponds to the associated resource schema key
+	KeyInterfaceGroupDescription = "description"
+)
+
+func resourceOpnInterfaceGroup() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceInterfaceGroupCreate,
+		Read:   resourceInterfaceGroupRead,
+		Update: resourceInterfaceGroupUpdate,
+		Delete: resourceInterfaceGroupDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyInterfaceGroupName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringMatch(rxInterfaceGroupName, "must only contain letters, digits and underscores, and not end with a digit"),
+			},
+			KeyInterfaceGroupMembers: {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+				Set:         schema.HashString,
+				Description: "Logical names of the member interfaces (e.g. lan, opt1), which must be assigned",
+			},
+			KeyInterfaceGroupDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func interfaceGroupFromResource(d *schema.ResourceData) *InterfaceGroup {
+	g := InterfaceGroup{
+		ID:          -1,
+		Name:        d.Get(KeyInterfaceGroupName).(string),
+		Members:     []string{},
+		Description: d.Get(KeyInterfaceGroupDescription).(string),
+	}
+	for _, m := range d.Get(KeyInterfaceGroupMembers).(*schema.Set).List() {
+		g.Members = append(g.Members, m.(string))
+	}
+	return &g
+}
+
+func resourceInterfaceGroupCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	ifaces := pconf.Interface
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new interface group
+	g := interfaceGroupFromResource(d)
+	err := ifaces.CreateInterfaceGroup(g)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(g.Name)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceInterfaceGroupRead(d, meta)
+
+	return err
+}
+
+func resourceInterfaceGroupRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ifaces := pconf.Interface
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	g := InterfaceGroup{
+		Name: d.Id(),
+	}
+
+	// read out interface group information
+	err := ifaces.ReadInterfaceGroup(&g)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyInterfaceGroupName, g.Name)
+	d.Set(KeyInterfaceGroupMembers, g.Members)
+	d.Set(KeyInterfaceGroupDescription, g.Description)
+
+	return nil
+}
+
+func resourceInterfaceGroupUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ifaces := pconf.Interface
+
+	lock.Lock()
+
+	// updated interface group
+	g := interfaceGroupFromResource(d)
+	err := ifaces.UpdateInterfaceGroup(g)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceInterfaceGroupRead(d, meta)
+
+	return err
+}
+
+func resourceInterfaceGroupDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ifaces := pconf.Interface
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	g := InterfaceGroup{
+		Name: d.Id(),
+	}
+
+	err := ifaces.DeleteInterfaceGroup(&g)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package opnsense
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyVIPMode corresponds to the associated resource schema key
+	KeyVIPMode = "mode"
+	// KeyVIPInterface corresponds to the associated resource schema key
+	KeyVIPInterface = "interface"
+	// KeyVIPAddress corresponds to the associated resource schema key
+	KeyVIPAddress = "address"
+	// KeyVIPVHID corresponds to the associated resource schema key
+	KeyVIPVHID = "vhid"
+	// KeyVIPPassword corresponds to the associated resource schema key
+	KeyVIPPassword = "password"
+	// KeyVIPDescription corresponds to the associated resource schema key
+	KeyVIPDescription = "description"
+)
+
+func resourceOpnInterfaceVIP() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceInterfaceVIPCreate,
+		Read:   resourceInterfaceVIPRead,
+		Update: resourceInterfaceVIPUpdate,
+		Delete: resourceInterfaceVIPDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyVIPMode: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"carp", "ipalias", "proxyarp"}, false),
+			},
+			KeyVIPInterface: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyVIPAddress: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			KeyVIPVHID: {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(1, 255),
+			},
+			KeyVIPPassword: {
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+			KeyVIPDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+var vipRsID = regexp.MustCompile("([^/]+)/([^/]+)/([0-9]+)")
+
+func parseVIPResourceID(resID string) (*VIP, error) {
+	if !vipRsID.MatchString(resID) {
+		return nil, fmt.Errorf("invalid resource format: %s. must be interface/address/bits", resID)
+	}
+	idMatch := vipRsID.FindStringSubmatch(resID)
+	v := VIP{
+		Interface: idMatch[1],
+		Address:   idMatch[2],
+	}
+	v.Bits, _ = strconv.Atoi(idMatch[3])
+	return &v, nil
+}
+
+func vipResourceID(v *VIP) string {
+	return fmt.Sprintf("%s/%s/%d", v.Interface, v.Address, v.Bits)
+}
+
+func vipFromResource(d *schema.ResourceData) *VIP {
+	cidr := strings.SplitN(d.Get(KeyVIPAddress).(string), "/", 2)
+	v := VIP{
+		Mode:        d.Get(KeyVIPMode).(string),
+		Interface:   d.Get(KeyVIPInterface).(string),
+		Address:     cidr[0],
+		VHID:        d.Get(KeyVIPVHID).(int),
+		Password:    d.Get(KeyVIPPassword).(string),
+		Description: d.Get(KeyVIPDescription).(string),
+	}
+	if len(cidr) > 1 {
+		v.Bits, _ = strconv.Atoi(cidr[1])
+	}
+	return &v
+}
+
+func resourceInterfaceVIPCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	vip := pconf.VIP
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new virtual IP
+	v := vipFromResource(d)
+	if v.Mode == "carp" && (v.VHID == 0 || v.Password == "") {
+		lock.Unlock()
+		return fmt.Errorf("carp virtual IPs require both %s and %s", KeyVIPVHID, KeyVIPPassword)
+	}
+
+	err := vip.CreateVIP(v)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(vipResourceID(v))
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceInterfaceVIPRead(d, meta)
+
+	return err
+}
+
+func resourceInterfaceVIPRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	vip := pconf.VIP
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	v, err := parseVIPResourceID(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// read out virtual IP information
+	err = vip.ReadVIP(v)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyVIPMode, v.Mode)
+	d.Set(KeyVIPInterface, v.Interface)
+	d.Set(KeyVIPAddress, fmt.Sprintf("%s/%d", v.Address, v.Bits))
+	if v.Mode == "carp" {
+		d.Set(KeyVIPVHID, v.VHID)
+	}
+	d.Set(KeyVIPDescription, v.Description)
+
+	return nil
+}
+
+func resourceInterfaceVIPUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	vip := pconf.VIP
+
+	lock.Lock()
+
+	// updated virtual IP
+	v := vipFromResource(d)
+	err := vip.UpdateVIP(v)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceInterfaceVIPRead(d, meta)
+
+	return err
+}
+
+func resourceInterfaceVIPDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	vip := pconf.VIP
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	v, err := parseVIPResourceID(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	err = vip.DeleteVIP(v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
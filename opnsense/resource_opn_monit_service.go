@@ -0,0 +1,183 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyMonitEnabled corresponds to the associated resource schema key
+	KeyMonitEnabled = "enabled"
+	// KeyMonitName corresponds to the associated resource schema key
+	KeyMonitName = "name"
+	// KeyMonitType corresponds to the associated resource schema key
+	KeyMonitType = "type"
+	// KeyMonitAddress corresponds to the associated resource schema key
+	KeyMonitAddress = "address"
+	// KeyMonitTests corresponds to the associated resource schema key
+	KeyMonitTests = "tests"
+	// KeyMonitDescription corresponds to the associated resource schema key
+	KeyMonitDescription = "description"
+)
+
+func resourceOpnMonitService() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceMonitServiceCreate,
+		Read:   resourceMonitServiceRead,
+		Update: resourceMonitServiceUpdate,
+		Delete: resourceMonitServiceDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyMonitEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeyMonitName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyMonitType: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(MonitServiceTypes, false),
+			},
+			KeyMonitAddress: {
+				Type:        schema.TypeString,
+				Optional:    true,
+				Description: "Address checked by host and network services",
+			},
+			KeyMonitTests: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+				Set:         schema.HashString,
+				Description: "UUIDs of the Monit tests run against the service",
+			},
+			KeyMonitDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func monitServiceFromResource(d *schema.ResourceData) *MonitService {
+	m := MonitService{
+		UUID:        d.Id(),
+		Enabled:     d.Get(KeyMonitEnabled).(bool),
+		Name:        d.Get(KeyMonitName).(string),
+		Type:        d.Get(KeyMonitType).(string),
+		Address:     d.Get(KeyMonitAddress).(string),
+		Tests:       []string{},
+		Description: d.Get(KeyMonitDescription).(string),
+	}
+	for _, t := range d.Get(KeyMonitTests).(*schema.Set).List() {
+		m.Tests = append(m.Tests, t.(string))
+	}
+	return &m
+}
+
+func resourceMonitServiceCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	monit := pconf.Monit
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new service check
+	m := monitServiceFromResource(d)
+	err := monit.CreateService(m)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(m.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceMonitServiceRead(d, meta)
+
+	return err
+}
+
+func resourceMonitServiceRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	monit := pconf.Monit
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	m := MonitService{
+		UUID: d.Id(),
+	}
+
+	// read out service check information
+	err := monit.ReadService(&m)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyMonitEnabled, m.Enabled)
+	d.Set(KeyMonitName, m.Name)
+	d.Set(KeyMonitType, m.Type)
+	d.Set(KeyMonitAddress, m.Address)
+	d.Set(KeyMonitTests, m.Tests)
+	d.Set(KeyMonitDescription, m.Description)
+
+	return nil
+}
+
+func resourceMonitServiceUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	monit := pconf.Monit
+
+	lock.Lock()
+
+	// updated service check
+	m := monitServiceFromResource(d)
+	err := monit.UpdateService(m)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceMonitServiceRead(d, meta)
+
+	return err
+}
+
+func resourceMonitServiceDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	monit := pconf.Monit
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	m := MonitService{
+		UUID: d.Id(),
+	}
+
+	err := monit.DeleteService(&m)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyNTPServers corresponds to the associated resource schema key
+	KeyNTPServers = "servers"
+	// KeyNTPPrefer corresponds to the associated resource schema key
+	KeyNTPPrefer = "prefer"
+	// KeyNTPRestrictions corresponds to the associated resource schema key
+	KeyNTPRestrictions = "restrictions"
+)
+
+// ntpResourceID is the identity of the per-instance network time settings
+const ntpResourceID = "ntp"
+
+func resourceOpnNTP() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceNTPCreate,
+		Read:   resourceNTPRead,
+		Update: resourceNTPUpdate,
+		Delete: resourceNTPDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyNTPServers: {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+				Description: "Time servers (host names or IP addresses), in order",
+			},
+			KeyNTPPrefer: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Set:         schema.HashString,
+				Description: "Time servers to prefer over the others, which must be part of servers",
+			},
+			KeyNTPRestrictions: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringInSlice(NTPRestrictions, false),
+				},
+				Set:         schema.HashString,
+				Description: "Access restrictions applied to every NTP client",
+			},
+		},
+	}
+}
+
+func ntpFromResource(d *schema.ResourceData) *NTPSettings {
+	n := NTPSettings{
+		Servers:      []string{},
+		Prefer:       []string{},
+		Restrictions: []string{},
+	}
+	for _, h := range d.Get(KeyNTPServers).([]interface{}) {
+		n.Servers = append(n.Servers, h.(string))
+	}
+	for _, p := range d.Get(KeyNTPPrefer).(*schema.Set).List() {
+		n.Prefer = append(n.Prefer, p.(string))
+	}
+	for _, r := range d.Get(KeyNTPRestrictions).(*schema.Set).List() {
+		n.Restrictions = append(n.Restrictions, r.(string))
+	}
+	return &n
+}
+
+func resourceNTPCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	ntp := pconf.NTP
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	err := ntp.UpdateNTPSettings(ntpFromResource(d))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(ntpResourceID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceNTPRead(d, meta)
+
+	return err
+}
+
+func resourceNTPRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ntp := pconf.NTP
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	n, err := ntp.ReadNTPSettings()
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyNTPServers, n.Servers)
+	d.Set(KeyNTPPrefer, n.Prefer)
+	d.Set(KeyNTPRestrictions, n.Restrictions)
+
+	return nil
+}
+
+func resourceNTPUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ntp := pconf.NTP
+
+	lock.Lock()
+
+	err := ntp.UpdateNTPSettings(ntpFromResource(d))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceNTPRead(d, meta)
+
+	return err
+}
+
+func resourceNTPDelete(d *schema.ResourceData, meta interface{}) error {
+	// singleton settings can't be removed, and dropping all time servers would
+	// let the clock drift: leave them as they are
+	return nil
+}
@@ -0,0 +1,172 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyCSCCommonName corresponds to the associated resource schema key
+	KeyCSCCommonName = "common_name"
+	// KeyCSCTunnelNetwork corresponds to the associated resource schema key
+	KeyCSCTunnelNetwork = "tunnel_network"
+	// KeyCSCDNSDomain corresponds to the associated resource schema key
+	KeyCSCDNSDomain = "dns_domain"
+	// KeyCSCDNSServers corresponds to the associated resource schema key
+	KeyCSCDNSServers = "dns_servers"
+	// KeyCSCDescription corresponds to the associated resource schema key
+	KeyCSCDescription = "description"
+)
+
+func resourceOpnOpenVPNClientOverride() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceOpenVPNClientOverrideCreate,
+		Read:   resourceOpenVPNClientOverrideRead,
+		Update: resourceOpenVPNClientOverrideUpdate,
+		Delete: resourceOpenVPNClientOverrideDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyCSCCommonName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyCSCTunnelNetwork: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsCIDR,
+			},
+			KeyCSCDNSDomain: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			KeyCSCDNSServers: {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: OpenVPNMaxDNSServers,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPAddress,
+				},
+			},
+			KeyCSCDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func clientOverrideFromResource(d *schema.ResourceData) *ClientOverride {
+	c := ClientOverride{
+		CommonName:    d.Get(KeyCSCCommonName).(string),
+		TunnelNetwork: d.Get(KeyCSCTunnelNetwork).(string),
+		DNSDomain:     d.Get(KeyCSCDNSDomain).(string),
+		DNSServers:    []string{},
+		Description:   d.Get(KeyCSCDescription).(string),
+	}
+	for _, s := range d.Get(KeyCSCDNSServers).([]interface{}) {
+		c.DNSServers = append(c.DNSServers, s.(string))
+	}
+	return &c
+}
+
+func resourceOpenVPNClientOverrideCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	ovpn := pconf.OpenVPN
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new client specific override
+	c := clientOverrideFromResource(d)
+	err := ovpn.CreateClientOverride(c)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(c.CommonName)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceOpenVPNClientOverrideRead(d, meta)
+
+	return err
+}
+
+func resourceOpenVPNClientOverrideRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ovpn := pconf.OpenVPN
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	c := ClientOverride{
+		CommonName: d.Id(),
+	}
+
+	// read out client specific override information
+	err := ovpn.ReadClientOverride(&c)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyCSCCommonName, c.CommonName)
+	d.Set(KeyCSCTunnelNetwork, c.TunnelNetwork)
+	d.Set(KeyCSCDNSDomain, c.DNSDomain)
+	d.Set(KeyCSCDNSServers, c.DNSServers)
+	d.Set(KeyCSCDescription, c.Description)
+
+	return nil
+}
+
+func resourceOpenVPNClientOverrideUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ovpn := pconf.OpenVPN
+
+	lock.Lock()
+
+	// updated client specific override
+	c := clientOverrideFromResource(d)
+	err := ovpn.UpdateClientOverride(c)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceOpenVPNClientOverrideRead(d, meta)
+
+	return err
+}
+
+func resourceOpenVPNClientOverrideDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	ovpn := pconf.OpenVPN
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	c := ClientOverride{
+		CommonName: d.Id(),
+	}
+
+	err := ovpn.DeleteClientOverride(&c)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeySyslogEnabled corresponds to the associated resource schema key
+	KeySyslogEnabled = "enabled"
+	// KeySyslogHost corresponds to the associated resource schema key
+	KeySyslogHost = "host"
+	// KeySyslogPort corresponds to the associated resource schema key
+	KeySyslogPort = "port"
+	// KeySyslogTransport corresponds to the associated resource schema key
+	KeySyslogTransport = "transport"
+	// KeySyslogFacilities corresponds to the associated resource schema key
+	KeySyslogFacilities = "facilities"
+	// KeySyslogDescription corresponds to the associated resource schema key
+	KeySyslogDescription = "description"
+)
+
+func resourceOpnSyslogTarget() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceSyslogTargetCreate,
+		Read:   resourceSyslogTargetRead,
+		Update: resourceSyslogTargetUpdate,
+		Delete: resourceSyslogTargetDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeySyslogEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeySyslogHost: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeySyslogPort: {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      514,
+				ValidateFunc: validation.IsPortNumber,
+			},
+			KeySyslogTransport: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "udp4",
+				ValidateFunc: validation.StringInSlice(SyslogTransports, false),
+			},
+			KeySyslogFacilities: {
+				Type:     schema.TypeSet,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+				Set:         schema.HashString,
+				Description: "Facilities forwarded to the target, all of them if empty",
+			},
+			KeySyslogDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func syslogTargetFromResource(d *schema.ResourceData) *SyslogTarget {
+	t := SyslogTarget{
+		UUID:        d.Id(),
+		Enabled:     d.Get(KeySyslogEnabled).(bool),
+		Host:        d.Get(KeySyslogHost).(string),
+		Port:        d.Get(KeySyslogPort).(int),
+		Transport:   d.Get(KeySyslogTransport).(string),
+		Facilities:  []string{},
+		Description: d.Get(KeySyslogDescription).(string),
+	}
+	for _, f := range d.Get(KeySyslogFacilities).(*schema.Set).List() {
+		t.Facilities = append(t.Facilities, f.(string))
+	}
+	return &t
+}
+
+func resourceSyslogTargetCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	syslog := pconf.Syslog
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new target
+	t := syslogTargetFromResource(d)
+	err := syslog.CreateTarget(t)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(t.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceSyslogTargetRead(d, meta)
+
+	return err
+}
+
+func resourceSyslogTargetRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	syslog := pconf.Syslog
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	t := SyslogTarget{
+		UUID: d.Id(),
+	}
+
+	// read out target information
+	err := syslog.ReadTarget(&t)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeySyslogEnabled, t.Enabled)
+	d.Set(KeySyslogHost, t.Host)
+	d.Set(KeySyslogPort, t.Port)
+	d.Set(KeySyslogTransport, t.Transport)
+	d.Set(KeySyslogFacilities, t.Facilities)
+	d.Set(KeySyslogDescription, t.Description)
+
+	return nil
+}
+
+func resourceSyslogTargetUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	syslog := pconf.Syslog
+
+	lock.Lock()
+
+	// updated target
+	t := syslogTargetFromResource(d)
+	err := syslog.UpdateTarget(t)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceSyslogTargetRead(d, meta)
+
+	return err
+}
+
+func resourceSyslogTargetDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	syslog := pconf.Syslog
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	t := SyslogTarget{
+		UUID: d.Id(),
+	}
+
+	err := syslog.DeleteTarget(&t)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
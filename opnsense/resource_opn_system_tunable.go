@@ -0,0 +1,148 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyTunableName corresponds to the associated resource schema key
+	KeyTunableName = "name"
+	// KeyTunableValue corresponds to the associated resource schema key
+	KeyTunableValue = "value"
+	// KeyTunableDescription corresponds to the associated resource schema key
+	KeyTunableDescription = "description"
+)
+
+func resourceOpnSystemTunable() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceSystemTunableCreate,
+		Read:   resourceSystemTunableRead,
+		Update: resourceSystemTunableUpdate,
+		Delete: resourceSystemTunableDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyTunableName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyTunableValue: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyTunableDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func tunableFromResource(d *schema.ResourceData) *Tunable {
+	return &Tunable{
+		ID:          -1,
+		Name:        d.Get(KeyTunableName).(string),
+		Value:       d.Get(KeyTunableValue).(string),
+		Description: d.Get(KeyTunableDescription).(string),
+	}
+}
+
+func resourceSystemTunableCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	tunables := pconf.Tunable
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new tunable
+	t := tunableFromResource(d)
+	err := tunables.CreateTunable(t)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(t.Name)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceSystemTunableRead(d, meta)
+
+	return err
+}
+
+func resourceSystemTunableRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	tunables := pconf.Tunable
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	t := Tunable{
+		Name: d.Id(),
+	}
+
+	// read out tunable information
+	err := tunables.ReadTunable(&t)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyTunableName, t.Name)
+	d.Set(KeyTunableValue, t.Value)
+	d.Set(KeyTunableDescription, t.Description)
+
+	return nil
+}
+
+func resourceSystemTunableUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	tunables := pconf.Tunable
+
+	lock.Lock()
+
+	// updated tunable
+	t := tunableFromResource(d)
+	err := tunables.UpdateTunable(t)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceSystemTunableRead(d, meta)
+
+	return err
+}
+
+func resourceSystemTunableDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	tunables := pconf.Tunable
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	t := Tunable{
+		Name: d.Id(),
+	}
+
+	err := tunables.DeleteTunable(&t)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyPipeBandwidth corresponds to the associated resource schema key
+	KeyPipeBandwidth = "bandwidth"
+	// KeyPipeBandwidthMetric corresponds to the associated resource schema key
+	KeyPipeBandwidthMetric = "bandwidth_metric"
+	// KeyPipeMask corresponds to the associated resource schema key
+	KeyPipeMask = "mask"
+	// KeyPipeDescription corresponds to the associated resource schema key
+	KeyPipeDescription = "description"
+	// KeyPipeNumber corresponds to the associated resource schema key
+	KeyPipeNumber = "number"
+)
+
+func resourceOpnTrafficShaperPipe() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceTrafficShaperPipeCreate,
+		Read:   resourceTrafficShaperPipeRead,
+		Update: resourceTrafficShaperPipeUpdate,
+		Delete: resourceTrafficShaperPipeDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyPipeBandwidth: {
+				Type:         schema.TypeInt,
+				Required:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			KeyPipeBandwidthMetric: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "Mbit",
+				ValidateFunc: validation.StringInSlice([]string{"bit", "Kbit", "Mbit", "Gbit"}, false),
+			},
+			KeyPipeMask: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "none",
+				ValidateFunc: validation.StringInSlice([]string{"none", "src-ip", "dst-ip"}, false),
+			},
+			KeyPipeDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			KeyPipeNumber: {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func pipeFromResource(d *schema.ResourceData) *Pipe {
+	return &Pipe{
+		UUID:            d.Id(),
+		Bandwidth:       d.Get(KeyPipeBandwidth).(int),
+		BandwidthMetric: d.Get(KeyPipeBandwidthMetric).(string),
+		Mask:            d.Get(KeyPipeMask).(string),
+		Description:     d.Get(KeyPipeDescription).(string),
+	}
+}
+
+func resourceTrafficShaperPipeCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	shaper := pconf.TrafficShaper
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new pipe
+	p := pipeFromResource(d)
+	err := shaper.CreatePipe(p)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(p.UUID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceTrafficShaperPipeRead(d, meta)
+
+	return err
+}
+
+func resourceTrafficShaperPipeRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	shaper := pconf.TrafficShaper
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	p := Pipe{
+		UUID: d.Id(),
+	}
+
+	// read out pipe information
+	err := shaper.ReadPipe(&p)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyPipeBandwidth, p.Bandwidth)
+	d.Set(KeyPipeBandwidthMetric, p.BandwidthMetric)
+	d.Set(KeyPipeMask, p.Mask)
+	d.Set(KeyPipeDescription, p.Description)
+	d.Set(KeyPipeNumber, p.Number)
+
+	return nil
+}
+
+func resourceTrafficShaperPipeUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	shaper := pconf.TrafficShaper
+
+	lock.Lock()
+
+	// updated pipe
+	p := pipeFromResource(d)
+	err := shaper.UpdatePipe(p)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceTrafficShaperPipeRead(d, meta)
+
+	return err
+}
+
+func resourceTrafficShaperPipeDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	shaper := pconf.TrafficShaper
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	p := Pipe{
+		UUID: d.Id(),
+	}
+
+	err := shaper.DeletePipe(&p)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
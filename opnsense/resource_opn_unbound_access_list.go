@@ -0,0 +1,174 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyACLName corresponds to the associated resource schema key
+	KeyACLName = "name"
+	// KeyACLAction corresponds to the associated resource schema key
+	KeyACLAction = "action"
+	// KeyACLNetworks corresponds to the associated resource schema key
+	KeyACLNetworks = "networks"
+	// KeyACLDescription corresponds to the associated resource schema key
+	KeyACLDescription = "description"
+)
+
+// UnboundACLActions lists the access list actions supported by UnboundDNS
+var UnboundACLActions = []string{
+	"allow",
+	"deny",
+	"refuse",
+	"allow snoop",
+	"deny nonlocal",
+	"refuse nonlocal",
+}
+
+func resourceOpnUnboundAccessList() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceUnboundACLCreate,
+		Read:   resourceUnboundACLRead,
+		Update: resourceUnboundACLUpdate,
+		Delete: resourceUnboundACLDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyACLName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyACLAction: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(UnboundACLActions, false),
+			},
+			KeyACLNetworks: {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsCIDR,
+				},
+			},
+			KeyACLDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func unboundACLFromResource(d *schema.ResourceData) *UnboundACL {
+	a := UnboundACL{
+		Name:        d.Get(KeyACLName).(string),
+		Action:      d.Get(KeyACLAction).(string),
+		Networks:    []string{},
+		Description: d.Get(KeyACLDescription).(string),
+	}
+	for _, n := range d.Get(KeyACLNetworks).([]interface{}) {
+		a.Networks = append(a.Networks, n.(string))
+	}
+	return &a
+}
+
+func resourceUnboundACLCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	acl := pconf.UnboundACL
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new access list
+	a := unboundACLFromResource(d)
+	err := acl.CreateACL(a)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(a.Name)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundACLRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundACLRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	acl := pconf.UnboundACL
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	a := UnboundACL{
+		Name: d.Id(),
+	}
+
+	// read out access list information
+	err := acl.ReadACL(&a)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyACLName, a.Name)
+	d.Set(KeyACLAction, a.Action)
+	d.Set(KeyACLNetworks, a.Networks)
+	d.Set(KeyACLDescription, a.Description)
+
+	return nil
+}
+
+func resourceUnboundACLUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	acl := pconf.UnboundACL
+
+	lock.Lock()
+
+	// updated access list
+	a := unboundACLFromResource(d)
+	err := acl.UpdateACL(a)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundACLRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundACLDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	acl := pconf.UnboundACL
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	a := UnboundACL{
+		Name: d.Id(),
+	}
+
+	err := acl.DeleteACL(&a)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
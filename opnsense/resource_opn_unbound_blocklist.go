@@ -0,0 +1,189 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyDNSBLEnabled corresponds to the associated resource schema key
+	KeyDNSBLEnabled = "enabled"
+	// KeyDNSBLTypes corresponds to the associated resource schema key
+	KeyDNSBLTypes = "types"
+	// KeyDNSBLURLs corresponds to the associated resource schema key
+	KeyDNSBLURLs = "blocklist_urls"
+	// KeyDNSBLWhitelist corresponds to the associated resource schema key
+	KeyDNSBLWhitelist = "whitelist_domains"
+	// KeyDNSBLBlacklist corresponds to the associated resource schema key
+	KeyDNSBLBlacklist = "blacklist_domains"
+)
+
+// dnsblResourceID is the identity of the per-instance Unbound DNS blocklist settings
+const dnsblResourceID = "dnsbl"
+
+func resourceOpnUnboundBlocklist() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceUnboundBlocklistCreate,
+		Read:   resourceUnboundBlocklistRead,
+		Update: resourceUnboundBlocklistUpdate,
+		Delete: resourceUnboundBlocklistDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyDNSBLEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeyDNSBLTypes: {
+				Type:        schema.TypeList,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Description: "OPNsense predefined blocklists identifiers (e.g. aa for AdAway)",
+			},
+			KeyDNSBLURLs: {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsURLWithScheme([]string{"http", "https"}),
+				},
+			},
+			KeyDNSBLWhitelist: {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			KeyDNSBLBlacklist: {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+func dnsblList(d *schema.ResourceData, key string) []string {
+	res := []string{}
+	for _, v := range d.Get(key).([]interface{}) {
+		res = append(res, v.(string))
+	}
+	return res
+}
+
+func dnsblFromResource(d *schema.ResourceData) *DNSBL {
+	return &DNSBL{
+		Enabled:    d.Get(KeyDNSBLEnabled).(bool),
+		Types:      dnsblList(d, KeyDNSBLTypes),
+		Lists:      dnsblList(d, KeyDNSBLURLs),
+		Whitelists: dnsblList(d, KeyDNSBLWhitelist),
+		Blocklists: dnsblList(d, KeyDNSBLBlacklist),
+	}
+}
+
+// dnsblSetList keeps configured order whenever live values are the same
+func dnsblSetList(d *schema.ResourceData, key string, live []string) {
+	set := map[string]bool{}
+	for _, v := range live {
+		set[v] = true
+	}
+	current := dnsblList(d, key)
+	if len(current) == len(live) {
+		same := true
+		for _, v := range current {
+			if !set[v] {
+				same = false
+			}
+		}
+		if same {
+			return
+		}
+	}
+	d.Set(key, live)
+}
+
+func resourceUnboundBlocklistCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	dns := pconf.DNS
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	err := dns.UpdateDNSBL(dnsblFromResource(d))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(dnsblResourceID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundBlocklistRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundBlocklistRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	b, err := dns.ReadDNSBL()
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyDNSBLEnabled, b.Enabled)
+	dnsblSetList(d, KeyDNSBLTypes, b.Types)
+	dnsblSetList(d, KeyDNSBLURLs, b.Lists)
+	dnsblSetList(d, KeyDNSBLWhitelist, b.Whitelists)
+	dnsblSetList(d, KeyDNSBLBlacklist, b.Blocklists)
+
+	return nil
+}
+
+func resourceUnboundBlocklistUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+
+	err := dns.UpdateDNSBL(dnsblFromResource(d))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundBlocklistRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundBlocklistDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// singleton settings can't be removed, disable and empty them instead
+	err := dns.UpdateDNSBL(&DNSBL{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
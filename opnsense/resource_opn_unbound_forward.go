@@ -0,0 +1,190 @@
+package opnsense
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyForwardDomain corresponds to the associated resource schema key
+	KeyForwardDomain = "domain"
+	// KeyForwardServers corresponds to the associated resource schema key
+	KeyForwardServers = "servers"
+	// KeyForwardUseTLS corresponds to the associated resource schema key
+	KeyForwardUseTLS = "use_tls"
+	// KeyForwardTLSHostname corresponds to the associated resource schema key
+	KeyForwardTLSHostname = "tls_hostname"
+	// KeyForwardDescription corresponds to the associated resource schema key
+	KeyForwardDescription = "description"
+)
+
+func resourceOpnUnboundForward() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceUnboundForwardCreate,
+		Read:   resourceUnboundForwardRead,
+		Update: resourceUnboundForwardUpdate,
+		Delete: resourceUnboundForwardDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		CustomizeDiff: resourceUnboundForwardCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			KeyForwardDomain: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateDNSDomain,
+				StateFunc:    dnsDomainStateFunc,
+			},
+			KeyForwardServers: {
+				Type:     schema.TypeSet,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPAddress,
+				},
+				Set: schema.HashString,
+			},
+			KeyForwardUseTLS: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			KeyForwardTLSHostname: {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				Description:  "Name servers TLS certificates are verified against, only with use_tls",
+			},
+			KeyForwardDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+// resourceUnboundForwardCustomizeDiff rejects TLS settings on plain DNS forwarding
+func resourceUnboundForwardCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown(KeyForwardUseTLS) || !d.NewValueKnown(KeyForwardTLSHostname) {
+		return nil
+	}
+	if !d.Get(KeyForwardUseTLS).(bool) && d.Get(KeyForwardTLSHostname).(string) != "" {
+		return fmt.Errorf("%s can only be set when %s is true", KeyForwardTLSHostname, KeyForwardUseTLS)
+	}
+	return nil
+}
+
+func unboundForwardFromResource(d *schema.ResourceData) *UnboundForward {
+	f := UnboundForward{
+		Domain:      d.Get(KeyForwardDomain).(string),
+		Servers:     []string{},
+		UseTLS:      d.Get(KeyForwardUseTLS).(bool),
+		TLSHostname: d.Get(KeyForwardTLSHostname).(string),
+		Description: d.Get(KeyForwardDescription).(string),
+	}
+	for _, s := range d.Get(KeyForwardServers).(*schema.Set).List() {
+		f.Servers = append(f.Servers, s.(string))
+	}
+	return &f
+}
+
+func resourceUnboundForwardCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	dns := pconf.DNS
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new forwarding
+	f := unboundForwardFromResource(d)
+	err := dns.CreateForward(f)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(NormalizeDomain(f.Domain))
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundForwardRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundForwardRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	f := UnboundForward{
+		Domain: d.Id(),
+	}
+
+	// read out forwarding information
+	err := dns.ReadForward(&f)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyForwardDomain, f.Domain)
+	d.Set(KeyForwardServers, f.Servers)
+	d.Set(KeyForwardUseTLS, f.UseTLS)
+	d.Set(KeyForwardTLSHostname, f.TLSHostname)
+	d.Set(KeyForwardDescription, f.Description)
+
+	return nil
+}
+
+func resourceUnboundForwardUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+
+	// updated forwarding
+	f := unboundForwardFromResource(d)
+	err := dns.UpdateForward(f)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundForwardRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundForwardDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	f := UnboundForward{
+		Domain: d.Id(),
+	}
+
+	err := dns.DeleteForward(&f)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
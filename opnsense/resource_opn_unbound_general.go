@@ -0,0 +1,137 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+const (
+	// KeyUnboundEnabled corresponds to the associated resource schema key
+	KeyUnboundEnabled = "enabled"
+	// KeyUnboundDNSSEC corresponds to the associated resource schema key
+	KeyUnboundDNSSEC = "dnssec"
+	// KeyUnboundRegisterLeases corresponds to the associated resource schema key
+	KeyUnboundRegisterLeases = "register_dhcp_leases"
+	// KeyUnboundRegisterStatics corresponds to the associated resource schema key
+	KeyUnboundRegisterStatics = "register_dhcp_static_mappings"
+)
+
+// unboundGeneralResourceID is the identity of the per-instance Unbound DNS general settings
+const unboundGeneralResourceID = "unbound"
+
+func resourceOpnUnboundGeneral() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceUnboundGeneralCreate,
+		Read:   resourceUnboundGeneralRead,
+		Update: resourceUnboundGeneralUpdate,
+		Delete: resourceUnboundGeneralDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyUnboundEnabled: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			KeyUnboundDNSSEC: {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			KeyUnboundRegisterLeases: {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Register DHCP leases hostnames in Unbound DNS",
+			},
+			KeyUnboundRegisterStatics: {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				Default:     false,
+				Description: "Register DHCP static mappings hostnames in Unbound DNS",
+			},
+		},
+	}
+}
+
+func unboundGeneralFromResource(d *schema.ResourceData) *UnboundGeneral {
+	return &UnboundGeneral{
+		Enabled:             d.Get(KeyUnboundEnabled).(bool),
+		DNSSEC:              d.Get(KeyUnboundDNSSEC).(bool),
+		RegisterDHCPLeases:  d.Get(KeyUnboundRegisterLeases).(bool),
+		RegisterDHCPStatics: d.Get(KeyUnboundRegisterStatics).(bool),
+	}
+}
+
+func resourceUnboundGeneralCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	dns := pconf.DNS
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	err := dns.UpdateUnboundGeneral(unboundGeneralFromResource(d))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(unboundGeneralResourceID)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundGeneralRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundGeneralRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	g, err := dns.ReadUnboundGeneral()
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params
+	d.Set(KeyUnboundEnabled, g.Enabled)
+	d.Set(KeyUnboundDNSSEC, g.DNSSEC)
+	d.Set(KeyUnboundRegisterLeases, g.RegisterDHCPLeases)
+	d.Set(KeyUnboundRegisterStatics, g.RegisterDHCPStatics)
+
+	return nil
+}
+
+func resourceUnboundGeneralUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	dns := pconf.DNS
+
+	lock.Lock()
+
+	err := dns.UpdateUnboundGeneral(unboundGeneralFromResource(d))
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUnboundGeneralRead(d, meta)
+
+	return err
+}
+
+func resourceUnboundGeneralDelete(d *schema.ResourceData, meta interface{}) error {
+	// singleton settings can't be removed, and turning the resolver off would
+	// break name resolution: leave them as they are
+	return nil
+}
@@ -0,0 +1,171 @@
+package opnsense
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const (
+	// KeyUserName corresponds to the associated resource schema key
+	KeyUserName = "username"
+	// KeyUserPassword corresponds to the associated resource schema key
+	KeyUserPassword = "password"
+	// KeyUserGroups corresponds to the associated resource schema key
+	KeyUserGroups = "groups"
+	// KeyUserDescription corresponds to the associated resource schema key
+	KeyUserDescription = "description"
+)
+
+func resourceOpnUser() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceUserCreate,
+		Read:   resourceUserRead,
+		Update: resourceUserUpdate,
+		Delete: resourceUserDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			KeyUserName: {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			KeyUserPassword: {
+				Type:         schema.TypeString,
+				Required:     true,
+				Sensitive:    true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
+			KeyUserGroups: {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			KeyUserDescription: {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func userGroups(d *schema.ResourceData) []string {
+	groups := []string{}
+	for _, g := range d.Get(KeyUserGroups).([]interface{}) {
+		groups = append(groups, g.(string))
+	}
+	return groups
+}
+
+func resourceUserCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	user := pconf.User
+	lock := pconf.Semaphore
+
+	lock.Lock()
+
+	// create a new user
+	u := User{
+		Name:        d.Get(KeyUserName).(string),
+		Password:    d.Get(KeyUserPassword).(string),
+		Groups:      userGroups(d),
+		Description: d.Get(KeyUserDescription).(string),
+	}
+
+	err := user.CreateUser(&u)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// set resource ID accordingly
+	d.SetId(u.Name)
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUserRead(d, meta)
+
+	return err
+}
+
+func resourceUserRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	user := pconf.User
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	u := User{
+		Name: d.Id(),
+	}
+
+	// read out user information
+	err := user.ReadUser(&u)
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	// set object params, password can't be read back
+	d.Set(KeyUserName, u.Name)
+	d.Set(KeyUserGroups, u.Groups)
+	d.Set(KeyUserDescription, u.Description)
+
+	return nil
+}
+
+func resourceUserUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	user := pconf.User
+
+	lock.Lock()
+
+	// updated user, password is only sent if it has changed
+	u := User{
+		Name:        d.Id(),
+		Groups:      userGroups(d),
+		Description: d.Get(KeyUserDescription).(string),
+	}
+	if d.HasChange(KeyUserPassword) {
+		u.Password = d.Get(KeyUserPassword).(string)
+	}
+
+	err := user.UpdateUser(&u)
+	if err != nil {
+		lock.Unlock()
+		return err
+	}
+
+	// read out resource again
+	lock.Unlock()
+	err = resourceUserRead(d, meta)
+
+	return err
+}
+
+func resourceUserDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*ProviderConfiguration)
+	lock := pconf.Semaphore
+	user := pconf.User
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	u := User{
+		Name: d.Id(),
+	}
+
+	err := user.DeleteUser(&u)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ScheduleServiceURI is the WebUI firewall schedules URI
+	ScheduleServiceURI = "/firewall_schedule.php"
+	// ScheduleServiceEditURI is the WebUI firewall schedule edit URI
+	ScheduleServiceEditURI = "/firewall_schedule_edit.php"
+)
+
+const (
+	// ErrScheduleExists is thrown when a schedule with the same name is already configured
+	ErrScheduleExists = "firewall schedule with this name already exists"
+	// ErrNoSuchSchedule is thrown if no schedule can be found for the specific name
+	ErrNoSuchSchedule = "firewall schedule doesn't exists"
+	// ErrScheduleNoTimeRange is thrown if a schedule is submitted without any time range
+	ErrScheduleNoTimeRange = "firewall schedule needs at least one time range"
+	// ErrScheduleInvalidTimeRange is thrown if a time range is malformed
+	ErrScheduleInvalidTimeRange = "invalid firewall schedule time range"
+)
+
+// ScheduleDays lists the week days a time range applies to, in OPNSense order
+var ScheduleDays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// rxScheduleTime matches a time of day, as HH:MM
+var rxScheduleTime = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// Schedule abstracts a firewall schedule, rules can be bound to
+type Schedule struct {
+	ID          int
+	Name        string
+	Description string
+	TimeRanges  []ScheduleTimeRange
+}
+
+// ScheduleTimeRange abstracts a weekly recurring firewall schedule time range
+type ScheduleTimeRange struct {
+	Days        []string
+	Start       string
+	Stop        string
+	Description string
+}
+
+// Validate checks that a time range is made of known days and of a start time before its stop time
+func (r *ScheduleTimeRange) Validate() error {
+	if len(r.Days) == 0 {
+		return fmt.Errorf("%s: no day set", ErrScheduleInvalidTimeRange)
+	}
+	for _, d := range r.Days {
+		if scheduleDayPosition(d) < 0 {
+			return fmt.Errorf("%s: unknown day %q (expected one of %s)", ErrScheduleInvalidTimeRange, d, strings.Join(ScheduleDays, ", "))
+		}
+	}
+	for _, t := range []string{r.Start, r.Stop} {
+		if !rxScheduleTime.MatchString(t) {
+			return fmt.Errorf("%s: %q isn't a HH:MM time", ErrScheduleInvalidTimeRange, t)
+		}
+	}
+	// zero-padded times compare alike as strings
+	if r.Start >= r.Stop {
+		return fmt.Errorf("%s: %s-%s ends before it starts", ErrScheduleInvalidTimeRange, r.Start, r.Stop)
+	}
+	return nil
+}
+
+// scheduleDayPosition returns the WebUI position (1 for monday) of a week day, -1 if unknown
+func scheduleDayPosition(day string) int {
+	for i, d := range ScheduleDays {
+		if d == day {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// toForm converts a time range into its WebUI form fields, at the given position
+func (r *ScheduleTimeRange) toForm(i int, data requests.Datas) {
+	positions := []string{}
+	for _, d := range r.Days {
+		positions = append(positions, strconv.Itoa(scheduleDayPosition(d)))
+	}
+	data[fmt.Sprintf("schedule%d", i)] = strings.Join(positions, ",")
+	data[fmt.Sprintf("timehour%d", i)] = fmt.Sprintf("%s-%s", r.Start, r.Stop)
+	data[fmt.Sprintf("timedescr%d", i)] = r.Description
+}
+
+// GetAllSchedules retrieves the list of all configured firewall schedules (without details)
+func (s *FirewallSession) GetAllSchedules() ([]Schedule, error) {
+
+	schedules := []Schedule{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return schedules, err
+	}
+
+	// read out the service page
+	doc, err := s.OPN.GetPage(s.OPN.URL(ScheduleServiceURI))
+	if err != nil {
+		return schedules, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table`) == nil {
+		return schedules, s.OPN.UnexpectedPage(doc, "firewall schedules table")
+	}
+
+	// XPath query to find all table rows with an edit link
+	q := fmt.Sprintf(`//table//tr[.//a[contains(@href, "%s")]]`, strings.TrimPrefix(ScheduleServiceEditURI, "/"))
+	rows, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return schedules, err
+	}
+
+	// retrieve all configured schedules
+	for _, r := range rows {
+		td := htmlquery.FindOne(r, `//td[1]`)
+		if td == nil {
+			continue
+		}
+		sc := Schedule{
+			ID:   RowID(r, ScheduleServiceEditURI),
+			Name: strings.TrimSpace(htmlquery.InnerText(td)),
+		}
+		if sc.ID == -1 {
+			continue
+		}
+		schedules = append(schedules, sc)
+	}
+
+	return schedules, nil
+}
+
+// FindSchedule retrieves all firewall schedules and select the one that matches the name
+func (s *FirewallSession) FindSchedule(name string) (*Schedule, error) {
+
+	// retrieves existing schedules
+	schedules, err := s.GetAllSchedules()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if a schedule exists
+	for _, sc := range schedules {
+		// we found it
+		if sc.Name == name {
+			return &sc, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchSchedule)
+}
+
+// ReadScheduleDetails retrieves a firewall schedule description and time ranges from its edit page
+func (s *FirewallSession) ReadScheduleDetails(sc *Schedule) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?id=%d", ScheduleServiceEditURI, sc.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	sc.Description = InputValue(doc, "descr")
+	sc.TimeRanges = []ScheduleTimeRange{}
+	for i := 0; ; i++ {
+		if htmlquery.FindOne(doc, fmt.Sprintf(`//input[@name="schedule%d"]`, i)) == nil {
+			break
+		}
+
+		r := ScheduleTimeRange{
+			Days:        []string{},
+			Description: InputValue(doc, fmt.Sprintf("timedescr%d", i)),
+		}
+		for _, p := range strings.Split(InputValue(doc, fmt.Sprintf("schedule%d", i)), ",") {
+			pos, err := strconv.Atoi(strings.TrimSpace(p))
+			if err == nil && pos >= 1 && pos <= len(ScheduleDays) {
+				r.Days = append(r.Days, ScheduleDays[pos-1])
+			}
+		}
+		hours := strings.SplitN(InputValue(doc, fmt.Sprintf("timehour%d", i)), "-", 2)
+		if len(hours) == 2 {
+			r.Start = strings.TrimSpace(hours[0])
+			r.Stop = strings.TrimSpace(hours[1])
+		}
+		sc.TimeRanges = append(sc.TimeRanges, r)
+	}
+
+	return nil
+}
+
+// ApplySchedules reloads firewall filter so that schedules changes take effect
+func (s *FirewallSession) ApplySchedules(page string) error {
+	data := requests.Datas{
+		"apply": "Apply changes",
+	}
+
+	_, err := s.OPN.ApplyChanges(s.OPN.URL(ScheduleServiceURI), page, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// saveSchedule creates or edit a firewall schedule
+func (s *FirewallSession) saveSchedule(sc *Schedule) error {
+
+	// reject malformed time ranges before submitting anything
+	if len(sc.TimeRanges) == 0 {
+		return s.OPN.Error(ErrScheduleNoTimeRange)
+	}
+	for _, r := range sc.TimeRanges {
+		err := r.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(ScheduleServiceEditURI)
+	if sc.ID != -1 {
+		editURI = fmt.Sprintf("%s?id=%d", editURI, sc.ID)
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+
+	// create a new schedule entry
+	data := requests.Datas{
+		"name":   sc.Name,
+		"descr":  sc.Description,
+		"Submit": "Save",
+	}
+	if sc.ID != -1 {
+		data["id"] = fmt.Sprintf("%d", sc.ID)
+	}
+	for i, r := range sc.TimeRanges {
+		r.toForm(i, data)
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplySchedules(resp.Text())
+}
+
+// CreateSchedule creates a new firewall schedule
+func (s *FirewallSession) CreateSchedule(sc *Schedule) error {
+
+	e, err := s.FindSchedule(sc.Name)
+
+	// check if the schedule is not already configured
+	if e != nil {
+		return s.OPN.Error(ErrScheduleExists)
+	}
+	if err != nil && err.Error() != ErrNoSuchSchedule {
+		return err
+	}
+
+	// create the schedule entry
+	sc.ID = -1
+	return s.saveSchedule(sc)
+}
+
+// ReadSchedule retrieves firewall schedule information for a specified name
+func (s *FirewallSession) ReadSchedule(sc *Schedule) error {
+
+	// check if a schedule exists
+	e, err := s.FindSchedule(sc.Name)
+	if e == nil {
+		return err
+	}
+
+	// assign values accordingly
+	sc.ID = e.ID
+
+	return s.ReadScheduleDetails(sc)
+}
+
+// UpdateSchedule modifies an already existing firewall schedule
+func (s *FirewallSession) UpdateSchedule(sc *Schedule) error {
+
+	// check if a schedule exists
+	e, err := s.FindSchedule(sc.Name)
+	if e == nil {
+		return err
+	}
+
+	// update the schedule entry
+	sc.ID = e.ID
+	return s.saveSchedule(sc)
+}
+
+// DeleteSchedule destroy an existing firewall schedule
+func (s *FirewallSession) DeleteSchedule(sc *Schedule) error {
+
+	// check if a schedule exists
+	e, err := s.FindSchedule(sc.Name)
+	if e == nil {
+		return err
+	}
+
+	// get the service page to retrieve form secret values
+	scheduleURI := s.OPN.URL(ScheduleServiceURI)
+	resp, err := s.OPN.Get(scheduleURI)
+	if err != nil {
+		return err
+	}
+
+	// destroy schedule entry
+	data := requests.Datas{
+		"act": "del",
+		"id":  fmt.Sprintf("%d", e.ID),
+	}
+
+	resp, err = s.OPN.PostForm(scheduleURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input (e.g. schedule still referenced by a rule)
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.ApplySchedules(resp.Text())
+}
@@ -0,0 +1,27 @@
+package opnsense
+
+// Semaphore bounds the number of resource operations run at once against
+// OPNSense. It satisfies sync.Locker, a single slot behaving as a mutex.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore creates a semaphore letting up to n holders in at once
+func NewSemaphore(n int) *Semaphore {
+	if n < 1 {
+		n = 1
+	}
+	return &Semaphore{
+		slots: make(chan struct{}, n),
+	}
+}
+
+// Lock acquires a slot, waiting for one to be released if all are held
+func (s *Semaphore) Lock() {
+	s.slots <- struct{}{}
+}
+
+// Unlock releases a slot
+func (s *Semaphore) Unlock() {
+	<-s.slots
+}
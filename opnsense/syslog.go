@@ -0,0 +1,174 @@
+package opnsense
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SyslogAPI is the syslog MVC API root
+	SyslogAPI = "/api/syslog"
+)
+
+// SyslogTransports are the transports a remote syslog target can be reached through
+var SyslogTransports = []string{"udp4", "tcp4", "udp6", "tcp6", "tls4", "tls6"}
+
+// SyslogSession abstracts OPNSense remote logging
+type SyslogSession struct {
+	OPN *OPNSession
+}
+
+// SyslogTarget abstracts a remote syslog destination
+type SyslogTarget struct {
+	UUID        string
+	Enabled     bool
+	Host        string
+	Port        int
+	Transport   string
+	Facilities  []string
+	Description string
+}
+
+type apiSyslogTarget struct {
+	Enabled     string `json:"enabled"`
+	Hostname    string `json:"hostname"`
+	Port        string `json:"port"`
+	Transport   string `json:"transport"`
+	Facility    string `json:"facility"`
+	Description string `json:"description"`
+}
+
+type apiSyslogTargetRead struct {
+	Enabled     string               `json:"enabled"`
+	Hostname    string               `json:"hostname"`
+	Port        string               `json:"port"`
+	Transport   map[string]APIOption `json:"transport"`
+	Facility    map[string]APIOption `json:"facility"`
+	Description string               `json:"description"`
+}
+
+func (t *SyslogTarget) toAPI() map[string]apiSyslogTarget {
+	enabled := "0"
+	if t.Enabled {
+		enabled = "1"
+	}
+
+	// facilities are a set, write them in a stable order
+	facilities := append([]string{}, t.Facilities...)
+	sort.Strings(facilities)
+
+	return map[string]apiSyslogTarget{
+		"destination": {
+			Enabled:     enabled,
+			Hostname:    t.Host,
+			Port:        fmt.Sprintf("%d", t.Port),
+			Transport:   t.Transport,
+			Facility:    strings.Join(facilities, ","),
+			Description: t.Description,
+		},
+	}
+}
+
+// Apply reconfigures the syslog service
+func (s *SyslogSession) Apply() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/service/reconfigure", SyslogAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// CreateTarget creates a new remote syslog target
+func (s *SyslogSession) CreateTarget(t *SyslogTarget) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/settings/addDestination", SyslogAPI), t.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+	t.UUID = res.UUID
+
+	// apply changes
+	return s.Apply()
+}
+
+// ReadTarget retrieves remote syslog target information for a specified UUID
+func (s *SyslogSession) ReadTarget(t *SyslogTarget) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]apiSyslogTargetRead{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/getDestination/%s", SyslogAPI, t.UUID), &res)
+	if err != nil {
+		return err
+	}
+	e, ok := res["destination"]
+	if !ok {
+		return fmt.Errorf("syslog target %s doesn't exists", t.UUID)
+	}
+
+	// assign values accordingly
+	port, err := strconv.Atoi(e.Port)
+	if err != nil {
+		return err
+	}
+	t.Enabled = e.Enabled == "1"
+	t.Host = e.Hostname
+	t.Port = port
+	t.Transport = SelectedOption(e.Transport)
+	t.Facilities = SelectedOptions(e.Facility)
+	t.Description = e.Description
+
+	return nil
+}
+
+// UpdateTarget modifies an already existing remote syslog target
+func (s *SyslogSession) UpdateTarget(t *SyslogTarget) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/setDestination/%s", SyslogAPI, t.UUID), t.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// DeleteTarget destroy an existing remote syslog target
+func (s *SyslogSession) DeleteTarget(t *SyslogTarget) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/delDestination/%s", SyslogAPI, t.UUID), nil, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
@@ -0,0 +1,151 @@
+package opnsense
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// TrafficShaperAPI is the traffic shaper MVC API root
+	TrafficShaperAPI = "/api/trafficshaper"
+)
+
+// TrafficShaperSession abstracts OPNSense Traffic Shaper
+type TrafficShaperSession struct {
+	OPN *OPNSession
+}
+
+// Pipe abstracts a traffic shaper pipe
+type Pipe struct {
+	UUID            string
+	Number          int
+	Bandwidth       int
+	BandwidthMetric string
+	Mask            string
+	Description     string
+}
+
+type apiPipe struct {
+	Enabled         string `json:"enabled"`
+	Bandwidth       string `json:"bandwidth"`
+	BandwidthMetric string `json:"bandwidthMetric"`
+	Mask            string `json:"mask"`
+	Description     string `json:"description"`
+}
+
+type apiPipeRead struct {
+	Number          string               `json:"number"`
+	Bandwidth       string               `json:"bandwidth"`
+	BandwidthMetric map[string]APIOption `json:"bandwidthMetric"`
+	Mask            map[string]APIOption `json:"mask"`
+	Description     string               `json:"description"`
+}
+
+func (p *Pipe) toAPI() map[string]apiPipe {
+	return map[string]apiPipe{
+		"pipe": {
+			Enabled:         "1",
+			Bandwidth:       fmt.Sprintf("%d", p.Bandwidth),
+			BandwidthMetric: p.BandwidthMetric,
+			Mask:            p.Mask,
+			Description:     p.Description,
+		},
+	}
+}
+
+// Apply reconfigures the traffic shaper
+func (s *TrafficShaperSession) Apply() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/service/reconfigure", TrafficShaperAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// CreatePipe creates a new traffic shaper pipe
+func (s *TrafficShaperSession) CreatePipe(p *Pipe) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/settings/addPipe", TrafficShaperAPI), p.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+	p.UUID = res.UUID
+
+	// apply changes
+	return s.Apply()
+}
+
+// ReadPipe retrieves traffic shaper pipe information for a specified UUID
+func (s *TrafficShaperSession) ReadPipe(p *Pipe) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := map[string]apiPipeRead{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/getPipe/%s", TrafficShaperAPI, p.UUID), &res)
+	if err != nil {
+		return err
+	}
+	e, ok := res["pipe"]
+	if !ok {
+		return fmt.Errorf("traffic shaper pipe %s doesn't exists", p.UUID)
+	}
+
+	// assign values accordingly
+	p.Number, _ = strconv.Atoi(e.Number)
+	p.Bandwidth, _ = strconv.Atoi(e.Bandwidth)
+	p.BandwidthMetric = SelectedOption(e.BandwidthMetric)
+	p.Mask = SelectedOption(e.Mask)
+	p.Description = e.Description
+
+	return nil
+}
+
+// UpdatePipe modifies an already existing traffic shaper pipe
+func (s *TrafficShaperSession) UpdatePipe(p *Pipe) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/setPipe/%s", TrafficShaperAPI, p.UUID), p.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
+
+// DeletePipe destroy an existing traffic shaper pipe
+func (s *TrafficShaperSession) DeletePipe(p *Pipe) error {
+
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/delPipe/%s", TrafficShaperAPI, p.UUID), nil, &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply()
+}
@@ -0,0 +1,247 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"strings"
+)
+
+const (
+	// TunableServiceURI is the WebUI system tunables URI
+	TunableServiceURI = "/system_advanced_sysctl.php"
+)
+
+const (
+	// ErrTunableExists is thrown when a tunable with the same name is already configured
+	ErrTunableExists = "tunable with this name already exists"
+	// ErrNoSuchTunable is thrown if no tunable can be found for the specific name
+	ErrNoSuchTunable = "tunable doesn't exists"
+)
+
+// TunableSession abstracts OPNSense system tunables (sysctl)
+type TunableSession struct {
+	OPN *OPNSession
+}
+
+// Tunable abstracts an OPNSense system tunable
+type Tunable struct {
+	ID          int
+	Name        string
+	Value       string
+	Description string
+}
+
+// Apply sets the pending tunables values on the running system
+func (s *TunableSession) Apply(page string) error {
+	data := requests.Datas{
+		"apply": "Apply changes",
+	}
+
+	_, err := s.OPN.ApplyChanges(s.OPN.URL(TunableServiceURI), page, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetAllTunables retrieves the list of all configured tunables (without details)
+func (s *TunableSession) GetAllTunables() ([]Tunable, error) {
+
+	tunables := []Tunable{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return tunables, err
+	}
+
+	// read out the service page
+	doc, err := s.OPN.GetPage(s.OPN.URL(TunableServiceURI))
+	if err != nil {
+		return tunables, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table`) == nil {
+		return tunables, s.OPN.UnexpectedPage(doc, "tunables table")
+	}
+
+	// XPath query to find all table rows with an edit link
+	q := `//table//tr[.//a[contains(@href, "act=edit")]]`
+	rows, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return tunables, err
+	}
+
+	// retrieve all configured tunables
+	for _, r := range rows {
+		td := htmlquery.FindOne(r, `//td[1]`)
+		if td == nil {
+			continue
+		}
+		t := Tunable{
+			ID:   RowID(r, TunableServiceURI),
+			Name: strings.TrimSpace(htmlquery.InnerText(td)),
+		}
+		if t.ID == -1 {
+			continue
+		}
+		tunables = append(tunables, t)
+	}
+
+	return tunables, nil
+}
+
+// FindTunable retrieves all tunables and select the one that matches the name
+func (s *TunableSession) FindTunable(name string) (*Tunable, error) {
+
+	// retrieves existing tunables
+	tunables, err := s.GetAllTunables()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if a tunable exists
+	for _, t := range tunables {
+		// we found it
+		if t.Name == name {
+			return &t, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchTunable)
+}
+
+// ReadDetails retrieves a tunable value and description from its edit page
+func (s *TunableSession) ReadDetails(t *Tunable) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=edit&id=%d", TunableServiceURI, t.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	t.Value = InputValue(doc, "value")
+	t.Description = InputValue(doc, "descr")
+
+	return nil
+}
+
+// CreateOrEdit creates or edit a tunable
+func (s *TunableSession) CreateOrEdit(t *Tunable) error {
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=edit", TunableServiceURI))
+	if t.ID != -1 {
+		editURI = fmt.Sprintf("%s&id=%d", editURI, t.ID)
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+
+	// create a new tunable entry
+	data := requests.Datas{
+		"act":     "edit",
+		"tunable": t.Name,
+		"value":   t.Value,
+		"descr":   t.Description,
+		"Submit":  "Save",
+	}
+	if t.ID != -1 {
+		data["id"] = fmt.Sprintf("%d", t.ID)
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply(resp.Text())
+}
+
+// CreateTunable creates a new tunable
+func (s *TunableSession) CreateTunable(t *Tunable) error {
+
+	e, err := s.FindTunable(t.Name)
+
+	// check if the tunable is not already configured
+	if e != nil {
+		return s.OPN.Error(ErrTunableExists)
+	}
+	if err != nil && err.Error() != ErrNoSuchTunable {
+		return err
+	}
+
+	// create the tunable entry
+	t.ID = -1
+	return s.CreateOrEdit(t)
+}
+
+// ReadTunable retrieves tunable information for a specified name
+func (s *TunableSession) ReadTunable(t *Tunable) error {
+
+	// check if a tunable exists
+	e, err := s.FindTunable(t.Name)
+	if e == nil {
+		return err
+	}
+
+	// assign values accordingly
+	t.ID = e.ID
+
+	return s.ReadDetails(t)
+}
+
+// UpdateTunable modifies an already existing tunable
+func (s *TunableSession) UpdateTunable(t *Tunable) error {
+
+	// check if a tunable exists
+	e, err := s.FindTunable(t.Name)
+	if e == nil {
+		return err
+	}
+
+	// update the tunable entry
+	t.ID = e.ID
+	return s.CreateOrEdit(t)
+}
+
+// DeleteTunable destroy an existing tunable
+func (s *TunableSession) DeleteTunable(t *Tunable) error {
+
+	// check if a tunable exists
+	e, err := s.FindTunable(t.Name)
+	if e == nil {
+		return err
+	}
+
+	// get the service page to retrieve form secret values
+	tunableURI := s.OPN.URL(TunableServiceURI)
+	resp, err := s.OPN.Get(tunableURI)
+	if err != nil {
+		return err
+	}
+
+	// destroy tunable entry
+	data := requests.Datas{
+		"act": "del",
+		"id":  fmt.Sprintf("%d", e.ID),
+	}
+
+	resp, err = s.OPN.PostForm(tunableURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.Apply(resp.Text())
+}
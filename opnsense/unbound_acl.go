@@ -0,0 +1,304 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// UnboundACLServiceURI is the WebUI service URI
+	UnboundACLServiceURI = "/services_unbound_acls.php"
+)
+
+const (
+	// ErrACLExists is thrown when an access list with the same name already exists
+	ErrACLExists = "access list with this name already exists"
+	// ErrNoSuchACL is thrown if no access list can be found for the specific name
+	ErrNoSuchACL = "access list doesn't exists"
+)
+
+var rxACLID = regexp.MustCompile(`id=([0-9]+)`)
+
+// UnboundACLSession abstracts OPNSense UnboundDNS Access Lists
+type UnboundACLSession struct {
+	OPN *OPNSession
+}
+
+// UnboundACL abstracts an UnboundDNS access list
+type UnboundACL struct {
+	ID          int
+	Name        string
+	Action      string
+	Networks    []string
+	Description string
+}
+
+// GetAllACLs retrieves the list of all configured access lists (without networks)
+func (s *UnboundACLSession) GetAllACLs() ([]UnboundACL, error) {
+
+	acls := []UnboundACL{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return acls, err
+	}
+
+	// read out the service page
+	aclURI := s.OPN.URL(UnboundACLServiceURI)
+	doc, err := s.OPN.GetPage(aclURI)
+	if err != nil {
+		return acls, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table[@class="table table-striped"]`) == nil {
+		return acls, s.OPN.UnexpectedPage(doc, "access lists table")
+	}
+
+	// XPath query to find all table rows with an edit link
+	q := `//table[@class="table table-striped"]//tr[.//a[contains(@href, "act=edit")]]`
+	rows, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return acls, err
+	}
+
+	// retrieve all configured access lists
+	for _, r := range rows {
+		a := htmlquery.FindOne(r, `//a[contains(@href, "act=edit")]`)
+		id := rxACLID.FindStringSubmatch(htmlquery.SelectAttr(a, "href"))
+		cells := htmlquery.Find(r, `//td`)
+		if len(id) < 2 || len(cells) < 3 {
+			continue
+		}
+		acl := UnboundACL{
+			Name:        strings.TrimSpace(htmlquery.InnerText(cells[0])),
+			Action:      strings.TrimSpace(htmlquery.InnerText(cells[1])),
+			Description: strings.TrimSpace(htmlquery.InnerText(cells[2])),
+		}
+		acl.ID, _ = strconv.Atoi(id[1])
+		acls = append(acls, acl)
+	}
+
+	return acls, nil
+}
+
+// FindACL retrieves all access lists and select the one that matches the name
+func (s *UnboundACLSession) FindACL(name string) (*UnboundACL, error) {
+
+	// retrieves existing access lists
+	acls, err := s.GetAllACLs()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if an access list exists
+	for _, a := range acls {
+		// we found it
+		if a.Name == name {
+			return &a, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchACL)
+}
+
+// ReadDetails retrieves an access list action and networks from its edit page
+func (s *UnboundACLSession) ReadDetails(a *UnboundACL) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=edit&id=%d", UnboundACLServiceURI, a.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	n := htmlquery.FindOne(doc, `//select[@name="aclaction"]/option[@selected]`)
+	if n != nil {
+		a.Action = htmlquery.SelectAttr(n, "value")
+	}
+	n = htmlquery.FindOne(doc, `//input[@name="description"]`)
+	if n != nil {
+		a.Description = htmlquery.SelectAttr(n, "value")
+	}
+
+	// networks are listed as numbered rows
+	a.Networks = []string{}
+	for i := 0; ; i++ {
+		n = htmlquery.FindOne(doc, fmt.Sprintf(`//input[@name="acl_network%d"]`, i))
+		if n == nil {
+			break
+		}
+		network := htmlquery.SelectAttr(n, "value")
+		if network == "" {
+			continue
+		}
+		mask := ""
+		m := htmlquery.FindOne(doc, fmt.Sprintf(`//select[@name="mask%d"]/option[@selected]`, i))
+		if m != nil {
+			mask = htmlquery.SelectAttr(m, "value")
+		}
+		if mask != "" {
+			network = fmt.Sprintf("%s/%s", network, mask)
+		}
+		a.Networks = append(a.Networks, network)
+	}
+
+	return nil
+}
+
+// Apply validates the configuration and reload DNS server
+func (s *UnboundACLSession) Apply(page string) error {
+	// apply changes
+	data := requests.Datas{
+		"apply": "Apply changes",
+	}
+
+	applyURI := s.OPN.URL(UnboundACLServiceURI)
+	_, err := s.OPN.PostForm(applyURI, page, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateOrEdit creates or edit an access list
+func (s *UnboundACLSession) CreateOrEdit(a *UnboundACL) error {
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=new", UnboundACLServiceURI))
+	if a.ID != -1 {
+		editURI = s.OPN.URL(fmt.Sprintf("%s?act=edit&id=%d", UnboundACLServiceURI, a.ID))
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+
+	// create a new access list entry
+	data := requests.Datas{
+		"aclname":     a.Name,
+		"aclaction":   a.Action,
+		"description": a.Description,
+		"Submit":      "Save",
+	}
+	for i, n := range a.Networks {
+		network := strings.SplitN(n, "/", 2)
+		data[fmt.Sprintf("acl_network%d", i)] = network[0]
+		if len(network) > 1 {
+			data[fmt.Sprintf("mask%d", i)] = network[1]
+		}
+	}
+	if a.ID != -1 {
+		data["id"] = fmt.Sprintf("%d", a.ID)
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	err = s.Apply(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateACL creates a new access list
+func (s *UnboundACLSession) CreateACL(a *UnboundACL) error {
+
+	e, err := s.FindACL(a.Name)
+
+	// check if the access list is not already registered
+	if e != nil {
+		return s.OPN.Error(ErrACLExists)
+	}
+
+	// create the access list entry
+	a.ID = -1
+	err = s.CreateOrEdit(a)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadACL retrieves access list information for a specified name
+func (s *UnboundACLSession) ReadACL(a *UnboundACL) error {
+
+	// check if an access list exists
+	e, err := s.FindACL(a.Name)
+	if e == nil {
+		return err
+	}
+
+	// assign values accordingly
+	a.ID = e.ID
+	a.Action = e.Action
+	a.Description = e.Description
+
+	return s.ReadDetails(a)
+}
+
+// UpdateACL modifies an already existing access list
+func (s *UnboundACLSession) UpdateACL(a *UnboundACL) error {
+
+	// check if an access list exists
+	e, err := s.FindACL(a.Name)
+	if e == nil {
+		return err
+	}
+
+	// update the access list entry
+	a.ID = e.ID
+	err = s.CreateOrEdit(a)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteACL destroy an existing access list
+func (s *UnboundACLSession) DeleteACL(a *UnboundACL) error {
+
+	// check if an access list exists
+	e, err := s.FindACL(a.Name)
+	if e == nil {
+		return err
+	}
+
+	aclURI := s.OPN.URL(UnboundACLServiceURI)
+
+	// destroy access list entry
+	data := requests.Datas{
+		"id":  fmt.Sprintf("%d", e.ID),
+		"act": "del",
+	}
+
+	resp, err := s.OPN.PostForm(aclURI, "", data)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	err = s.Apply(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
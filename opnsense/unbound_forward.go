@@ -0,0 +1,231 @@
+package opnsense
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// UnboundForwardTypePlain refers to plain DNS query forwarding
+	UnboundForwardTypePlain = "forward"
+	// UnboundForwardTypeTLS refers to DNS over TLS query forwarding
+	UnboundForwardTypeTLS = "dot"
+)
+
+// UnboundForward abstracts Unbound conditional query forwarding of a domain
+// to a set of servers, each of them being stored as a separate OPNSense entry
+type UnboundForward struct {
+	Domain      string
+	Servers     []string
+	UseTLS      bool
+	TLSHostname string
+	Description string
+}
+
+type apiUnboundForward struct {
+	Enabled     string `json:"enabled"`
+	Type        string `json:"type"`
+	Domain      string `json:"domain"`
+	Server      string `json:"server"`
+	Verify      string `json:"verify"`
+	Description string `json:"description"`
+}
+
+type apiUnboundForwardRead struct {
+	Enabled     string               `json:"enabled"`
+	Type        map[string]APIOption `json:"type"`
+	Domain      string               `json:"domain"`
+	Server      string               `json:"server"`
+	Verify      string               `json:"verify"`
+	Description string               `json:"description"`
+}
+
+type apiUnboundForwardSearch struct {
+	Rows []struct {
+		UUID string `json:"uuid"`
+	} `json:"rows"`
+}
+
+// forwardEntries retrieves the UUIDs and values of all forwarding entries of a domain
+func (s *DNSSession) forwardEntries(domain string) (map[string]apiUnboundForwardRead, error) {
+	entries := map[string]apiUnboundForwardRead{}
+
+	res := apiUnboundForwardSearch{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/searchDot", UnboundAPI), keaSearchQuery, &res)
+	if err != nil {
+		return entries, err
+	}
+
+	for _, r := range res.Rows {
+		e := map[string]apiUnboundForwardRead{}
+		err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/getDot/%s", UnboundAPI, r.UUID), &e)
+		if err != nil {
+			return entries, err
+		}
+		dot, ok := e["dot"]
+		if ok && NormalizeDomain(dot.Domain) == NormalizeDomain(domain) {
+			entries[r.UUID] = dot
+		}
+	}
+
+	return entries, nil
+}
+
+// addForwardEntries creates one forwarding entry per server of a domain
+func (s *DNSSession) addForwardEntries(f *UnboundForward) error {
+	rr := UnboundForwardTypePlain
+	verify := ""
+	if f.UseTLS {
+		rr = UnboundForwardTypeTLS
+		verify = f.TLSHostname
+	}
+
+	for _, server := range f.Servers {
+		data := map[string]apiUnboundForward{
+			"dot": {
+				Enabled:     "1",
+				Type:        rr,
+				Domain:      NormalizeDomain(f.Domain),
+				Server:      server,
+				Verify:      verify,
+				Description: f.Description,
+			},
+		}
+		res := APIResult{}
+		err := s.OPN.PostJSON(fmt.Sprintf("%s/settings/addDot", UnboundAPI), data, &res)
+		if err != nil {
+			return err
+		}
+		err = s.OPN.APIError(&res)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteForwardEntries removes all forwarding entries of a domain
+func (s *DNSSession) deleteForwardEntries(domain string) error {
+	entries, err := s.forwardEntries(domain)
+	if err != nil {
+		return err
+	}
+
+	for uuid := range entries {
+		res := APIResult{}
+		err = s.OPN.PostJSON(fmt.Sprintf("%s/settings/delDot/%s", UnboundAPI, uuid), nil, &res)
+		if err != nil {
+			return err
+		}
+		err = s.OPN.APIError(&res)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconfigureUnbound reloads DNS server with its new settings
+func (s *DNSSession) reconfigureUnbound() error {
+	res := APIResult{}
+	err := s.OPN.PostJSON(fmt.Sprintf("%s/service/reconfigure", UnboundAPI), nil, &res)
+	if err != nil {
+		return err
+	}
+	return s.OPN.APIError(&res)
+}
+
+// CreateForward creates Unbound query forwarding of a domain
+func (s *DNSSession) CreateForward(f *UnboundForward) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	err = s.addForwardEntries(f)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.reconfigureUnbound()
+}
+
+// ReadForward retrieves Unbound query forwarding of a domain
+func (s *DNSSession) ReadForward(f *UnboundForward) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.forwardEntries(f.Domain)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("Unbound query forwarding of %s doesn't exists", f.Domain)
+	}
+
+	// assign values accordingly
+	f.Servers = []string{}
+	f.UseTLS = false
+	f.TLSHostname = ""
+	for _, e := range entries {
+		f.Domain = NormalizeDomain(e.Domain)
+		f.Servers = append(f.Servers, e.Server)
+		f.Description = e.Description
+		if SelectedOption(e.Type) == UnboundForwardTypeTLS {
+			f.UseTLS = true
+			f.TLSHostname = e.Verify
+		}
+	}
+	sort.Strings(f.Servers)
+
+	return nil
+}
+
+// UpdateForward replaces Unbound query forwarding of a domain
+func (s *DNSSession) UpdateForward(f *UnboundForward) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	err = s.deleteForwardEntries(f.Domain)
+	if err != nil {
+		return err
+	}
+	err = s.addForwardEntries(f)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.reconfigureUnbound()
+}
+
+// DeleteForward removes Unbound query forwarding of a domain
+func (s *DNSSession) DeleteForward(f *UnboundForward) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	err = s.deleteForwardEntries(f.Domain)
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	return s.reconfigureUnbound()
+}
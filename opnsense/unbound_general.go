@@ -0,0 +1,95 @@
+package opnsense
+
+import (
+	"fmt"
+)
+
+// UnboundGeneral abstracts Unbound DNS general settings
+type UnboundGeneral struct {
+	Enabled             bool
+	DNSSEC              bool
+	RegisterDHCPLeases  bool
+	RegisterDHCPStatics bool
+}
+
+type apiUnboundGeneral struct {
+	Enabled       string `json:"enabled"`
+	DNSSEC        string `json:"dnssec"`
+	RegDHCP       string `json:"regdhcp"`
+	RegDHCPStatic string `json:"regdhcpstatic"`
+}
+
+type apiUnboundGeneralSettings struct {
+	Unbound struct {
+		General apiUnboundGeneral `json:"general"`
+	} `json:"unbound"`
+}
+
+// apiBool converts a boolean to its MVC API representation
+func apiBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func (g *UnboundGeneral) toAPI() map[string]map[string]apiUnboundGeneral {
+	return map[string]map[string]apiUnboundGeneral{
+		"unbound": {
+			"general": {
+				Enabled:       apiBool(g.Enabled),
+				DNSSEC:        apiBool(g.DNSSEC),
+				RegDHCP:       apiBool(g.RegisterDHCPLeases),
+				RegDHCPStatic: apiBool(g.RegisterDHCPStatics),
+			},
+		},
+	}
+}
+
+// ReadUnboundGeneral retrieves Unbound DNS general settings
+func (s *DNSSession) ReadUnboundGeneral() (*UnboundGeneral, error) {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return nil, err
+	}
+
+	res := apiUnboundGeneralSettings{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/settings/get", UnboundAPI), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	r := res.Unbound.General
+	g := UnboundGeneral{
+		Enabled:             r.Enabled == "1",
+		DNSSEC:              r.DNSSEC == "1",
+		RegisterDHCPLeases:  r.RegDHCP == "1",
+		RegisterDHCPStatics: r.RegDHCPStatic == "1",
+	}
+
+	return &g, nil
+}
+
+// UpdateUnboundGeneral modifies Unbound DNS general settings and reloads DNS server
+func (s *DNSSession) UpdateUnboundGeneral(g *UnboundGeneral) error {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return err
+	}
+
+	res := APIResult{}
+	err = s.OPN.PostJSON(fmt.Sprintf("%s/settings/set", UnboundAPI), g.toAPI(), &res)
+	if err != nil {
+		return err
+	}
+	err = s.OPN.APIError(&res)
+	if err != nil {
+		return err
+	}
+
+	return s.reconfigureUnbound()
+}
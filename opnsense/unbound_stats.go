@@ -0,0 +1,83 @@
+package opnsense
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ErrUnboundStatsUnavailable is thrown if Unbound doesn't report any statistics (e.g. service stopped)
+	ErrUnboundStatsUnavailable = "Unbound statistics are unavailable, make sure the DNS service is enabled and running"
+)
+
+// UnboundStats abstracts Unbound DNS key counters, accumulated over all threads since last restart
+type UnboundStats struct {
+	Queries          int64
+	CacheHits        int64
+	CacheMisses      int64
+	Prefetches       int64
+	RecursiveReplies int64
+}
+
+// apiCounter decodes an Unbound counter, reported either as a JSON number or a string
+type apiCounter int64
+
+func (c *apiCounter) UnmarshalJSON(b []byte) error {
+	v, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid Unbound counter %s", b)
+	}
+	*c = apiCounter(v)
+	return nil
+}
+
+type apiUnboundStats struct {
+	Status string `json:"status"`
+	Data   struct {
+		Total struct {
+			Num struct {
+				Queries          apiCounter `json:"queries"`
+				CacheHits        apiCounter `json:"cachehits"`
+				CacheMiss        apiCounter `json:"cachemiss"`
+				Prefetch         apiCounter `json:"prefetch"`
+				RecursiveReplies apiCounter `json:"recursivereplies"`
+			} `json:"num"`
+		} `json:"total"`
+	} `json:"data"`
+}
+
+// ReadUnboundStats retrieves Unbound DNS key counters from its diagnostics
+func (s *DNSSession) ReadUnboundStats() (*UnboundStats, error) {
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return nil, err
+	}
+
+	// statistics are kept as raw JSON, so that a disabled service isn't mistaken for a decoding error
+	raw := json.RawMessage{}
+	err = s.OPN.GetJSON(fmt.Sprintf("%s/diagnostics/stats", UnboundAPI), &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	res := apiUnboundStats{}
+	err = json.Unmarshal(raw, &res)
+	if err != nil || res.Status != "ok" {
+		return nil, s.OPN.Error(ErrUnboundStatsUnavailable)
+	}
+
+	n := res.Data.Total.Num
+	st := UnboundStats{
+		Queries:          int64(n.Queries),
+		CacheHits:        int64(n.CacheHits),
+		CacheMisses:      int64(n.CacheMiss),
+		Prefetches:       int64(n.Prefetch),
+		RecursiveReplies: int64(n.RecursiveReplies),
+	}
+
+	return &st, nil
+}
@@ -0,0 +1,257 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// UserServiceURI is the WebUI service URI
+	UserServiceURI = "/system_usermanager.php"
+)
+
+const (
+	// ErrUserExists is thrown when a user with the same name already exists
+	ErrUserExists = "user with this name already exists"
+	// ErrNoSuchUser is thrown if no user can be found for the specific name
+	ErrNoSuchUser = "user doesn't exists"
+)
+
+var rxUserID = regexp.MustCompile(`userid=([0-9]+)`)
+
+// UserSession abstracts OPNSense User Manager
+type UserSession struct {
+	OPN *OPNSession
+}
+
+// User abstracts an OPNSense local user account
+type User struct {
+	ID          int
+	Name        string
+	Password    string
+	Groups      []string
+	Description string
+}
+
+// GetAllUsers retrieves the list of all configured users (without details)
+func (s *UserSession) GetAllUsers() ([]User, error) {
+
+	users := []User{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return users, err
+	}
+
+	// read out the service page
+	userURI := s.OPN.URL(UserServiceURI)
+	doc, err := s.OPN.GetPage(userURI)
+	if err != nil {
+		return users, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table[@class="table table-striped"]`) == nil {
+		return users, s.OPN.UnexpectedPage(doc, "users table")
+	}
+
+	// XPath query to find all table rows with an edit link
+	q := `//table[@class="table table-striped"]//tr[.//a[contains(@href, "userid=")]]`
+	rows, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return users, err
+	}
+
+	// retrieve all configured users
+	for _, r := range rows {
+		a := htmlquery.FindOne(r, `//a[contains(@href, "userid=")]`)
+		id := rxUserID.FindStringSubmatch(htmlquery.SelectAttr(a, "href"))
+		if len(id) < 2 {
+			continue
+		}
+		td := htmlquery.FindOne(r, `//td[1]`)
+		if td == nil {
+			continue
+		}
+		u := User{
+			Name: strings.TrimSpace(htmlquery.InnerText(td)),
+		}
+		u.ID, _ = strconv.Atoi(id[1])
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
+// FindUser retrieves all users and select the one that matches the name
+func (s *UserSession) FindUser(name string) (*User, error) {
+
+	// retrieves existing users
+	users, err := s.GetAllUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if a user exists
+	for _, u := range users {
+		// we found it
+		if u.Name == name {
+			return &u, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchUser)
+}
+
+// ReadDetails retrieves a user description and groups from its edit page
+func (s *UserSession) ReadDetails(u *User) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=edit&userid=%d", UserServiceURI, u.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	n := htmlquery.FindOne(doc, `//input[@name="descr"]`)
+	if n != nil {
+		u.Description = htmlquery.SelectAttr(n, "value")
+	}
+
+	u.Groups = []string{}
+	options := htmlquery.Find(doc, `//select[@name="groups[]"]/option[@selected]`)
+	for _, o := range options {
+		u.Groups = append(u.Groups, htmlquery.SelectAttr(o, "value"))
+	}
+
+	return nil
+}
+
+// CreateOrEdit creates or edit a user
+func (s *UserSession) CreateOrEdit(u *User) error {
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(fmt.Sprintf("%s?act=new", UserServiceURI))
+	if u.ID != -1 {
+		editURI = s.OPN.URL(fmt.Sprintf("%s?act=edit&userid=%d", UserServiceURI, u.ID))
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+
+	// create a new user entry, an empty password leaves it untouched
+	data := requests.Datas{
+		"usernamefld":  u.Name,
+		"passwordfld1": u.Password,
+		"passwordfld2": u.Password,
+		"descr":        u.Description,
+		"save":         "Save",
+	}
+	for i, g := range u.Groups {
+		data[fmt.Sprintf("groups[%d]", i)] = g
+	}
+	if u.ID != -1 {
+		data["act"] = "edit"
+		data["userid"] = fmt.Sprintf("%d", u.ID)
+	} else {
+		data["act"] = "new"
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateUser creates a new user
+func (s *UserSession) CreateUser(u *User) error {
+
+	e, err := s.FindUser(u.Name)
+
+	// check if the user is not already registered
+	if e != nil {
+		return s.OPN.Error(ErrUserExists)
+	}
+
+	// create the user entry
+	u.ID = -1
+	err = s.CreateOrEdit(u)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadUser retrieves user information for a specified name
+func (s *UserSession) ReadUser(u *User) error {
+
+	// check if a user exists
+	e, err := s.FindUser(u.Name)
+	if e == nil {
+		return err
+	}
+
+	// assign values accordingly
+	u.ID = e.ID
+
+	return s.ReadDetails(u)
+}
+
+// UpdateUser modifies an already existing user
+func (s *UserSession) UpdateUser(u *User) error {
+
+	// check if a user exists
+	e, err := s.FindUser(u.Name)
+	if e == nil {
+		return err
+	}
+
+	// update the user entry
+	u.ID = e.ID
+	err = s.CreateOrEdit(u)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUser destroy an existing user
+func (s *UserSession) DeleteUser(u *User) error {
+
+	// check if a user exists
+	e, err := s.FindUser(u.Name)
+	if e == nil {
+		return err
+	}
+
+	userURI := s.OPN.URL(UserServiceURI)
+
+	// destroy user entry
+	data := requests.Datas{
+		"act":      "deluser",
+		"userid":   fmt.Sprintf("%d", e.ID),
+		"username": e.Name,
+	}
+
+	_, err = s.OPN.PostForm(userURI, "", data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -1,10 +0,0 @@
-package opnsense
-
-func index(slice []string, item string) int {
-	for i := range slice {
-		if slice[i] == item {
-			return i
-		}
-	}
-	return -1
-}
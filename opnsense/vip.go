@@ -0,0 +1,293 @@
+package opnsense
+
+import (
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/asmcos/requests"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// VIPServiceURI is the WebUI service URI
+	VIPServiceURI = "/interfaces_vip.php"
+	// VIPServiceEditURI is the WebUI service edit URI
+	VIPServiceEditURI = "/interfaces_vip_edit.php"
+)
+
+const (
+	// ErrVIPExists is thrown when a virtual IP already exists for this interface/address
+	ErrVIPExists = "virtual IP for this interface and address already exists"
+	// ErrNoSuchVIP is thrown if no virtual IP can be found for the specific interface/address
+	ErrNoSuchVIP = "virtual IP doesn't exists"
+)
+
+var rxVIPID = regexp.MustCompile(`id=([0-9]+)`)
+
+// VIPSession abstracts OPNSense Interfaces Virtual IPs
+type VIPSession struct {
+	OPN *OPNSession
+}
+
+// VIP abstracts an interface virtual IP
+type VIP struct {
+	ID          int
+	Mode        string
+	Interface   string
+	Address     string
+	Bits        int
+	VHID        int
+	Password    string
+	Description string
+}
+
+// ReadDetails retrieves a virtual IP settings from its edit page
+func (s *VIPSession) ReadDetails(v *VIP) error {
+
+	editURI := s.OPN.URL(fmt.Sprintf("%s?id=%d", VIPServiceEditURI, v.ID))
+	doc, err := s.OPN.GetPage(editURI)
+	if err != nil {
+		return err
+	}
+
+	v.Mode = SelectedValue(doc, "mode")
+	v.Interface = SelectedValue(doc, "interface")
+	v.Address = InputValue(doc, "subnet")
+	v.Bits, _ = strconv.Atoi(SelectedValue(doc, "subnet_bits"))
+	v.VHID, _ = strconv.Atoi(SelectedValue(doc, "vhid"))
+	v.Description = InputValue(doc, "descr")
+
+	return nil
+}
+
+// GetAllVIPs retrieves the list of all configured virtual IPs
+func (s *VIPSession) GetAllVIPs() ([]VIP, error) {
+
+	vips := []VIP{}
+
+	// check for proper authentication
+	err := s.OPN.IsAuthenticated()
+	if err != nil {
+		return vips, err
+	}
+
+	// read out the service page
+	vipURI := s.OPN.URL(VIPServiceURI)
+	doc, err := s.OPN.GetPage(vipURI)
+	if err != nil {
+		return vips, err
+	}
+
+	// make sure we've not been served an error or partial page
+	if htmlquery.FindOne(doc, `//table`) == nil {
+		return vips, s.OPN.UnexpectedPage(doc, "virtual IPs table")
+	}
+
+	// XPath query to find all edit links
+	q := fmt.Sprintf(`//table//a[contains(@href, "%s?id=")]`, VIPServiceEditURI[1:])
+	links, err := htmlquery.QueryAll(doc, q)
+	if err != nil {
+		return vips, err
+	}
+
+	// retrieve all configured virtual IPs details
+	seen := map[int]bool{}
+	for _, l := range links {
+		id := rxVIPID.FindStringSubmatch(htmlquery.SelectAttr(l, "href"))
+		if len(id) < 2 {
+			continue
+		}
+		v := VIP{}
+		v.ID, _ = strconv.Atoi(id[1])
+		if seen[v.ID] {
+			continue
+		}
+		seen[v.ID] = true
+
+		err = s.ReadDetails(&v)
+		if err != nil {
+			return vips, err
+		}
+		vips = append(vips, v)
+	}
+
+	return vips, nil
+}
+
+// FindVIP retrieves all virtual IPs and select the one that matches interface and address
+func (s *VIPSession) FindVIP(v *VIP) (*VIP, error) {
+
+	// retrieves existing virtual IPs
+	vips, err := s.GetAllVIPs()
+	if err != nil {
+		return nil, err
+	}
+
+	// check if a virtual IP exists
+	for _, e := range vips {
+		// we found it
+		if e.Interface == v.Interface && e.Address == v.Address {
+			return &e, nil
+		}
+	}
+
+	return nil, s.OPN.Error(ErrNoSuchVIP)
+}
+
+// Apply validates the configuration and reconfigures virtual IPs
+func (s *VIPSession) Apply(page string) error {
+	// apply changes
+	data := requests.Datas{
+		"apply": "Apply changes",
+	}
+
+	applyURI := s.OPN.URL(VIPServiceURI)
+	_, err := s.OPN.PostForm(applyURI, page, data)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CreateOrEdit creates or edit a virtual IP
+func (s *VIPSession) CreateOrEdit(v *VIP) error {
+
+	// get the edit page to retrieve form secret values
+	editURI := s.OPN.URL(VIPServiceEditURI)
+	if v.ID != -1 {
+		editURI = fmt.Sprintf("%s?id=%d", editURI, v.ID)
+	}
+	resp, err := s.OPN.Get(editURI)
+	if err != nil {
+		return err
+	}
+
+	// create a new virtual IP entry
+	data := requests.Datas{
+		"mode":        v.Mode,
+		"interface":   v.Interface,
+		"subnet":      v.Address,
+		"subnet_bits": fmt.Sprintf("%d", v.Bits),
+		"descr":       v.Description,
+		"submit":      "Save",
+	}
+	if v.Mode == "carp" {
+		data["vhid"] = fmt.Sprintf("%d", v.VHID)
+		data["password"] = v.Password
+	}
+	if v.ID != -1 {
+		data["id"] = fmt.Sprintf("%d", v.ID)
+	}
+
+	resp, err = s.OPN.EditForm(editURI, resp.Text(), data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected input
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	err = s.Apply(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateVIP creates a new virtual IP
+func (s *VIPSession) CreateVIP(v *VIP) error {
+
+	e, err := s.FindVIP(v)
+
+	// check if the virtual IP is not already registered
+	if e != nil {
+		return s.OPN.Error(ErrVIPExists)
+	}
+
+	// create the virtual IP entry
+	v.ID = -1
+	err = s.CreateOrEdit(v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReadVIP retrieves virtual IP information for a specified interface/address
+func (s *VIPSession) ReadVIP(v *VIP) error {
+
+	// check if a virtual IP exists
+	e, err := s.FindVIP(v)
+	if e == nil {
+		return err
+	}
+
+	// assign all values accordingly, password can't be read back
+	password := v.Password
+	*v = *e
+	v.Password = password
+
+	return nil
+}
+
+// UpdateVIP modifies an already existing virtual IP
+func (s *VIPSession) UpdateVIP(v *VIP) error {
+
+	// check if a virtual IP exists
+	e, err := s.FindVIP(v)
+	if e == nil {
+		return err
+	}
+
+	// update the virtual IP entry
+	v.ID = e.ID
+	err = s.CreateOrEdit(v)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteVIP destroy an existing virtual IP
+func (s *VIPSession) DeleteVIP(v *VIP) error {
+
+	// check if a virtual IP exists
+	e, err := s.FindVIP(v)
+	if e == nil {
+		return err
+	}
+
+	vipURI := s.OPN.URL(VIPServiceURI)
+
+	// destroy virtual IP entry
+	data := requests.Datas{
+		"id":  fmt.Sprintf("%d", e.ID),
+		"act": "del",
+	}
+
+	resp, err := s.OPN.PostForm(vipURI, "", data)
+	if err != nil {
+		return err
+	}
+
+	// check for rejected removal (e.g. virtual IP still in use)
+	err = s.OPN.FormErrors(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	// apply changes
+	err = s.Apply(resp.Text())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}